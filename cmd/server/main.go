@@ -17,11 +17,13 @@ import (
 
 	"github.com/darkdragonsastro/draco-simulator/internal/api/rest"
 	"github.com/darkdragonsastro/draco-simulator/internal/api/websocket"
+	"github.com/darkdragonsastro/draco-simulator/internal/camera"
 	"github.com/darkdragonsastro/draco-simulator/internal/catalog"
 	"github.com/darkdragonsastro/draco-simulator/internal/database"
 	"github.com/darkdragonsastro/draco-simulator/internal/eventbus"
 	"github.com/darkdragonsastro/draco-simulator/internal/game"
 	"github.com/darkdragonsastro/draco-simulator/internal/mount"
+	"github.com/darkdragonsastro/draco-simulator/internal/simclock"
 )
 
 // Version information (set during build)
@@ -85,8 +87,11 @@ func run(ctx context.Context, config Config) error {
 	bus := eventbus.NewInMemoryBus()
 	db := database.NewInMemoryDB()
 
+	// Shared simulation clock for the sky, mount and game session
+	clock := simclock.New()
+
 	// Initialize game service
-	gameService := game.NewService(bus, db)
+	gameService := game.NewService(bus, db, clock)
 	if err := gameService.Initialize(ctx); err != nil {
 		return fmt.Errorf("failed to initialize game service: %w", err)
 	}
@@ -115,17 +120,37 @@ func run(ctx context.Context, config Config) error {
 	wsHub := websocket.NewHub()
 	go wsHub.Run(ctx)
 
-	// Initialize mount simulator
-	mountSim := mount.NewSimulator(mount.DefaultConfig(), func(status mount.MountStatus) {
+	// Initialize mount simulator with the starter mount's tracking characteristics
+	mountConfig := mount.DefaultConfig()
+	mountConfig.Mechanics = game.LoadoutToVirtualConfig(game.StarterLoadout).Mount
+	mountConfig.Clock = clock
+	mountSim := mount.NewSimulator(mountConfig, func(status mount.MountStatus) {
 		wsHub.Broadcast(websocket.EventMountPosition, status)
 	})
+	mountSim.SetEventHandler(wsHub.Broadcast)
+
+	// Initialize the virtual camera on the starter telescope. Exposures go
+	// out on the event bus so the game service scores real captures.
+	cam := camera.NewSimulator(camera.Config{
+		Loadout: game.StarterLoadout,
+		Stars:   starCatalog,
+		Mount:   mountSim,
+		Clock:   clock,
+	})
+	cam.SetEventHandler(func(eventType string, data any) {
+		if err := bus.Publish(ctx, eventType, data); err != nil {
+			log.Printf("Warning: failed to publish %s: %v", eventType, err)
+		}
+		wsHub.Broadcast(eventType, data)
+	})
 
 	// Initialize REST API server
 	restConfig := rest.Config{
 		Address: fmt.Sprintf("%s:%d", config.Host, config.Port),
 		Debug:   config.Debug,
 	}
-	server := rest.NewServer(restConfig, gameService, starCatalog, dsoCatalog, mountSim)
+	server := rest.NewServer(restConfig, gameService, starCatalog, dsoCatalog, mountSim, cam, clock)
+	server.RegisterWebSocketCommands(wsHub)
 
 	// Create HTTP server that combines REST + WebSocket
 	mux := http.NewServeMux()
@@ -161,6 +186,7 @@ func run(ctx context.Context, config Config) error {
 	log.Println("  GET  /api/v1/game/challenges  - All challenges")
 	log.Println("  GET  /api/v1/game/achievements - All achievements")
 	log.Println("  GET  /api/v1/game/store       - Equipment store")
+	log.Println("  POST /api/v1/game/score       - Score an image from its pixels")
 	log.Println("  GET  /api/v1/catalog/stars/bright - Bright stars for planetarium")
 	log.Println("  GET  /api/v1/catalog/constellations - Constellation data")
 	log.Println("  GET  /api/v1/catalog/dso/messier - Messier catalog")
@@ -171,6 +197,15 @@ func run(ctx context.Context, config Config) error {
 	log.Println("  GET  /api/v1/sky/moon         - Moon info")
 	log.Println("  GET  /api/v1/mount/status     - Mount status")
 	log.Println("  POST /api/v1/mount/slew       - Slew to target")
+	log.Println("  POST /api/v1/mount/pulseguide - Guide pulse")
+	log.Println("  POST /api/v1/mount/sync       - Sync and add alignment star")
+	log.Println("  POST /api/v1/camera/expose    - Start an exposure")
+	log.Println("  GET  /api/v1/camera/image     - Preview of the last frame")
+	log.Println("  PUT  /api/v1/camera/readout   - Binning, subframe and readout mode")
+	log.Println("  PUT  /api/v1/camera/cooler    - Cooler on/off and setpoint")
+	log.Println("  GET  /api/v1/camera/calibration - Calibration masters")
+	log.Println("  POST /api/v1/stacking         - Stack uploaded frames")
+	log.Println("  GET  /api/v1/stacking/gallery - Stacked final images")
 	log.Println("  WS   /ws                      - WebSocket connection")
 	log.Println("")
 
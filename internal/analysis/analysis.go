@@ -0,0 +1,241 @@
+// Package analysis measures astronomical images from their pixels: the sky
+// background and its noise, and the stars, with their centroids, sizes,
+// shapes and signal-to-noise ratios. Its results feed image scoring, so
+// players are graded on what their frames actually show.
+package analysis
+
+import (
+	"math"
+
+	"github.com/darkdragonsastro/draco-simulator/internal/game"
+)
+
+const (
+	// DefaultThreshold is the detection threshold, in standard deviations
+	// of the smoothed background noise.
+	DefaultThreshold = 5.0
+
+	// DefaultMaxStars is how many of the brightest stars are measured.
+	DefaultMaxStars = 1000
+
+	// minShapeSNR is the SNR of the stars the image's size and shape are
+	// judged by, when there are at least minShapeStars of them; noise
+	// stretches fainter stars.
+	minShapeSNR   = 20.0
+	minShapeStars = 5
+)
+
+// Options tune the analysis. Zero values take the defaults.
+type Options struct {
+	Threshold float64 // detection threshold, in standard deviations of the noise
+	MaxStars  int     // how many of the brightest stars to measure
+
+	// Correlated says the noise is correlated between neighbouring pixels,
+	// as in registered and stacked images, so the smoothed noise stars are
+	// detected against must be measured rather than scaled from the noise
+	Correlated bool
+}
+
+// Result is the analysis of an image. Lengths are in pixels of the image
+// as given, and levels in its ADU.
+type Result struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+
+	Background float64 `json:"background"` // mean sky level
+	Noise      float64 `json:"noise"`      // standard deviation of the sky
+	MaxADU     float64 `json:"max_adu"`
+
+	// Stars are the detected stars, brightest first, up to the
+	// Options.MaxStars brightest
+	Stars []Star `json:"stars"`
+
+	// Medians over the well-exposed, unsaturated stars
+	HFR          float64 `json:"hfr"`
+	FWHM         float64 `json:"fwhm"`
+	Eccentricity float64 `json:"eccentricity"`
+	Elongation   float64 `json:"elongation"`
+
+	// TrailingAngle is the mean direction of the stars' major axes, in
+	// degrees from the +x axis towards +y, weighted by how elongated they
+	// are; it shows which way the mount drifted
+	TrailingAngle float64 `json:"trailing_angle"`
+
+	SaturatedFraction float64 `json:"saturated_fraction"` // of the stars
+	SaturatedPixels   float64 `json:"saturated_pixels"`   // fraction of all pixels
+	SNR               float64 `json:"snr"`                // median over the stars
+
+	context Image // the image's context, without its pixels
+}
+
+// Analyze measures img. Raw color frames are measured on 2×2 superpixels,
+// with lengths and positions reported in the frame's own pixels.
+func Analyze(img *Image, opts Options) (*Result, error) {
+	if img.Width <= 0 || img.Height <= 0 {
+		return nil, errEmptyImage
+	}
+	if len(img.Pixels) != img.Width*img.Height {
+		return nil, errBadDimensions
+	}
+	if opts.Threshold < 0 {
+		return nil, errInvalidOptions
+	}
+	if opts.Threshold == 0 {
+		opts.Threshold = DefaultThreshold
+	}
+	if opts.MaxStars <= 0 {
+		opts.MaxStars = DefaultMaxStars
+	}
+
+	res := &Result{Width: img.Width, Height: img.Height, context: *img}
+	res.context.Pixels = nil
+
+	// Saturation is judged on the pixels as read out
+	saturation := saturationMargin * img.saturation()
+	var saturated int
+	for _, v := range img.Pixels {
+		res.MaxADU = max(res.MaxADU, float64(v))
+		if float64(v) >= saturation {
+			saturated++
+		}
+	}
+	res.SaturatedPixels = float64(saturated) / float64(len(img.Pixels))
+
+	work, peaks, scale := img, img.Pixels, 1.0
+	if img.BayerPattern != "" && img.Width >= 2 && img.Height >= 2 {
+		work, peaks = img.superpixels()
+		scale = 2
+	}
+
+	bg := estimateBackground(work)
+	res.Background = bg.mean()
+	res.Noise = bg.noise
+
+	residual := make([]float32, len(work.Pixels))
+	for y := 0; y < work.Height; y++ {
+		for x := 0; x < work.Width; x++ {
+			i := y*work.Width + x
+			residual[i] = work.Pixels[i] - float32(bg.at(x, y))
+		}
+	}
+
+	res.Stars = []Star{}
+	if bg.noise > 0 {
+		for _, c := range detect(residual, work.Width, work.Height, bg.noise, opts.Threshold, opts.Correlated) {
+			if len(res.Stars) == opts.MaxStars {
+				break
+			}
+			star, ok := measureStar(work, residual, peaks, bg.noise, c)
+			if !ok {
+				continue
+			}
+			if scale != 1 {
+				star.X = scale*star.X + 0.5
+				star.Y = scale*star.Y + 0.5
+				star.HFR *= scale
+				star.FWHM *= scale
+			}
+			if !duplicate(res.Stars, star) {
+				res.Stars = append(res.Stars, star)
+			}
+		}
+	}
+	res.summarize()
+	return res, nil
+}
+
+// duplicate reports whether star is one of stars found again from another
+// of its local maxima, as a saturated star's flat top gives.
+func duplicate(stars []Star, star Star) bool {
+	for _, s := range stars {
+		if math.Hypot(s.X-star.X, s.Y-star.Y) < max(2, s.HFR) {
+			return true
+		}
+	}
+	return false
+}
+
+// summarize fills in the medians over the stars.
+func (r *Result) summarize() {
+	if len(r.Stars) == 0 {
+		return
+	}
+	var snrs []float64
+	var saturated int
+	var shape []Star
+	for _, s := range r.Stars {
+		snrs = append(snrs, s.SNR)
+		if s.Saturated {
+			saturated++
+			continue
+		}
+		if s.SNR >= minShapeSNR {
+			shape = append(shape, s)
+		}
+	}
+	r.SNR = median(snrs)
+	r.SaturatedFraction = float64(saturated) / float64(len(r.Stars))
+
+	if len(shape) < minShapeStars {
+		shape = shape[:0]
+		for _, s := range r.Stars {
+			if !s.Saturated {
+				shape = append(shape, s)
+			}
+		}
+	}
+	if len(shape) == 0 {
+		// Every star is saturated; their sizes are all there is
+		shape = r.Stars
+	}
+
+	hfr := make([]float64, len(shape))
+	fwhm := make([]float64, len(shape))
+	ecc := make([]float64, len(shape))
+	elong := make([]float64, len(shape))
+	var sin, cos float64
+	for i, s := range shape {
+		hfr[i], fwhm[i], ecc[i], elong[i] = s.HFR, s.FWHM, s.Eccentricity, s.Elongation
+
+		// Average axes as doubled angles, so 179° and 1° agree
+		w := s.Eccentricity * s.Eccentricity
+		a := 2 * s.Angle * math.Pi / 180
+		sin += w * math.Sin(a)
+		cos += w * math.Cos(a)
+	}
+	r.HFR = median(hfr)
+	r.FWHM = median(fwhm)
+	r.Eccentricity = median(ecc)
+	r.Elongation = median(elong)
+	if sin != 0 || cos != 0 {
+		r.TrailingAngle = math.Atan2(sin, cos) / 2 * 180 / math.Pi
+		if r.TrailingAngle < 0 {
+			r.TrailingAngle += 180
+		}
+	}
+}
+
+// Metrics returns the result as the metrics images are scored by, with
+// the exposure settings the image recorded.
+func (r *Result) Metrics() game.ImageMetrics {
+	m := game.ImageMetrics{
+		HFR:               r.HFR,
+		StarCount:         len(r.Stars),
+		Elongation:        r.Elongation,
+		TrailingAngle:     r.TrailingAngle,
+		ExposureTime:      r.context.Exposure,
+		Gain:              r.context.Gain,
+		MeanADU:           r.Background,
+		MaxADU:            r.MaxADU,
+		SaturatedFraction: r.SaturatedFraction,
+		BackgroundStdDev:  r.Noise,
+		SNR:               r.SNR,
+		BitDepth:          16,
+		PixelScale:        r.context.PixelScale,
+		Binning:           r.context.Binning,
+	}
+	if r.context.PixelScale > 0 {
+		m.FWHM = r.FWHM * r.context.PixelScale
+	}
+	return m
+}
@@ -0,0 +1,170 @@
+package analysis
+
+import (
+	"math"
+	"slices"
+)
+
+const (
+	// meshSize is the side of the tiles the background is estimated in, in
+	// pixels: large next to stars, small next to gradients.
+	meshSize = 64
+
+	// minMeshSize is the smallest tile used for small images.
+	minMeshSize = 16
+
+	// backgroundClip is how far above or below the median, in robust
+	// standard deviations, tile pixels are rejected as stars or defects
+	// when estimating the background.
+	backgroundClip = 3.0
+
+	// backgroundIterations is how many times the clipping is repeated.
+	backgroundIterations = 3
+)
+
+// background is a smooth estimate of the sky level under the stars, with
+// the pixel-to-pixel noise around it.
+type background struct {
+	width, height int
+	tile          int
+	cols, rows    int
+	levels        []float64 // per tile, row by row
+	noise         float64   // ADU, median over the tiles
+}
+
+// estimateBackground measures the sky in a mesh of tiles, each the
+// sigma-clipped median of its pixels, then median-filters the mesh so
+// tiles swamped by bright stars or nebulae take their neighbours' level.
+func estimateBackground(img *Image) *background {
+	tile := meshSize
+	for tile > minMeshSize && (img.Width < 4*tile || img.Height < 4*tile) {
+		tile /= 2
+	}
+	bg := &background{
+		width:  img.Width,
+		height: img.Height,
+		tile:   tile,
+		cols:   max(1, img.Width/tile),
+		rows:   max(1, img.Height/tile),
+	}
+	levels := make([]float64, bg.cols*bg.rows)
+	noises := make([]float64, 0, len(levels))
+	values := make([]float64, 0, tile*tile)
+	for ty := 0; ty < bg.rows; ty++ {
+		for tx := 0; tx < bg.cols; tx++ {
+			// The last row and column of tiles take in the remainder
+			x0, y0 := tx*tile, ty*tile
+			x1, y1 := x0+tile, y0+tile
+			if tx == bg.cols-1 {
+				x1 = img.Width
+			}
+			if ty == bg.rows-1 {
+				y1 = img.Height
+			}
+			values = values[:0]
+			for y := y0; y < y1; y++ {
+				for _, v := range img.Pixels[y*img.Width+x0 : y*img.Width+x1] {
+					values = append(values, float64(v))
+				}
+			}
+			level, sigma := clippedStats(values)
+			levels[ty*bg.cols+tx] = level
+			noises = append(noises, sigma)
+		}
+	}
+	bg.levels = medianFilter(levels, bg.cols, bg.rows)
+	bg.noise = median(noises)
+	return bg
+}
+
+// at returns the background at pixel (x, y), interpolated bilinearly
+// between the tile centres.
+func (bg *background) at(x, y int) float64 {
+	fx := (float64(x)+0.5)/float64(bg.tile) - 0.5
+	fy := (float64(y)+0.5)/float64(bg.tile) - 0.5
+	fx = math.Max(0, math.Min(float64(bg.cols-1), fx))
+	fy = math.Max(0, math.Min(float64(bg.rows-1), fy))
+	x0, y0 := int(fx), int(fy)
+	x1, y1 := min(x0+1, bg.cols-1), min(y0+1, bg.rows-1)
+	tx, ty := fx-float64(x0), fy-float64(y0)
+	top := bg.levels[y0*bg.cols+x0]*(1-tx) + bg.levels[y0*bg.cols+x1]*tx
+	bottom := bg.levels[y1*bg.cols+x0]*(1-tx) + bg.levels[y1*bg.cols+x1]*tx
+	return top*(1-ty) + bottom*ty
+}
+
+// mean returns the mean background level.
+func (bg *background) mean() float64 {
+	var sum float64
+	for _, v := range bg.levels {
+		sum += v
+	}
+	return sum / float64(len(bg.levels))
+}
+
+// clippedStats returns the median and robust standard deviation of values
+// after repeatedly rejecting those beyond backgroundClip deviations of the
+// median. values is reordered and may be truncated.
+func clippedStats(values []float64) (level, sigma float64) {
+	for i := 0; i < backgroundIterations && len(values) > 0; i++ {
+		level, sigma = robustStats(values)
+		kept := values[:0]
+		for _, v := range values {
+			if sigma == 0 || math.Abs(v-level) <= backgroundClip*sigma {
+				kept = append(kept, v)
+			}
+		}
+		if len(kept) == len(values) {
+			break
+		}
+		values = kept
+	}
+	if len(values) > 0 {
+		level, sigma = robustStats(values)
+	}
+	return level, sigma
+}
+
+// robustStats returns the median of values and their median absolute
+// deviation scaled to a standard deviation. values is sorted.
+func robustStats(values []float64) (level, sigma float64) {
+	slices.Sort(values)
+	level = values[len(values)/2]
+	dev := make([]float64, len(values))
+	for i, v := range values {
+		dev[i] = math.Abs(v - level)
+	}
+	slices.Sort(dev)
+	return level, 1.4826 * dev[len(dev)/2]
+}
+
+// medianFilter returns grid with each cell replaced by the median of its
+// 3×3 neighbourhood.
+func medianFilter(grid []float64, cols, rows int) []float64 {
+	out := make([]float64, len(grid))
+	var window []float64
+	for y := 0; y < rows; y++ {
+		for x := 0; x < cols; x++ {
+			window = window[:0]
+			for ny := max(0, y-1); ny <= min(rows-1, y+1); ny++ {
+				for nx := max(0, x-1); nx <= min(cols-1, x+1); nx++ {
+					window = append(window, grid[ny*cols+nx])
+				}
+			}
+			out[y*cols+x] = median(window)
+		}
+	}
+	return out
+}
+
+// median returns the median of values, reordering them; 0 when empty.
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	slices.Sort(values)
+	n := len(values)
+	if n%2 == 0 {
+		return (values[n/2-1] + values[n/2]) / 2
+	}
+	return values[n/2]
+}
@@ -0,0 +1,11 @@
+package analysis
+
+import "errors"
+
+var (
+	errEmptyImage     = errors.New("image has no pixels")
+	errBadDimensions  = errors.New("image dimensions do not match pixel count")
+	errUnknownFormat  = errors.New("image must be FITS or PNG")
+	errTooLarge       = errors.New("image dimensions too large")
+	errInvalidOptions = errors.New("detection threshold must not be negative")
+)
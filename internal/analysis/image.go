@@ -0,0 +1,163 @@
+package analysis
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/png" // PNG uploads
+	"io"
+	"math"
+
+	"github.com/darkdragonsastro/draco-simulator/internal/fits"
+)
+
+// Image is a frame to analyze with what is known of how it was taken. Any
+// of the context may be zero when unknown.
+type Image struct {
+	Width  int
+	Height int
+	Pixels []float32 // ADU on a 16-bit scale, row by row
+
+	// BayerPattern marks a raw one-shot color frame, which is measured on
+	// 2×2 superpixels so the mosaic isn't mistaken for structure
+	BayerPattern string
+
+	Saturation      float64 // ADU at which pixels clip; 0 means 65535
+	ElectronsPerADU float64 // for the shot noise of stars; 0 leaves it out of their SNR
+	Exposure        float64 // seconds
+	Gain            int
+	PixelScale      float64 // arcsec/pixel
+	Binning         int
+}
+
+// Decode reads an image from a FITS or PNG file.
+func Decode(r io.Reader) (*Image, error) {
+	br := bufio.NewReader(r)
+	if magic, _ := br.Peek(9); string(magic) == "SIMPLE  =" {
+		img, err := fits.Read(br)
+		if err != nil {
+			return nil, err
+		}
+		return FromFITS(img), nil
+	}
+
+	// Check the dimensions before decoding allocates for them, replaying
+	// the bytes the check read
+	var head bytes.Buffer
+	cfg, _, err := image.DecodeConfig(io.TeeReader(br, &head))
+	if err != nil {
+		return nil, errUnknownFormat
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width > fits.MaxDimension || cfg.Height > fits.MaxDimension ||
+		cfg.Width > fits.MaxPixels/cfg.Height {
+		return nil, fmt.Errorf("%w: %d×%d", errTooLarge, cfg.Width, cfg.Height)
+	}
+	img, _, err := image.Decode(io.MultiReader(&head, br))
+	if err != nil {
+		return nil, errUnknownFormat
+	}
+	return FromImage(img), nil
+}
+
+// FromFITS returns a FITS image for analysis, with the context its capture
+// headers record. Float images normalized to 0-1 are scaled up to 16-bit
+// ADU.
+func FromFITS(img *fits.Image) *Image {
+	out := &Image{
+		Width:  img.Width,
+		Height: img.Height,
+		Pixels: img.Pixels,
+	}
+	h := &img.Header
+	if img.Bitpix == fits.Float32 {
+		peak := float32(0)
+		for _, v := range img.Pixels {
+			peak = max(peak, v)
+		}
+		if peak <= 1 {
+			out.Pixels = make([]float32, len(img.Pixels))
+			for i, v := range img.Pixels {
+				out.Pixels[i] = v * math.MaxUint16
+			}
+		}
+	}
+
+	out.BayerPattern, _ = h.String("BAYERPAT")
+	if v, ok := h.Float("SATURATE"); ok {
+		out.Saturation = v
+	} else if v, ok := h.Float("DATAMAX"); ok {
+		out.Saturation = v
+	}
+	out.ElectronsPerADU, _ = h.Float("EGAIN")
+	if v, ok := h.Float("EXPTIME"); ok {
+		out.Exposure = v
+	} else {
+		out.Exposure, _ = h.Float("EXPOSURE")
+	}
+	out.Gain, _ = h.Int("GAIN")
+	out.Binning, _ = h.Int("XBINNING")
+
+	// The plate solution gives the scale exactly; pixel size and focal
+	// length give it nominally
+	if wcs, err := fits.ReadWCS(h); err == nil {
+		out.PixelScale = wcs.Scale()
+	} else {
+		size, okSize := h.Float("XPIXSZ")
+		focal, okFocal := h.Float("FOCALLEN")
+		if okSize && okFocal && focal > 0 {
+			out.PixelScale = 206.265 * size / focal
+		}
+	}
+	return out
+}
+
+// FromImage returns a decoded image for analysis as 16-bit luminance.
+// 8-bit images are scaled up to the 16-bit range.
+func FromImage(img image.Image) *Image {
+	b := img.Bounds()
+	out := &Image{
+		Width:  b.Dx(),
+		Height: b.Dy(),
+		Pixels: make([]float32, b.Dx()*b.Dy()),
+	}
+	i := 0
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			out.Pixels[i] = float32(color.Gray16Model.Convert(img.At(x, y)).(color.Gray16).Y)
+			i++
+		}
+	}
+	return out
+}
+
+// saturation returns the level at which the image's pixels clip.
+func (img *Image) saturation() float64 {
+	if img.Saturation > 0 {
+		return img.Saturation
+	}
+	return math.MaxUint16
+}
+
+// superpixels returns a raw color frame averaged over its 2×2 Bayer cells,
+// with the brightest pixel of each cell for judging saturation.
+func (img *Image) superpixels() (*Image, []float32) {
+	out := *img
+	out.Width, out.Height = img.Width/2, img.Height/2
+	out.Pixels = make([]float32, out.Width*out.Height)
+	out.BayerPattern = ""
+	peaks := make([]float32, len(out.Pixels))
+	for y := 0; y < out.Height; y++ {
+		for x := 0; x < out.Width; x++ {
+			i := 2*y*img.Width + 2*x
+			a, b, c, d := img.Pixels[i], img.Pixels[i+1], img.Pixels[i+img.Width], img.Pixels[i+img.Width+1]
+			out.Pixels[y*out.Width+x] = (a + b + c + d) / 4
+			peaks[y*out.Width+x] = max(a, b, c, d)
+		}
+	}
+	// A superpixel's ADU stands for four pixels' electrons
+	out.PixelScale *= 2
+	out.ElectronsPerADU *= 4
+	return &out, peaks
+}
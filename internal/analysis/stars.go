@@ -0,0 +1,345 @@
+package analysis
+
+import (
+	"math"
+	"slices"
+)
+
+const (
+	// smoothingNoise is the factor the 1-2-1 smoothing kernel scales white
+	// noise by: the root of the sum of its squared weights.
+	smoothingNoise = 0.375
+
+	// noiseSamples is about how many smoothed pixels the smoothed noise is
+	// measured on.
+	noiseSamples = 100000
+
+	// peakRadius is the half-width of the neighbourhood a candidate must be
+	// the brightest smoothed pixel of.
+	peakRadius = 2
+
+	// The window moments are measured in spans this many standard
+	// deviations of the star, within these bounds in pixels.
+	windowSigmas = 4.0
+	minWindow    = 6
+	maxWindow    = 48
+
+	// momentIterations bounds the adaptive moment iterations, which stop
+	// once the centroid moves less than momentTolerance pixels.
+	momentIterations = 30
+	momentTolerance  = 1e-3
+
+	// minStarVariance is the smallest second moment, in square pixels, of a
+	// star; sharper sources are hot pixels and cosmic ray hits, which light
+	// a single pixel. A pixel's own extent contributes pixelVariance.
+	minStarVariance = 0.15
+	pixelVariance   = 1.0 / 12
+
+	// edgeMargin is how close to the edge, in pixels, a centroid may lie
+	// before the star is dropped as cut off.
+	edgeMargin = 2.0
+
+	// saturationMargin of the saturation level counts as saturated.
+	saturationMargin = 0.98
+
+	// hfrBin is the width of the radial bins the half-flux radius is
+	// interpolated in.
+	hfrBin = 0.25
+
+	// hfrPerSigma converts a Gaussian's standard deviation to its half-flux
+	// radius.
+	hfrPerSigma = 1.1774100225154747
+
+	// fwhmPerSigma converts a Gaussian's standard deviation to its FWHM.
+	fwhmPerSigma = 2.3548200450309493
+)
+
+// Star is a detected star. Positions are in pixels with the centre of the
+// top-left pixel at (0, 0).
+type Star struct {
+	X    float64 `json:"x"`
+	Y    float64 `json:"y"`
+	Flux float64 `json:"flux"` // ADU above the background within the aperture
+	Peak float64 `json:"peak"` // ADU above the background at the brightest pixel
+	HFR  float64 `json:"hfr"`  // pixels, radius enclosing half the flux
+	FWHM float64 `json:"fwhm"` // pixels, of the Gaussian with the star's second moments
+
+	// The star's shape from its second moments: eccentricity 0 and
+	// elongation 1 for a round star, and the major axis's angle in degrees
+	// from the +x axis towards +y, in [0, 180)
+	Eccentricity float64 `json:"eccentricity"`
+	Elongation   float64 `json:"elongation"`
+	Angle        float64 `json:"angle"`
+
+	SNR       float64 `json:"snr"`
+	Saturated bool    `json:"saturated"`
+}
+
+// candidate is a local maximum of the smoothed image above the threshold.
+type candidate struct {
+	x, y   int
+	signal float32 // smoothed ADU above the background
+}
+
+// detect finds the local maxima of the background-subtracted image,
+// smoothed with a 1-2-1 kernel to suppress noise, that rise more than
+// threshold standard deviations of the smoothed noise. They are returned
+// brightest first.
+//
+// Smoothing averages white noise down by smoothingNoise. Correlated noise
+// averages down less, so when correlated is set the smoothed noise is
+// measured as well and the larger taken.
+func detect(residual []float32, width, height int, noise, threshold float64, correlated bool) []candidate {
+	smoothed := smooth(residual, width, height)
+	noise *= smoothingNoise
+	if correlated {
+		step := max(1, len(smoothed)/noiseSamples)
+		values := make([]float64, 0, len(smoothed)/step+1)
+		for i := 0; i < len(smoothed); i += step {
+			values = append(values, float64(smoothed[i]))
+		}
+		_, sigma := clippedStats(values)
+		noise = max(noise, sigma)
+	}
+	level := float32(threshold * noise)
+
+	var found []candidate
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			v := smoothed[y*width+x]
+			if v <= level || !isPeak(smoothed, width, height, x, y) {
+				continue
+			}
+			found = append(found, candidate{x: x, y: y, signal: v})
+		}
+	}
+	slices.SortFunc(found, func(a, b candidate) int {
+		switch {
+		case a.signal > b.signal:
+			return -1
+		case a.signal < b.signal:
+			return 1
+		}
+		return 0
+	})
+	return found
+}
+
+// isPeak reports whether (x, y) is the brightest pixel within peakRadius,
+// ties going to the first in reading order.
+func isPeak(img []float32, width, height, x, y int) bool {
+	v := img[y*width+x]
+	for ny := max(0, y-peakRadius); ny <= min(height-1, y+peakRadius); ny++ {
+		for nx := max(0, x-peakRadius); nx <= min(width-1, x+peakRadius); nx++ {
+			u := img[ny*width+nx]
+			before := ny < y || ny == y && nx < x
+			if u > v || before && u == v {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// smooth convolves img with the separable 1-2-1 kernel, repeating edge
+// pixels.
+func smooth(img []float32, width, height int) []float32 {
+	rows := make([]float32, len(img))
+	for y := 0; y < height; y++ {
+		row := img[y*width : (y+1)*width]
+		for x := range row {
+			rows[y*width+x] = (row[max(0, x-1)] + 2*row[x] + row[min(width-1, x+1)]) / 4
+		}
+	}
+	out := make([]float32, len(img))
+	for y := 0; y < height; y++ {
+		up, down := max(0, y-1), min(height-1, y+1)
+		for x := 0; x < width; x++ {
+			out[y*width+x] = (rows[up*width+x] + 2*rows[y*width+x] + rows[down*width+x]) / 4
+		}
+	}
+	return out
+}
+
+// moments are a star's adaptive second moments: the centroid and the
+// covariance of the elliptical Gaussian matching its light.
+type moments struct {
+	x, y          float64
+	xx, yy, xy    float64
+	window        int
+	width, height int
+}
+
+// measureMoments fits adaptive moments to the star near (x, y): each
+// iteration weights the pixels by the current Gaussian estimate and, since
+// a Gaussian weighted by itself has half its variance, doubles the
+// weighted covariance for the next. The weighting keeps noise in the wings
+// from swamping faint stars.
+func measureMoments(residual []float32, width, height int, x, y float64) (moments, bool) {
+	m := moments{x: x, y: y, xx: 1.5, yy: 1.5, window: minWindow, width: width, height: height}
+	for pass := 0; pass < 3; pass++ {
+		if !m.iterate(residual) {
+			return m, false
+		}
+		// Refit in a window sized to the star
+		window := int(math.Ceil(windowSigmas * math.Sqrt(max(m.xx, m.yy))))
+		window = max(minWindow, min(maxWindow, window))
+		if window == m.window {
+			break
+		}
+		m.window = window
+	}
+	return m, true
+}
+
+// iterate runs the adaptive moment iterations in m's window.
+func (m *moments) iterate(residual []float32) bool {
+	for i := 0; i < momentIterations; i++ {
+		det := m.xx*m.yy - m.xy*m.xy
+		if det <= 0 {
+			return false
+		}
+		ixx, iyy, ixy := m.yy/det, m.xx/det, -m.xy/det
+
+		cx, cy := int(math.Round(m.x)), int(math.Round(m.y))
+		var sw, sx, sy, sxx, syy, sxy float64
+		for y := max(0, cy-m.window); y <= min(m.height-1, cy+m.window); y++ {
+			for x := max(0, cx-m.window); x <= min(m.width-1, cx+m.window); x++ {
+				dx, dy := float64(x)-m.x, float64(y)-m.y
+				w := math.Exp(-0.5*(dx*dx*ixx+2*dx*dy*ixy+dy*dy*iyy)) * float64(residual[y*m.width+x])
+				sw += w
+				sx += w * dx
+				sy += w * dy
+				sxx += w * dx * dx
+				syy += w * dy * dy
+				sxy += w * dx * dy
+			}
+		}
+		if sw <= 0 {
+			return false
+		}
+		// Moments about the new centroid
+		mx, my := sx/sw, sy/sw
+		nx, ny := m.x+mx, m.y+my
+		mxx, myy, mxy := sxx/sw-mx*mx, syy/sw-my*my, sxy/sw-mx*my
+		moved := math.Hypot(nx-m.x, ny-m.y)
+		m.x, m.y = nx, ny
+		m.xx, m.yy, m.xy = 2*mxx, 2*myy, 2*mxy
+		if math.Abs(m.x-float64(cx)) > float64(m.window) || math.Abs(m.y-float64(cy)) > float64(m.window) {
+			// Wandered off, as it does between close pairs
+			return false
+		}
+		if moved < momentTolerance {
+			break
+		}
+	}
+	return m.xx > 0 && m.yy > 0
+}
+
+// axes returns the variances along the major and minor axes and the major
+// axis's angle in degrees.
+func (m moments) axes() (major, minor, angle float64) {
+	mean := (m.xx + m.yy) / 2
+	diff := math.Hypot((m.xx-m.yy)/2, m.xy)
+	angle = 0.5 * math.Atan2(2*m.xy, m.xx-m.yy) * 180 / math.Pi
+	if angle < 0 {
+		angle += 180
+	}
+	return mean + diff, mean - diff, angle
+}
+
+// measureStar measures the star found at c, reporting false for sources
+// that aren't stars. Stars broader than the largest window are taken for
+// gradients the background missed.
+func measureStar(img *Image, residual []float32, peaks []float32, noise float64, c candidate) (Star, bool) {
+	m, ok := measureMoments(residual, img.Width, img.Height, float64(c.x), float64(c.y))
+	if !ok {
+		return Star{}, false
+	}
+	if m.x < edgeMargin || m.y < edgeMargin || m.x > float64(img.Width-1)-edgeMargin || m.y > float64(img.Height-1)-edgeMargin {
+		return Star{}, false
+	}
+	major, minor, angle := m.axes()
+	if minor < minStarVariance || windowSigmas*math.Sqrt(major) > maxWindow {
+		// Too sharp for a star, or too broad: amp glow or nebulosity
+		return Star{}, false
+	}
+	// The star itself, without the spread of pixel sampling
+	major -= pixelVariance
+	minor -= pixelVariance
+
+	s := Star{
+		X:            m.x,
+		Y:            m.y,
+		FWHM:         fwhmPerSigma * math.Sqrt(math.Sqrt(major*minor)),
+		Eccentricity: math.Sqrt(1 - minor/major),
+		Elongation:   math.Sqrt(major / minor),
+		Angle:        angle,
+	}
+	s.HFR = halfFluxRadius(residual, img.Width, img.Height, m.x, m.y, float64(m.window))
+
+	// Aperture photometry out to where a Gaussian has given up all but a
+	// fraction of a percent of its light
+	aperture := max(3, 1.5*s.FWHM)
+	saturation := saturationMargin * img.saturation()
+	var n int
+	cx, cy := int(math.Round(m.x)), int(math.Round(m.y))
+	r := int(math.Ceil(aperture))
+	for y := max(0, cy-r); y <= min(img.Height-1, cy+r); y++ {
+		for x := max(0, cx-r); x <= min(img.Width-1, cx+r); x++ {
+			if math.Hypot(float64(x)-m.x, float64(y)-m.y) > aperture {
+				continue
+			}
+			i := y*img.Width + x
+			s.Flux += float64(residual[i])
+			s.Peak = max(s.Peak, float64(residual[i]))
+			if float64(peaks[i]) >= saturation {
+				s.Saturated = true
+			}
+			n++
+		}
+	}
+	if s.Flux <= 0 {
+		return Star{}, false
+	}
+	variance := float64(n) * noise * noise
+	if img.ElectronsPerADU > 0 {
+		variance += s.Flux / img.ElectronsPerADU
+	}
+	s.SNR = s.Flux / math.Sqrt(variance)
+	return s, true
+}
+
+// halfFluxRadius returns the radius around (cx, cy) enclosing half the
+// flux within radius, corrected for the spread pixel sampling adds as for
+// a Gaussian star.
+func halfFluxRadius(residual []float32, width, height int, cx, cy, radius float64) float64 {
+	bins := make([]float64, int(radius/hfrBin)+1)
+	var total float64
+	r := int(math.Ceil(radius))
+	x0, y0 := int(math.Round(cx)), int(math.Round(cy))
+	for y := max(0, y0-r); y <= min(height-1, y0+r); y++ {
+		for x := max(0, x0-r); x <= min(width-1, x0+r); x++ {
+			d := math.Hypot(float64(x)-cx, float64(y)-cy)
+			if d >= radius {
+				continue
+			}
+			f := float64(residual[y*width+x])
+			bins[int(d/hfrBin)] += f
+			total += f
+		}
+	}
+	if total <= 0 {
+		return 0
+	}
+	hfr := radius
+	var sum float64
+	for i, b := range bins {
+		if sum+b >= total/2 && b > 0 {
+			hfr = hfrBin * (float64(i) + (total/2-sum)/b)
+			break
+		}
+		sum += b
+	}
+	return math.Sqrt(max(hfr*hfr-hfrPerSigma*hfrPerSigma*pixelVariance, 0))
+}
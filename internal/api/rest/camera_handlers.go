@@ -0,0 +1,390 @@
+package rest
+
+import (
+	"bytes"
+	"fmt"
+	"image/png"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/darkdragonsastro/draco-simulator/internal/camera"
+	"github.com/darkdragonsastro/draco-simulator/internal/debayer"
+	"github.com/darkdragonsastro/draco-simulator/internal/fits"
+	"github.com/darkdragonsastro/draco-simulator/internal/game"
+	"github.com/darkdragonsastro/draco-simulator/internal/xisf"
+	"github.com/gin-gonic/gin"
+)
+
+// defaultPreviewWidth is the width frame previews are binned down to unless
+// the client asks otherwise.
+const defaultPreviewWidth = 1024
+
+// CameraHandlers provides REST endpoints for the virtual camera.
+type CameraHandlers struct {
+	cam *camera.Simulator
+
+	// object names light frames when the request doesn't, usually from the
+	// mount's last named slew
+	object func() string
+}
+
+// NewCameraHandlers creates a new CameraHandlers.
+func NewCameraHandlers(cam *camera.Simulator, object func() string) *CameraHandlers {
+	return &CameraHandlers{cam: cam, object: object}
+}
+
+// withCamera returns the camera, responding 503 when none is configured.
+func (h *CameraHandlers) withCamera(c *gin.Context) (*camera.Simulator, bool) {
+	if h.cam == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "camera not available"})
+		return nil, false
+	}
+	return h.cam, true
+}
+
+func (h *CameraHandlers) getStatus(c *gin.Context) {
+	cam, ok := h.withCamera(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, cam.GetStatus())
+}
+
+func (h *CameraHandlers) connect(c *gin.Context) {
+	cam, ok := h.withCamera(c)
+	if !ok {
+		return
+	}
+	if err := cam.Connect(c.Request.Context()); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "connected"})
+}
+
+func (h *CameraHandlers) disconnect(c *gin.Context) {
+	cam, ok := h.withCamera(c)
+	if !ok {
+		return
+	}
+	if err := cam.Disconnect(); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "disconnected"})
+}
+
+func (h *CameraHandlers) startExposure(c *gin.Context) {
+	cam, ok := h.withCamera(c)
+	if !ok {
+		return
+	}
+	var req camera.ExposureRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Object == "" && h.object != nil {
+		req.Object = h.object()
+	}
+	if err := cam.StartExposure(req); err != nil {
+		respondCameraError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "exposing"})
+}
+
+func (h *CameraHandlers) abortExposure(c *gin.Context) {
+	cam, ok := h.withCamera(c)
+	if !ok {
+		return
+	}
+	if err := cam.AbortExposure(); err != nil {
+		respondCameraError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "aborted"})
+}
+
+// DefocusRequest sets how far out of focus the camera is
+type DefocusRequest struct {
+	HFR float64 `json:"hfr"` // pixels added to star HFR, 0 = in focus
+}
+
+func (h *CameraHandlers) setDefocus(c *gin.Context) {
+	cam, ok := h.withCamera(c)
+	if !ok {
+		return
+	}
+	var req DefocusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := cam.SetDefocus(req.HFR); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, cam.GetStatus())
+}
+
+// setReadout sets binning, subframe and readout mode for the following
+// exposures.
+func (h *CameraHandlers) setReadout(c *gin.Context) {
+	cam, ok := h.withCamera(c)
+	if !ok {
+		return
+	}
+	var req game.Readout
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := cam.SetReadout(req); err != nil {
+		respondCameraError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, cam.GetStatus())
+}
+
+func (h *CameraHandlers) getCooler(c *gin.Context) {
+	cam, ok := h.withCamera(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, cam.CoolerStatus())
+}
+
+func (h *CameraHandlers) setCooler(c *gin.Context) {
+	cam, ok := h.withCamera(c)
+	if !ok {
+		return
+	}
+	var req camera.CoolerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := cam.SetCooler(req); err != nil {
+		respondCameraError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, cam.CoolerStatus())
+}
+
+func (h *CameraHandlers) getFrame(c *gin.Context) {
+	cam, ok := h.withCamera(c)
+	if !ok {
+		return
+	}
+	frame, err := cam.LastFrame()
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"width":  frame.Width,
+		"height": frame.Height,
+		"info":   frame.Info,
+	})
+}
+
+// getImage serves a stretched PNG preview of the last frame. The optional
+// width query parameter bounds the preview size, and debayer picks how raw
+// color frames are demosaiced ("bilinear", default, or "vng"). Like the
+// downloads, it serves the calibrated frame with calibrate=true.
+func (h *CameraHandlers) getImage(c *gin.Context) {
+	cam, ok := h.withCamera(c)
+	if !ok {
+		return
+	}
+	width := defaultPreviewWidth
+	if v := c.Query("width"); v != "" {
+		w, err := strconv.Atoi(v)
+		if err != nil || w <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "width must be a positive integer"})
+			return
+		}
+		width = w
+	}
+	method, err := debayer.ParseMethod(c.DefaultQuery("debayer", string(debayer.Bilinear)))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	frame, ok := requestedFrame(c, cam)
+	if !ok {
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, frame.Preview(width, method)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Data(http.StatusOK, "image/png", buf.Bytes())
+}
+
+// getFITS serves the last frame as a FITS file. The format query parameter
+// selects "int16" (default) or "float32" pixels.
+func (h *CameraHandlers) getFITS(c *gin.Context) {
+	cam, ok := h.withCamera(c)
+	if !ok {
+		return
+	}
+	var bitpix int
+	switch c.DefaultQuery("format", "int16") {
+	case "int16":
+		bitpix = fits.Int16
+	case "float32":
+		bitpix = fits.Float32
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be int16 or float32"})
+		return
+	}
+
+	frame, ok := requestedFrame(c, cam)
+	if !ok {
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := fits.Write(&buf, frame.FITS(bitpix)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", frameFileName(frame, "fits")))
+	c.Data(http.StatusOK, "application/fits", buf.Bytes())
+}
+
+// getXISF serves the last frame as an XISF file. Query parameters select
+// the sample format ("uint16", default, or "float32"), the compression
+// ("none", default, "zlib" or "lz4") and byte shuffling (shuffle=true).
+func (h *CameraHandlers) getXISF(c *gin.Context) {
+	cam, ok := h.withCamera(c)
+	if !ok {
+		return
+	}
+	var format string
+	switch c.DefaultQuery("format", "uint16") {
+	case "uint16":
+		format = xisf.UInt16
+	case "float32":
+		format = xisf.Float32
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be uint16 or float32"})
+		return
+	}
+	var opts xisf.WriteOptions
+	switch c.DefaultQuery("compression", "none") {
+	case "none":
+	case "zlib":
+		opts.Compression = xisf.CompressionZlib
+	case "lz4":
+		opts.Compression = xisf.CompressionLZ4
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "compression must be none, zlib or lz4"})
+		return
+	}
+	if v := c.Query("shuffle"); v != "" {
+		shuffle, err := strconv.ParseBool(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "shuffle must be true or false"})
+			return
+		}
+		opts.Shuffle = shuffle
+	}
+
+	frame, ok := requestedFrame(c, cam)
+	if !ok {
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := xisf.Write(&buf, frame.XISF(format), opts); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", frameFileName(frame, "xisf")))
+	c.Data(http.StatusOK, "application/xisf", buf.Bytes())
+}
+
+// requestedFrame returns the last frame, calibrated with the matching
+// masters when the calibrate query parameter is true, responding with an
+// error when there is none.
+func requestedFrame(c *gin.Context, cam *camera.Simulator) (*camera.Frame, bool) {
+	calibrate := false
+	if v := c.Query("calibrate"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "calibrate must be true or false"})
+			return nil, false
+		}
+		calibrate = b
+	}
+
+	frame, err := cam.LastFrame()
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if !calibrate {
+		return frame, true
+	}
+	frame, err = cam.CalibratedFrame()
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return frame, true
+}
+
+// getCalibration lists the calibration masters built so far.
+func (h *CameraHandlers) getCalibration(c *gin.Context) {
+	cam, ok := h.withCamera(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"masters": cam.CalibrationStatus()})
+}
+
+// resetCalibration discards the calibration masters, e.g. before taking a
+// fresh set for the night.
+func (h *CameraHandlers) resetCalibration(c *gin.Context) {
+	cam, ok := h.withCamera(c)
+	if !ok {
+		return
+	}
+	cam.ResetCalibration()
+	c.JSON(http.StatusOK, gin.H{"masters": cam.CalibrationStatus()})
+}
+
+// frameFileName names a downloaded frame the way capture software names
+// its files: target, type, exposure and start time.
+func frameFileName(frame *camera.Frame, ext string) string {
+	info := frame.Info
+	name := info.Object
+	if name == "" || info.ImageType != camera.ImageLight {
+		name = info.ImageType
+	}
+	name = strings.Map(func(r rune) rune {
+		if r == ' ' || r == '/' || r == '\\' {
+			return '_'
+		}
+		return r
+	}, name)
+	return fmt.Sprintf("%s_%s_%gs_%s.%s", name, info.Filter, info.Duration,
+		info.StartTime.UTC().Format("20060102-150405"), ext)
+}
+
+// respondCameraError reports a rejected camera command. Bad parameters are
+// 400s; commands that conflict with the camera's state are 409s.
+func respondCameraError(c *gin.Context, err error) {
+	if camera.IsInvalidRequest(err) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+}
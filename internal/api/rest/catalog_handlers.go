@@ -1,6 +1,7 @@
 package rest
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
@@ -237,7 +238,7 @@ func (s *Server) getVisibleObjects(c *gin.Context) {
 	maxMag, _ := strconv.ParseFloat(maxMagStr, 64)
 	limit, _ := strconv.Atoi(limitStr)
 
-	now := time.Now().UTC()
+	now := s.skyState.Clock.Now()
 	observer := s.skyState.Observer
 
 	// Get all DSOs via a full-sky cone search
@@ -350,7 +351,7 @@ func (s *Server) suggestTargets(c *gin.Context) {
 	limitStr := c.DefaultQuery("limit", "5")
 	limit, _ := strconv.Atoi(limitStr)
 
-	now := time.Now().UTC()
+	now := s.skyState.Clock.Now()
 	observer := s.skyState.Observer
 
 	// Get bright DSOs
@@ -434,3 +435,27 @@ func (s *Server) suggestTargets(c *gin.Context) {
 		"observer":    observer,
 	})
 }
+
+func (s *Server) resolveObject(c *gin.Context) {
+	name := c.Query("name")
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name parameter required"})
+		return
+	}
+
+	target, err := s.resolver.Resolve(c.Request.Context(), name, s.skyState.Clock.Now())
+	if err != nil {
+		respondResolveError(c, name, err)
+		return
+	}
+	c.JSON(http.StatusOK, target)
+}
+
+// respondResolveError maps name resolution errors to HTTP responses.
+func respondResolveError(c *gin.Context, name string, err error) {
+	if errors.Is(err, catalog.ErrObjectNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "unknown object: " + name})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
@@ -1,12 +1,20 @@
 package rest
 
 import (
+	"io"
+	"mime"
 	"net/http"
+	"strconv"
 
+	"github.com/darkdragonsastro/draco-simulator/internal/analysis"
+	"github.com/darkdragonsastro/draco-simulator/internal/fits"
 	"github.com/darkdragonsastro/draco-simulator/internal/game"
 	"github.com/gin-gonic/gin"
 )
 
+// maxScoreUpload bounds the size of an image uploaded for scoring.
+const maxScoreUpload = 256 << 20
+
 // ProgressResponse contains player progress data
 type ProgressResponse struct {
 	Level                int             `json:"level"`
@@ -18,6 +26,7 @@ type ProgressResponse struct {
 	TotalImages          int             `json:"total_images"`
 	UnlockedAchievements []string        `json:"unlocked_achievements"`
 	AchievementProgress  float64         `json:"achievement_progress"`
+	SessionDuration      float64         `json:"session_duration"` // simulated seconds
 }
 
 func (s *Server) getProgress(c *gin.Context) {
@@ -39,6 +48,7 @@ func (s *Server) getProgress(c *gin.Context) {
 		TotalImages:          state.TotalImages,
 		UnlockedAchievements: state.UnlockedAchievements,
 		AchievementProgress:  game.CalculateAchievementProgress(state.UnlockedAchievements),
+		SessionDuration:      s.gameService.SessionDuration().Seconds(),
 	}
 
 	c.JSON(http.StatusOK, response)
@@ -255,9 +265,83 @@ type ScoreRequest struct {
 	Gain         int     `json:"gain"`
 	MeanADU      float64 `json:"mean_adu"`
 	MaxADU       float64 `json:"max_adu"`
+	PixelScale   float64 `json:"pixel_scale"` // arcsec per binned pixel
+	Binning      int     `json:"binning"`
 }
 
+// scoreImage scores an image. Pixels are measured when the request
+// carries them: a FITS or PNG file in the multipart field "image" or as
+// the body, or the camera's last frame with source=camera. Guide RMS,
+// which pixels don't show, and context a file lacks, such as the pixel
+// scale of a PNG, come from form or query parameters. A JSON body gives
+// the metrics directly.
 func (s *Server) scoreImage(c *gin.Context) {
+	mediaType, _, _ := mime.ParseMediaType(c.ContentType())
+	if c.Query("source") != "camera" && (mediaType == "" || mediaType == "application/json") {
+		s.scoreMetrics(c)
+		return
+	}
+
+	img, ok := s.scoreUpload(c)
+	if !ok {
+		return
+	}
+	for _, p := range []struct {
+		key string
+		dst *float64
+	}{
+		{"pixel_scale", &img.PixelScale},
+		{"exposure_time", &img.Exposure},
+		{"electrons_per_adu", &img.ElectronsPerADU},
+	} {
+		if v := scoreParam(c, p.key); v != "" {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil || f < 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": p.key + " must be a non-negative number"})
+				return
+			}
+			*p.dst = f
+		}
+	}
+	for _, p := range []struct {
+		key string
+		dst *int
+	}{
+		{"gain", &img.Gain},
+		{"binning", &img.Binning},
+	} {
+		if v := scoreParam(c, p.key); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": p.key + " must be a non-negative integer"})
+				return
+			}
+			*p.dst = n
+		}
+	}
+
+	result, err := analysis.Analyze(img, analysis.Options{})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	metrics := result.Metrics()
+	if v := scoreParam(c, "guide_rms"); v != "" {
+		rms, err := strconv.ParseFloat(v, 64)
+		if err != nil || rms < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "guide_rms must be a non-negative number"})
+			return
+		}
+		metrics.GuideRMS = rms
+	}
+
+	score := game.NewImageScorer().ScoreImage(metrics)
+	score.Metrics = &metrics
+	c.JSON(http.StatusOK, score)
+}
+
+// scoreMetrics scores the metrics of a JSON ScoreRequest.
+func (s *Server) scoreMetrics(c *gin.Context) {
 	var req ScoreRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -275,8 +359,58 @@ func (s *Server) scoreImage(c *gin.Context) {
 		Gain:         req.Gain,
 		MeanADU:      req.MeanADU,
 		MaxADU:       req.MaxADU,
+		PixelScale:   req.PixelScale,
+		Binning:      req.Binning,
 	}
 
 	result := scorer.ScoreImage(metrics)
 	c.JSON(http.StatusOK, result)
 }
+
+// scoreUpload decodes the image a score request carries, responding with
+// an error when there is none.
+func (s *Server) scoreUpload(c *gin.Context) (*analysis.Image, bool) {
+	if c.Query("source") == "camera" {
+		if s.cameraHandlers.cam == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "camera not available"})
+			return nil, false
+		}
+		frame, err := s.cameraHandlers.cam.LastFrame()
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return nil, false
+		}
+		return analysis.FromFITS(frame.FITS(fits.Int16)), true
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxScoreUpload)
+	var body io.Reader = c.Request.Body
+	if c.ContentType() == "multipart/form-data" {
+		file, err := c.FormFile("image")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "multipart upload needs an image field"})
+			return nil, false
+		}
+		f, err := file.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return nil, false
+		}
+		defer f.Close()
+		body = f
+	}
+	img, err := analysis.Decode(body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return img, true
+}
+
+// scoreParam returns a score request's form value, or its query parameter.
+func scoreParam(c *gin.Context, key string) string {
+	if v, ok := c.GetPostForm(key); ok {
+		return v
+	}
+	return c.Query(key)
+}
@@ -1,70 +1,280 @@
 package rest
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"sync"
+	"time"
 
+	"github.com/darkdragonsastro/draco-simulator/internal/api/websocket"
+	"github.com/darkdragonsastro/draco-simulator/internal/catalog"
+	"github.com/darkdragonsastro/draco-simulator/internal/device"
 	"github.com/darkdragonsastro/draco-simulator/internal/mount"
+	"github.com/darkdragonsastro/draco-simulator/internal/simclock"
 	"github.com/gin-gonic/gin"
 )
 
-// MountHandlers provides REST endpoints for mount control.
+// errSimulatorOnly is returned for features only the simulated mount has,
+// such as injected pointing errors, when a real mount is active.
+var errSimulatorOnly = errors.New("not supported by the active mount driver")
+
+// MountHandlers provides REST endpoints for mount control. Commands go to the
+// driver for the active equipment profile's mount: the simulator for virtual
+// mounts, or an Alpaca or INDI client for real ones.
 type MountHandlers struct {
-	sim *mount.Simulator
+	sim      *mount.Simulator
+	profiles *device.ProfileManager
+	targets  *catalog.Resolver // resolves slew targets given by name
+	clock    *simclock.Clock
+
+	mu        sync.Mutex
+	driver    mount.Driver
+	driverKey string // identifies the profile device the driver was built for
+	onStatus  func(mount.MountStatus)
+	target    string // name of the last target slewed to by name
 }
 
 // NewMountHandlers creates a new MountHandlers.
-func NewMountHandlers(sim *mount.Simulator) *MountHandlers {
-	return &MountHandlers{sim: sim}
+func NewMountHandlers(sim *mount.Simulator, profiles *device.ProfileManager, targets *catalog.Resolver, clock *simclock.Clock) *MountHandlers {
+	return &MountHandlers{sim: sim, profiles: profiles, targets: targets, clock: clock, driver: sim}
+}
+
+// mountDriver returns the driver for the active profile's mount, replacing
+// the current one when the profile or its mount changed. Without a profile
+// manager or an active profile the simulator is used.
+func (h *MountHandlers) mountDriver() (mount.Driver, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.profiles == nil {
+		return h.sim, nil
+	}
+	profile, err := h.profiles.GetActiveProfile()
+	if err != nil {
+		h.useDriver(h.sim, "")
+		return h.sim, nil
+	}
+
+	key := driverKey(profile)
+	if key != "" && key == h.driverKey && h.driver != nil {
+		return h.driver, nil
+	}
+
+	driver, err := mount.NewDriver(profile, h.sim)
+	if err != nil {
+		return nil, err
+	}
+	h.useDriver(driver, key)
+	return driver, nil
+}
+
+// useDriver makes driver, built for the profile device key identifies, the
+// active one: the driver it replaces stops streaming status and, unless
+// it's the simulator, is disconnected. Must be called with mu held.
+func (h *MountHandlers) useDriver(driver mount.Driver, key string) {
+	if h.driver != nil && h.driver != driver {
+		// Only the active mount streams status
+		h.driver.SetStatusHandler(nil)
+		if h.driver != mount.Driver(h.sim) {
+			h.driver.Disconnect()
+		}
+	}
+	if h.onStatus != nil {
+		driver.SetStatusHandler(h.onStatus)
+	}
+	h.driver, h.driverKey = driver, key
+}
+
+// driverKey identifies the profile's mount device: the profile, and the
+// device and how it connects. The connection config is encoded as JSON,
+// whose object keys come sorted, so equal configs give equal keys.
+func driverKey(profile *device.EquipmentProfile) string {
+	dev := mount.MountDevice(profile)
+	if dev == nil {
+		return profile.ID
+	}
+	config, err := json.Marshal(dev.ConnectionConfig)
+	if err != nil {
+		// An empty key never matches, so the driver is rebuilt
+		return ""
+	}
+	return fmt.Sprintf("%s/%s/%s/%s", profile.ID, dev.ID, dev.ConnectionType, config)
+}
+
+// withDriver resolves the active driver, responding with an error if that
+// fails.
+func (h *MountHandlers) withDriver(c *gin.Context) (mount.Driver, bool) {
+	driver, err := h.mountDriver()
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return driver, true
+}
+
+// simulator returns the simulator if it is the active driver, responding
+// with 501 otherwise.
+func (h *MountHandlers) simulator(c *gin.Context) (*mount.Simulator, bool) {
+	driver, ok := h.withDriver(c)
+	if !ok {
+		return nil, false
+	}
+	if driver != mount.Driver(h.sim) {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": errSimulatorOnly.Error()})
+		return nil, false
+	}
+	return h.sim, true
 }
 
 func (h *MountHandlers) getStatus(c *gin.Context) {
-	c.JSON(http.StatusOK, h.sim.GetStatus())
+	driver, ok := h.withDriver(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, driver.GetStatus())
+}
+
+// SlewRequest slews to coordinates, or to a named target when Target is set
+type SlewRequest struct {
+	RA     float64 `json:"ra"`     // hours
+	Dec    float64 `json:"dec"`    // degrees
+	Target string  `json:"target"` // e.g. "M31", "Vega", "HIP 91262", "Jupiter"
 }
 
 func (h *MountHandlers) slewTo(c *gin.Context) {
-	var req struct {
-		RA  float64 `json:"ra"`
-		Dec float64 `json:"dec"`
-	}
+	var req SlewRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	if err := h.sim.SlewTo(c.Request.Context(), req.RA, req.Dec); err != nil {
-		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	var target *catalog.Target
+	if req.Target != "" {
+		if h.targets == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "catalogs not available"})
+			return
+		}
+		var err error
+		target, err = h.targets.Resolve(c.Request.Context(), req.Target, h.clock.Now())
+		if err != nil {
+			respondResolveError(c, req.Target, err)
+			return
+		}
+		if target.Kind == catalog.TargetKindSun {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "refusing to slew to the Sun"})
+			return
+		}
+		req.RA, req.Dec = target.RA/15.0, target.Dec
+	}
+
+	driver, ok := h.withDriver(c)
+	if !ok {
 		return
 	}
+	if err := driver.SlewTo(c.Request.Context(), req.RA, req.Dec); err != nil {
+		respondMountError(c, err)
+		return
+	}
+
+	h.mu.Lock()
+	h.target = ""
+	if target != nil {
+		h.target = target.Name
+	}
+	h.mu.Unlock()
 
+	if target != nil {
+		c.JSON(http.StatusOK, gin.H{"status": "slewing", "target": target})
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{"status": "slewing"})
 }
 
+// targetName returns the name of the object the mount was last sent to, or
+// "" after a slew to bare coordinates.
+func (h *MountHandlers) targetName() string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.target
+}
+
 func (h *MountHandlers) stopSlew(c *gin.Context) {
-	h.sim.StopSlew()
+	driver, ok := h.withDriver(c)
+	if !ok {
+		return
+	}
+	if err := driver.StopSlew(); err != nil {
+		respondMountError(c, err)
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{"status": "stopped"})
 }
 
 func (h *MountHandlers) setTracking(c *gin.Context) {
 	var req struct {
 		Mode string `json:"mode"`
+		Body string `json:"body"` // required for "body" mode, e.g. "moon" or "jupiter"
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	driver, ok := h.withDriver(c)
+	if !ok {
+		return
+	}
+
+	var err error
 	switch req.Mode {
 	case "off", "sidereal", "lunar", "solar":
-		// valid
+		err = driver.SetTracking(req.Mode)
+	case "body":
+		sim, ok := h.simulator(c)
+		if !ok {
+			return
+		}
+		err = sim.TrackBody(req.Body)
 	default:
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid tracking mode"})
 		return
 	}
+	if err != nil {
+		respondMountError(c, err)
+		return
+	}
 
-	h.sim.SetTracking(req.Mode)
 	c.JSON(http.StatusOK, gin.H{"status": "tracking", "mode": req.Mode})
 }
 
+// TrackingRatesRequest sets ASCOM-style tracking rate offsets
+type TrackingRatesRequest struct {
+	RightAscensionRate float64 `json:"right_ascension_rate"` // seconds of RA per sidereal second
+	DeclinationRate    float64 `json:"declination_rate"`     // arcsec per second
+}
+
+func (h *MountHandlers) setTrackingRates(c *gin.Context) {
+	var req TrackingRatesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sim, ok := h.simulator(c)
+	if !ok {
+		return
+	}
+	if err := sim.SetTrackingRates(req.RightAscensionRate, req.DeclinationRate); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, req)
+}
+
 func (h *MountHandlers) jog(c *gin.Context) {
 	var req struct {
 		Direction string  `json:"direction"`
@@ -83,26 +293,442 @@ func (h *MountHandlers) jog(c *gin.Context) {
 		return
 	}
 
-	h.sim.Jog(req.Direction, req.Rate)
+	sim, ok := h.simulator(c)
+	if !ok {
+		return
+	}
+	if err := sim.Jog(req.Direction, req.Rate); err != nil {
+		respondMountError(c, err)
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{"status": "jogged"})
 }
 
+func (h *MountHandlers) sync(c *gin.Context) {
+	var req struct {
+		RA  float64 `json:"ra"`
+		Dec float64 `json:"dec"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	driver, ok := h.withDriver(c)
+	if !ok {
+		return
+	}
+	if err := driver.Sync(req.RA, req.Dec); err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+
+	if driver != mount.Driver(h.sim) {
+		c.JSON(http.StatusOK, driver.GetStatus())
+		return
+	}
+	c.JSON(http.StatusOK, h.sim.GetPointing())
+}
+
+func (h *MountHandlers) getPointing(c *gin.Context) {
+	sim, ok := h.simulator(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, sim.GetPointing())
+}
+
+func (h *MountHandlers) setPointingErrors(c *gin.Context) {
+	var req mount.PointingModel
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sim, ok := h.simulator(c)
+	if !ok {
+		return
+	}
+	sim.SetPointingErrors(req)
+	c.JSON(http.StatusOK, sim.GetPointing())
+}
+
+func (h *MountHandlers) clearAlignment(c *gin.Context) {
+	sim, ok := h.simulator(c)
+	if !ok {
+		return
+	}
+	sim.ClearAlignment()
+	c.JSON(http.StatusOK, sim.GetPointing())
+}
+
+func (h *MountHandlers) getLimits(c *gin.Context) {
+	sim, ok := h.simulator(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, sim.GetLimits())
+}
+
+func (h *MountHandlers) setLimits(c *gin.Context) {
+	var req mount.Limits
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sim, ok := h.simulator(c)
+	if !ok {
+		return
+	}
+	if err := sim.SetLimits(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, sim.GetLimits())
+}
+
+// respondMountError maps mount errors to HTTP responses. Limit violations
+// include the offending limit so the UI can explain why a move was refused.
+func respondMountError(c *gin.Context, err error) {
+	var limitErr *mount.LimitError
+	if errors.As(err, &limitErr) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "limit": limitErr})
+		return
+	}
+	c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+}
+
+// PulseGuideRequest contains a guide pulse command
+type PulseGuideRequest struct {
+	Direction  string `json:"direction"`   // "north"|"south"|"east"|"west"
+	DurationMs int    `json:"duration_ms"` // pulse length in milliseconds
+}
+
+func (h *MountHandlers) pulseGuide(c *gin.Context) {
+	var req PulseGuideRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	driver, ok := h.withDriver(c)
+	if !ok {
+		return
+	}
+	if err := driver.PulseGuide(req.Direction, time.Duration(req.DurationMs)*time.Millisecond); err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "guiding", "direction": req.Direction, "duration_ms": req.DurationMs})
+}
+
+// GuideRatesRequest sets guide rates as fractions of sidereal
+type GuideRatesRequest struct {
+	RA  float64 `json:"ra"`
+	Dec float64 `json:"dec"`
+}
+
+func (h *MountHandlers) setGuideRates(c *gin.Context) {
+	var req GuideRatesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sim, ok := h.simulator(c)
+	if !ok {
+		return
+	}
+	if err := sim.SetGuideRates(req.RA, req.Dec); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"guide_rate_ra": req.RA, "guide_rate_dec": req.Dec})
+}
+
+// BalanceRequest sets which side of the RA axis is heavier
+type BalanceRequest struct {
+	Balance string `json:"balance" binding:"required"` // "balanced"|"east_heavy"|"west_heavy"
+}
+
+func (h *MountHandlers) setBalance(c *gin.Context) {
+	var req BalanceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sim, ok := h.simulator(c)
+	if !ok {
+		return
+	}
+	if err := sim.SetBalance(req.Balance); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"balance": req.Balance})
+}
+
+// registerCommands exposes mount commands over the WebSocket hub and streams
+// the active mount's status to it.
+func (h *MountHandlers) registerCommands(hub *websocket.Hub) {
+	h.mu.Lock()
+	h.onStatus = func(status mount.MountStatus) {
+		hub.Broadcast(websocket.EventMountPosition, status)
+	}
+	if h.driver != nil {
+		h.driver.SetStatusHandler(h.onStatus)
+	}
+	h.mu.Unlock()
+
+	hub.RegisterCommand(websocket.CommandMountPulseGuide, func(data json.RawMessage) (any, error) {
+		var req PulseGuideRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			return nil, err
+		}
+		driver, err := h.mountDriver()
+		if err != nil {
+			return nil, err
+		}
+		if err := driver.PulseGuide(req.Direction, time.Duration(req.DurationMs)*time.Millisecond); err != nil {
+			return nil, err
+		}
+		return req, nil
+	})
+
+	hub.RegisterCommand(websocket.CommandMountSetGuideRates, func(data json.RawMessage) (any, error) {
+		var req GuideRatesRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			return nil, err
+		}
+		if driver, err := h.mountDriver(); err != nil {
+			return nil, err
+		} else if driver != mount.Driver(h.sim) {
+			return nil, errSimulatorOnly
+		}
+		if err := h.sim.SetGuideRates(req.RA, req.Dec); err != nil {
+			return nil, err
+		}
+		return req, nil
+	})
+}
+
+func (h *MountHandlers) flipMeridian(c *gin.Context) {
+	sim, ok := h.simulator(c)
+	if !ok {
+		return
+	}
+	if err := sim.FlipMeridian(); err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "flipping"})
+}
+
 func (h *MountHandlers) park(c *gin.Context) {
-	h.sim.Park()
-	c.JSON(http.StatusOK, gin.H{"status": "parked"})
+	driver, ok := h.withDriver(c)
+	if !ok {
+		return
+	}
+	if err := driver.Park(); err != nil {
+		respondMountError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "parking"})
+}
+
+// telemetryRange reads the sample range from the query: "from" and "to" as
+// RFC 3339 times, or "last" as a duration before the current simulation time
+// (e.g. "10m"). With neither, every sample is returned.
+func (h *MountHandlers) telemetryRange(c *gin.Context) (from, to time.Time, ok bool) {
+	if last := c.Query("last"); last != "" {
+		d, err := time.ParseDuration(last)
+		if err != nil || d <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid last, use a duration such as 10m"})
+			return from, to, false
+		}
+		return h.clock.Now().Add(-d), time.Time{}, true
+	}
+
+	for _, p := range []struct {
+		name string
+		dst  *time.Time
+	}{{"from", &from}, {"to", &to}} {
+		if v := c.Query(p.name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + p.name + " time format, use RFC3339"})
+				return from, to, false
+			}
+			*p.dst = t
+		}
+	}
+	return from, to, true
+}
+
+func (h *MountHandlers) getTelemetry(c *gin.Context) {
+	sim, ok := h.simulator(c)
+	if !ok {
+		return
+	}
+	from, to, ok := h.telemetryRange(c)
+	if !ok {
+		return
+	}
+
+	rec := sim.Telemetry()
+	samples := rec.Range(from, to)
+	c.JSON(http.StatusOK, gin.H{
+		"samples":  samples,
+		"count":    len(samples),
+		"capacity": rec.Capacity(),
+	})
+}
+
+// exportTelemetry returns the telemetry range as a CSV download.
+func (h *MountHandlers) exportTelemetry(c *gin.Context) {
+	sim, ok := h.simulator(c)
+	if !ok {
+		return
+	}
+	from, to, ok := h.telemetryRange(c)
+	if !ok {
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := mount.WriteTelemetryCSV(&buf, sim.Telemetry().Range(from, to)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Content-Disposition", `attachment; filename="mount-telemetry.csv"`)
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+}
+
+func (h *MountHandlers) clearTelemetry(c *gin.Context) {
+	sim, ok := h.simulator(c)
+	if !ok {
+		return
+	}
+	sim.Telemetry().Clear()
+	c.JSON(http.StatusOK, gin.H{"status": "cleared"})
+}
+
+func (h *MountHandlers) findHome(c *gin.Context) {
+	sim, ok := h.simulator(c)
+	if !ok {
+		return
+	}
+	if err := sim.FindHome(); err != nil {
+		respondMountError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "homing"})
+}
+
+func (h *MountHandlers) getParkPosition(c *gin.Context) {
+	sim, ok := h.simulator(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, sim.GetParkPosition())
+}
+
+func (h *MountHandlers) setParkPosition(c *gin.Context) {
+	var req mount.ParkPosition
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sim, ok := h.simulator(c)
+	if !ok {
+		return
+	}
+	if err := sim.SetParkPosition(req); err != nil {
+		respondMountError(c, err)
+		return
+	}
+	h.saveParkPosition(c, req)
+}
+
+// setParkToCurrent makes wherever the mount points now the park position.
+func (h *MountHandlers) setParkToCurrent(c *gin.Context) {
+	sim, ok := h.simulator(c)
+	if !ok {
+		return
+	}
+	pos, err := sim.SetParkToCurrent()
+	if err != nil {
+		respondMountError(c, err)
+		return
+	}
+	h.saveParkPosition(c, pos)
+}
+
+// saveParkPosition stores the park position in the active profile's mount
+// settings so it survives restarts, then responds with it.
+func (h *MountHandlers) saveParkPosition(c *gin.Context, pos mount.ParkPosition) {
+	if h.profiles != nil {
+		if profile, err := h.profiles.GetActiveProfile(); err == nil && mount.MountDevice(profile) != nil {
+			// Edit a copy; the manager's profile is shared
+			updated := *profile
+			updated.Devices = append([]device.DeviceProfile(nil), profile.Devices...)
+			dev := mount.MountDevice(&updated)
+			cfg := make(map[string]any, len(dev.ConnectionConfig)+2)
+			for k, v := range dev.ConnectionConfig {
+				cfg[k] = v
+			}
+			cfg["park_alt"] = pos.Alt
+			cfg["park_az"] = pos.Az
+			dev.ConnectionConfig = cfg
+
+			if err := h.profiles.UpdateProfile(&updated); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+		}
+	}
+	c.JSON(http.StatusOK, pos)
 }
 
 func (h *MountHandlers) unpark(c *gin.Context) {
-	h.sim.Unpark()
+	driver, ok := h.withDriver(c)
+	if !ok {
+		return
+	}
+	if err := driver.Unpark(); err != nil {
+		respondMountError(c, err)
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{"status": "unparked"})
 }
 
 func (h *MountHandlers) connect(c *gin.Context) {
-	h.sim.Connect()
+	driver, ok := h.withDriver(c)
+	if !ok {
+		return
+	}
+	if err := driver.Connect(c.Request.Context()); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{"status": "connected"})
 }
 
 func (h *MountHandlers) disconnect(c *gin.Context) {
-	h.sim.Disconnect()
+	driver, ok := h.withDriver(c)
+	if !ok {
+		return
+	}
+	if err := driver.Disconnect(); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{"status": "disconnected"})
 }
@@ -3,30 +3,35 @@ package rest
 import (
 	"net/http"
 
+	"github.com/darkdragonsastro/draco-simulator/internal/api/websocket"
+	"github.com/darkdragonsastro/draco-simulator/internal/camera"
 	"github.com/darkdragonsastro/draco-simulator/internal/catalog"
 	"github.com/darkdragonsastro/draco-simulator/internal/device"
 	"github.com/darkdragonsastro/draco-simulator/internal/game"
 	"github.com/darkdragonsastro/draco-simulator/internal/mount"
+	"github.com/darkdragonsastro/draco-simulator/internal/simclock"
 	"github.com/gin-gonic/gin"
 )
 
 // Server holds the HTTP server and its dependencies
 type Server struct {
-	router         *gin.Engine
-	gameService    *game.Service
-	starCatalog    catalog.StarCatalog
-	dsoCatalog     catalog.DSOCatalog
-	skyState       *SkyState
-	profileManager *device.ProfileManager
-	deviceHandlers *DeviceHandlers
-	mountHandlers  *MountHandlers
+	router           *gin.Engine
+	gameService      *game.Service
+	starCatalog      catalog.StarCatalog
+	dsoCatalog       catalog.DSOCatalog
+	resolver         *catalog.Resolver
+	skyState         *SkyState
+	profileManager   *device.ProfileManager
+	deviceHandlers   *DeviceHandlers
+	mountHandlers    *MountHandlers
+	cameraHandlers   *CameraHandlers
+	stackingHandlers *StackingHandlers
 }
 
 // SkyState holds the current sky simulation state
 type SkyState struct {
 	Observer     catalog.Observer
-	TimeOffset   float64 // Hours offset from real time (0 = real time)
-	UseRealTime  bool
+	Clock        *simclock.Clock // simulation time shared with the mount and game
 	Conditions   SkyConditions
 }
 
@@ -37,6 +42,7 @@ type SkyConditions struct {
 	CloudCover   float64 `json:"cloud_cover"`   // 0-1 scale
 	BortleClass  int     `json:"bortle_class"`  // 1-9
 	Temperature  float64 `json:"temperature"`   // Celsius
+	Pressure     float64 `json:"pressure"`      // hPa, drives refraction
 	Humidity     float64 `json:"humidity"`      // 0-100%
 	WindSpeed    float64 `json:"wind_speed"`    // m/s
 }
@@ -48,7 +54,7 @@ type Config struct {
 }
 
 // NewServer creates a new HTTP server
-func NewServer(cfg Config, gameService *game.Service, starCatalog catalog.StarCatalog, dsoCatalog catalog.DSOCatalog, mountSim *mount.Simulator) *Server {
+func NewServer(cfg Config, gameService *game.Service, starCatalog catalog.StarCatalog, dsoCatalog catalog.DSOCatalog, mountSim *mount.Simulator, cam *camera.Simulator, clock *simclock.Clock) *Server {
 	if !cfg.Debug {
 		gin.SetMode(gin.ReleaseMode)
 	}
@@ -56,33 +62,47 @@ func NewServer(cfg Config, gameService *game.Service, starCatalog catalog.StarCa
 	// Initialize profile manager with data directory
 	profileManager := device.NewProfileManager("./data")
 
-	s := &Server{
-		router:         gin.New(),
-		gameService:    gameService,
-		starCatalog:    starCatalog,
-		dsoCatalog:     dsoCatalog,
-		profileManager: profileManager,
-		deviceHandlers: NewDeviceHandlers(profileManager),
-		mountHandlers:  NewMountHandlers(mountSim),
-		skyState: &SkyState{
-			Observer: catalog.Observer{
-				Latitude:  34.0522,  // Default: Los Angeles
-				Longitude: -118.2437,
-				Elevation: 100,
-			},
-			UseRealTime: true,
-			Conditions: SkyConditions{
-				Seeing:       2.5,
-				Transparency: 0.8,
-				CloudCover:   0.0,
-				BortleClass:  6,
-				Temperature:  15.0,
-				Humidity:     50.0,
-				WindSpeed:    5.0,
-			},
+	skyState := &SkyState{
+		Observer: catalog.Observer{
+			Latitude:  34.0522,  // Default: Los Angeles
+			Longitude: -118.2437,
+			Elevation: 100,
+		},
+		Clock: clock,
+		Conditions: SkyConditions{
+			Seeing:       2.5,
+			Transparency: 0.8,
+			CloudCover:   0.0,
+			BortleClass:  6,
+			Temperature:  15.0,
+			Pressure:     1001.3, // standard pressure at 100 m
+			Humidity:     50.0,
+			WindSpeed:    5.0,
 		},
 	}
 
+	// Name lookups see location changes through the shared observer
+	resolver := catalog.NewResolver(starCatalog, &skyState.Observer, dsoCatalog)
+
+	mountHandlers := NewMountHandlers(mountSim, profileManager, resolver, clock)
+
+	s := &Server{
+		router:           gin.New(),
+		gameService:      gameService,
+		starCatalog:      starCatalog,
+		dsoCatalog:       dsoCatalog,
+		resolver:         resolver,
+		profileManager:   profileManager,
+		deviceHandlers:   NewDeviceHandlers(profileManager),
+		mountHandlers:    mountHandlers,
+		cameraHandlers:   NewCameraHandlers(cam, mountHandlers.targetName),
+		stackingHandlers: NewStackingHandlers(),
+		skyState:         skyState,
+	}
+
+	s.applyConditions()
+	s.applySite()
+
 	s.router.Use(gin.Recovery())
 	s.router.Use(corsMiddleware())
 
@@ -139,6 +159,7 @@ func (s *Server) setupRoutes() {
 		catalogGroup.GET("/dso/:id", s.getDSO)
 		catalogGroup.GET("/dso/messier", s.getMessierCatalog)
 
+		catalogGroup.GET("/resolve", s.resolveObject)
 		catalogGroup.GET("/visible", s.getVisibleObjects)
 		catalogGroup.GET("/suggest", s.suggestTargets)
 	}
@@ -168,13 +189,61 @@ func (s *Server) setupRoutes() {
 		mountGroup.POST("/slew", s.mountHandlers.slewTo)
 		mountGroup.POST("/stop", s.mountHandlers.stopSlew)
 		mountGroup.POST("/track", s.mountHandlers.setTracking)
+		mountGroup.PUT("/trackingrates", s.mountHandlers.setTrackingRates)
 		mountGroup.POST("/jog", s.mountHandlers.jog)
+		mountGroup.POST("/sync", s.mountHandlers.sync)
+		mountGroup.GET("/pointing", s.mountHandlers.getPointing)
+		mountGroup.PUT("/pointing/errors", s.mountHandlers.setPointingErrors)
+		mountGroup.DELETE("/pointing", s.mountHandlers.clearAlignment)
+		mountGroup.POST("/pulseguide", s.mountHandlers.pulseGuide)
+		mountGroup.PUT("/guiderates", s.mountHandlers.setGuideRates)
+		mountGroup.PUT("/balance", s.mountHandlers.setBalance)
+		mountGroup.GET("/limits", s.mountHandlers.getLimits)
+		mountGroup.PUT("/limits", s.mountHandlers.setLimits)
+		mountGroup.POST("/flip", s.mountHandlers.flipMeridian)
 		mountGroup.POST("/park", s.mountHandlers.park)
 		mountGroup.POST("/unpark", s.mountHandlers.unpark)
+		mountGroup.GET("/parkposition", s.mountHandlers.getParkPosition)
+		mountGroup.PUT("/parkposition", s.mountHandlers.setParkPosition)
+		mountGroup.POST("/parkposition/current", s.mountHandlers.setParkToCurrent)
+		mountGroup.POST("/home", s.mountHandlers.findHome)
+		mountGroup.GET("/telemetry", s.mountHandlers.getTelemetry)
+		mountGroup.GET("/telemetry/csv", s.mountHandlers.exportTelemetry)
+		mountGroup.DELETE("/telemetry", s.mountHandlers.clearTelemetry)
 		mountGroup.POST("/connect", s.mountHandlers.connect)
 		mountGroup.POST("/disconnect", s.mountHandlers.disconnect)
 	}
 
+	// Camera endpoints
+	cameraGroup := api.Group("/camera")
+	{
+		cameraGroup.GET("/status", s.cameraHandlers.getStatus)
+		cameraGroup.POST("/expose", s.cameraHandlers.startExposure)
+		cameraGroup.POST("/abort", s.cameraHandlers.abortExposure)
+		cameraGroup.PUT("/defocus", s.cameraHandlers.setDefocus)
+		cameraGroup.PUT("/readout", s.cameraHandlers.setReadout)
+		cameraGroup.GET("/cooler", s.cameraHandlers.getCooler)
+		cameraGroup.PUT("/cooler", s.cameraHandlers.setCooler)
+		cameraGroup.GET("/frame", s.cameraHandlers.getFrame)
+		cameraGroup.GET("/image", s.cameraHandlers.getImage)
+		cameraGroup.GET("/image/fits", s.cameraHandlers.getFITS)
+		cameraGroup.GET("/image/xisf", s.cameraHandlers.getXISF)
+		cameraGroup.GET("/calibration", s.cameraHandlers.getCalibration)
+		cameraGroup.DELETE("/calibration", s.cameraHandlers.resetCalibration)
+		cameraGroup.POST("/connect", s.cameraHandlers.connect)
+		cameraGroup.POST("/disconnect", s.cameraHandlers.disconnect)
+	}
+
+	// Stacking endpoints
+	stackingGroup := api.Group("/stacking")
+	{
+		stackingGroup.POST("", s.stackingHandlers.stack)
+		stackingGroup.GET("/gallery", s.stackingHandlers.getGallery)
+		stackingGroup.GET("/gallery/:id", s.stackingHandlers.getEntry)
+		stackingGroup.GET("/gallery/:id/image", s.stackingHandlers.getEntryImage)
+		stackingGroup.DELETE("/gallery/:id", s.stackingHandlers.deleteEntry)
+	}
+
 	// Device/Profile endpoints
 	deviceGroup := api.Group("/devices")
 	{
@@ -198,6 +267,11 @@ func (s *Server) setupRoutes() {
 	}
 }
 
+// RegisterWebSocketCommands exposes device commands over the WebSocket hub
+func (s *Server) RegisterWebSocketCommands(hub *websocket.Hub) {
+	s.mountHandlers.registerCommands(hub)
+}
+
 // Handler returns the HTTP handler
 func (s *Server) Handler() http.Handler {
 	return s.router
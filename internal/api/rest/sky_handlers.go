@@ -4,7 +4,9 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/darkdragonsastro/draco-simulator/internal/camera"
 	"github.com/darkdragonsastro/draco-simulator/internal/catalog"
+	"github.com/darkdragonsastro/draco-simulator/internal/simclock"
 	"github.com/gin-gonic/gin"
 )
 
@@ -19,6 +21,7 @@ type SetConditionsRequest struct {
 	CloudCover   *float64 `json:"cloud_cover"`
 	BortleClass  *int     `json:"bortle_class"`
 	Temperature  *float64 `json:"temperature"`
+	Pressure     *float64 `json:"pressure"`
 	Humidity     *float64 `json:"humidity"`
 	WindSpeed    *float64 `json:"wind_speed"`
 }
@@ -46,31 +49,71 @@ func (s *Server) setSkyConditions(c *gin.Context) {
 	if req.Temperature != nil {
 		s.skyState.Conditions.Temperature = *req.Temperature
 	}
+	if req.Pressure != nil {
+		if *req.Pressure <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "pressure must be positive"})
+			return
+		}
+		s.skyState.Conditions.Pressure = *req.Pressure
+	}
 	if req.Humidity != nil {
 		s.skyState.Conditions.Humidity = *req.Humidity
 	}
 	if req.WindSpeed != nil {
 		s.skyState.Conditions.WindSpeed = *req.WindSpeed
 	}
+	s.applyConditions()
 
 	c.JSON(http.StatusOK, s.skyState.Conditions)
 }
 
+// applyConditions passes temperature and pressure on to the observer and
+// the mount so refraction follows the sky conditions, and the seeing and
+// sky brightness on to the camera.
+func (s *Server) applyConditions() {
+	s.skyState.Observer.Temperature = s.skyState.Conditions.Temperature
+	s.skyState.Observer.Pressure = s.skyState.Conditions.Pressure
+	if s.mountHandlers.sim != nil {
+		s.mountHandlers.sim.SetAtmosphere(s.skyState.Observer.Atmosphere())
+	}
+	if s.cameraHandlers.cam != nil {
+		s.cameraHandlers.cam.SetConditions(camera.Conditions{
+			Seeing:       s.skyState.Conditions.Seeing,
+			Transparency: s.skyState.Conditions.Transparency,
+			CloudCover:   s.skyState.Conditions.CloudCover,
+			BortleClass:  s.skyState.Conditions.BortleClass,
+			Temperature:  s.skyState.Conditions.Temperature,
+		})
+	}
+}
+
+// applySite passes the observer's location on to the camera for frame
+// headers.
+func (s *Server) applySite() {
+	if s.cameraHandlers.cam != nil {
+		s.cameraHandlers.cam.SetSite(camera.Site{
+			Latitude:  s.skyState.Observer.Latitude,
+			Longitude: s.skyState.Observer.Longitude,
+			Elevation: s.skyState.Observer.Elevation,
+		})
+	}
+}
+
 // TimeResponse contains simulation time info
 type TimeResponse struct {
-	UTC         time.Time `json:"utc"`
-	Local       time.Time `json:"local"`
-	JulianDate  float64   `json:"julian_date"`
-	LST         float64   `json:"lst"` // Local Sidereal Time in hours
-	UseRealTime bool      `json:"use_real_time"`
-	TimeOffset  float64   `json:"time_offset"` // Hours
+	UTC         time.Time     `json:"utc"`
+	Local       time.Time     `json:"local"`
+	JulianDate  float64       `json:"julian_date"`
+	LST         float64       `json:"lst"` // Local Sidereal Time in hours
+	UseRealTime bool          `json:"use_real_time"`
+	TimeOffset  float64       `json:"time_offset"` // Hours
+	Mode        simclock.Mode `json:"mode"`        // "real"|"offset"|"accelerated"|"paused"
+	Rate        float64       `json:"rate"`        // simulated seconds per real second
 }
 
 func (s *Server) getSkyTime(c *gin.Context) {
-	now := time.Now().UTC()
-	if !s.skyState.UseRealTime {
-		now = now.Add(time.Duration(s.skyState.TimeOffset * float64(time.Hour)))
-	}
+	state := s.skyState.Clock.State()
+	now := state.Time
 
 	jd := catalog.JulianDate(now)
 	lst := catalog.LocalSiderealTime(now, s.skyState.Observer.Longitude)
@@ -80,16 +123,21 @@ func (s *Server) getSkyTime(c *gin.Context) {
 		Local:       now.Local(),
 		JulianDate:  jd,
 		LST:         lst,
-		UseRealTime: s.skyState.UseRealTime,
-		TimeOffset:  s.skyState.TimeOffset,
+		UseRealTime: state.Mode == simclock.ModeReal,
+		TimeOffset:  state.Offset,
+		Mode:        state.Mode,
+		Rate:        state.Rate,
 	})
 }
 
-// SetTimeRequest for updating simulation time
+// SetTimeRequest for updating simulation time. Fields are applied in order:
+// real time, offset, absolute time, rate, then pause.
 type SetTimeRequest struct {
 	UseRealTime *bool    `json:"use_real_time"`
 	TimeOffset  *float64 `json:"time_offset"` // Hours offset from real time
 	SetTime     *string  `json:"set_time"`    // ISO 8601 time to set
+	Rate        *float64 `json:"rate"`        // e.g. 60 for a time-lapse night
+	Paused      *bool    `json:"paused"`
 }
 
 func (s *Server) setSkyTime(c *gin.Context) {
@@ -99,23 +147,50 @@ func (s *Server) setSkyTime(c *gin.Context) {
 		return
 	}
 
+	var setTime time.Time
+	if req.SetTime != nil {
+		t, err := time.Parse(time.RFC3339, *req.SetTime)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid time format, use RFC3339"})
+			return
+		}
+		setTime = t
+	}
+	if req.Rate != nil && (*req.Rate <= 0 || *req.Rate > simclock.MaxRate) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "rate must be greater than 0 and at most 3600"})
+		return
+	}
+
+	clock := s.skyState.Clock
 	if req.UseRealTime != nil {
-		s.skyState.UseRealTime = *req.UseRealTime
+		if *req.UseRealTime {
+			clock.SetRealTime()
+		} else if clock.Mode() == simclock.ModeReal {
+			clock.SetOffset(0)
+		}
 	}
 
 	if req.TimeOffset != nil {
-		s.skyState.TimeOffset = *req.TimeOffset
+		clock.SetOffset(time.Duration(*req.TimeOffset * float64(time.Hour)))
 	}
 
 	if req.SetTime != nil {
-		t, err := time.Parse(time.RFC3339, *req.SetTime)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid time format, use RFC3339"})
+		clock.SetTime(setTime)
+	}
+
+	if req.Rate != nil {
+		if err := clock.SetRate(*req.Rate); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
-		// Calculate offset from now
-		s.skyState.TimeOffset = t.Sub(time.Now().UTC()).Hours()
-		s.skyState.UseRealTime = false
+	}
+
+	if req.Paused != nil {
+		if *req.Paused {
+			clock.Pause()
+		} else {
+			clock.Resume()
+		}
 	}
 
 	// Return updated time info
@@ -160,6 +235,7 @@ func (s *Server) setLocation(c *gin.Context) {
 	if req.Elevation != nil {
 		s.skyState.Observer.Elevation = *req.Elevation
 	}
+	s.applySite()
 
 	c.JSON(http.StatusOK, s.skyState.Observer)
 }
@@ -178,10 +254,7 @@ type TwilightResponse struct {
 }
 
 func (s *Server) getTwilightTimes(c *gin.Context) {
-	now := time.Now().UTC()
-	if !s.skyState.UseRealTime {
-		now = now.Add(time.Duration(s.skyState.TimeOffset * float64(time.Hour)))
-	}
+	now := s.skyState.Clock.Now()
 
 	twilight := catalog.CalculateTwilight(&s.skyState.Observer, now)
 
@@ -224,10 +297,7 @@ type MoonInfoResponse struct {
 }
 
 func (s *Server) getMoonInfo(c *gin.Context) {
-	now := time.Now().UTC()
-	if !s.skyState.UseRealTime {
-		now = now.Add(time.Duration(s.skyState.TimeOffset * float64(time.Hour)))
-	}
+	now := s.skyState.Clock.Now()
 
 	// Use Ephemeris to get moon position
 	ephemeris := catalog.NewEphemeris(&s.skyState.Observer)
@@ -275,10 +345,7 @@ type PlanetInfoResponse struct {
 }
 
 func (s *Server) getPlanets(c *gin.Context) {
-	now := time.Now().UTC()
-	if !s.skyState.UseRealTime {
-		now = now.Add(time.Duration(s.skyState.TimeOffset * float64(time.Hour)))
-	}
+	now := s.skyState.Clock.Now()
 
 	ephemeris := catalog.NewEphemeris(&s.skyState.Observer)
 
@@ -313,10 +380,7 @@ func (s *Server) getPlanets(c *gin.Context) {
 }
 
 func (s *Server) getSunInfo(c *gin.Context) {
-	now := time.Now().UTC()
-	if !s.skyState.UseRealTime {
-		now = now.Add(time.Duration(s.skyState.TimeOffset * float64(time.Hour)))
-	}
+	now := s.skyState.Clock.Now()
 
 	// Use Ephemeris to get sun position
 	ephemeris := catalog.NewEphemeris(&s.skyState.Observer)
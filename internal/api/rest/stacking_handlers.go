@@ -0,0 +1,238 @@
+package rest
+
+import (
+	"bytes"
+	"image/png"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/darkdragonsastro/draco-simulator/internal/analysis"
+	"github.com/darkdragonsastro/draco-simulator/internal/game"
+	"github.com/darkdragonsastro/draco-simulator/internal/stacking"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// maxStackFrames bounds how many frames one stack may take.
+	maxStackFrames = 64
+
+	// maxStackSamples bounds the decoded samples of one stack's frames,
+	// all held at once while stacking. Raw color frames count four times
+	// over, for their debayered planes.
+	maxStackSamples = 1 << 28
+
+	// maxStackUpload bounds the size of the frames uploaded for stacking:
+	// maxStackSamples of the widest samples, 64-bit floats.
+	maxStackUpload = 8 * maxStackSamples
+
+	// maxGallery is how many stacks the gallery keeps, the oldest going
+	// first.
+	maxGallery = 20
+
+	// maxGalleryWidth is the width the gallery bins its stacks down to;
+	// it keeps no full-resolution planes.
+	maxGalleryWidth = 2048
+)
+
+// GalleryEntry is a stacked image in the gallery.
+type GalleryEntry struct {
+	ID        int              `json:"id"`
+	Name      string           `json:"name"`
+	CreatedAt time.Time        `json:"created_at"`
+	Stack     *stacking.Result `json:"stack"`
+	Score     game.ImageScore  `json:"score"`
+
+	preview *stacking.Result // binned to maxGalleryWidth
+}
+
+// StackingHandlers provides REST endpoints for stacking frames and the
+// gallery of final images.
+type StackingHandlers struct {
+	mu      sync.RWMutex
+	gallery []*GalleryEntry // oldest first
+	nextID  int
+}
+
+// NewStackingHandlers creates a new StackingHandlers with an empty gallery.
+func NewStackingHandlers() *StackingHandlers {
+	return &StackingHandlers{nextID: 1}
+}
+
+// stack registers and integrates the FITS or PNG frames of a multipart
+// upload's frames fields, scores the result and adds it to the gallery.
+// The rejection, low, high and weighting form values or query parameters
+// tune the integration, and name labels it.
+func (h *StackingHandlers) stack(c *gin.Context) {
+	var opts stacking.Options
+	if v := scoreParam(c, "rejection"); v != "" {
+		r, err := stacking.ParseRejection(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		opts.Rejection = r
+	}
+	if v := scoreParam(c, "weighting"); v != "" {
+		w, err := stacking.ParseWeighting(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		opts.Weighting = w
+	}
+	for _, p := range []struct {
+		key string
+		dst *float64
+	}{
+		{"low", &opts.Low},
+		{"high", &opts.High},
+	} {
+		if v := scoreParam(c, p.key); v != "" {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil || f <= 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": p.key + " must be a positive number"})
+				return
+			}
+			*p.dst = f
+		}
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxStackUpload)
+	form, err := c.MultipartForm()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "stacking needs a multipart upload of frames"})
+		return
+	}
+	if len(form.File["frames"]) > maxStackFrames {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "at most " + strconv.Itoa(maxStackFrames) + " frames can be stacked"})
+		return
+	}
+	var frames []*analysis.Image
+	var samples int
+	for _, file := range form.File["frames"] {
+		f, err := file.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		img, err := analysis.Decode(f)
+		f.Close()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": file.Filename + ": " + err.Error()})
+			return
+		}
+		planes := 1
+		if img.BayerPattern != "" {
+			planes = 4
+		}
+		samples += planes * img.Width * img.Height
+		if samples > maxStackSamples {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "the frames are too large to stack together"})
+			return
+		}
+		frames = append(frames, img)
+	}
+
+	result, err := stacking.Stack(frames, opts)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	metrics := result.Metrics()
+	score := game.NewImageScorer().ScoreStack(metrics, result.Integrated)
+	score.Metrics = &metrics
+
+	entry := &GalleryEntry{
+		Name:      scoreParam(c, "name"),
+		CreatedAt: time.Now(),
+		Stack:     result,
+		Score:     score,
+		preview:   result.Binned(maxGalleryWidth),
+	}
+	result.Channels = nil
+	h.mu.Lock()
+	entry.ID = h.nextID
+	h.nextID++
+	if entry.Name == "" {
+		entry.Name = "Stack " + strconv.Itoa(entry.ID)
+	}
+	h.gallery = append(h.gallery, entry)
+	if len(h.gallery) > maxGallery {
+		h.gallery = slices.Delete(h.gallery, 0, len(h.gallery)-maxGallery)
+	}
+	h.mu.Unlock()
+
+	c.JSON(http.StatusCreated, entry)
+}
+
+// getGallery lists the gallery, newest first.
+func (h *StackingHandlers) getGallery(c *gin.Context) {
+	h.mu.RLock()
+	entries := slices.Clone(h.gallery)
+	h.mu.RUnlock()
+	slices.Reverse(entries)
+	c.JSON(http.StatusOK, gin.H{"entries": entries, "count": len(entries)})
+}
+
+// entry returns the gallery entry the id parameter names, responding 404
+// when there is none.
+func (h *StackingHandlers) entry(c *gin.Context) (*GalleryEntry, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err == nil {
+		h.mu.RLock()
+		defer h.mu.RUnlock()
+		for _, e := range h.gallery {
+			if e.ID == id {
+				return e, true
+			}
+		}
+	}
+	c.JSON(http.StatusNotFound, gin.H{"error": "no such stack"})
+	return nil, false
+}
+
+func (h *StackingHandlers) getEntry(c *gin.Context) {
+	e, ok := h.entry(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, e)
+}
+
+// getEntryImage serves a stretched preview of a stack, binned to the width
+// query parameter.
+func (h *StackingHandlers) getEntryImage(c *gin.Context) {
+	width := defaultPreviewWidth
+	if v := c.Query("width"); v != "" {
+		w, err := strconv.Atoi(v)
+		if err != nil || w <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "width must be a positive integer"})
+			return
+		}
+		width = w
+	}
+	e, ok := h.entry(c)
+	if !ok {
+		return
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, e.preview.Preview(width)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Data(http.StatusOK, "image/png", buf.Bytes())
+}
+
+func (h *StackingHandlers) deleteEntry(c *gin.Context) {
+	e, ok := h.entry(c)
+	if !ok {
+		return
+	}
+	h.mu.Lock()
+	h.gallery = slices.DeleteFunc(h.gallery, func(g *GalleryEntry) bool { return g == e })
+	h.mu.Unlock()
+	c.JSON(http.StatusOK, gin.H{"status": "deleted"})
+}
@@ -8,6 +8,8 @@ import (
 	"sync"
 	"time"
 
+	"github.com/darkdragonsastro/draco-simulator/internal/camera"
+	"github.com/darkdragonsastro/draco-simulator/internal/mount"
 	"github.com/gorilla/websocket"
 )
 
@@ -34,10 +36,15 @@ type Client struct {
 	id   string
 }
 
+// CommandFunc handles a client command. The returned value is sent back to
+// the client as the command result.
+type CommandFunc func(data json.RawMessage) (any, error)
+
 // Hub manages WebSocket connections
 type Hub struct {
 	mu         sync.RWMutex
 	clients    map[*Client]bool
+	commands   map[string]CommandFunc
 	broadcast  chan []byte
 	register   chan *Client
 	unregister chan *Client
@@ -48,6 +55,7 @@ type Hub struct {
 func NewHub() *Hub {
 	return &Hub{
 		clients:    make(map[*Client]bool),
+		commands:   make(map[string]CommandFunc),
 		broadcast:  make(chan []byte, 256),
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
@@ -118,6 +126,13 @@ func (h *Hub) Broadcast(msgType string, data any) {
 	}
 }
 
+// RegisterCommand registers a handler for a client message type
+func (h *Hub) RegisterCommand(msgType string, fn CommandFunc) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.commands[msgType] = fn
+}
+
 // ClientCount returns the number of connected clients
 func (h *Hub) ClientCount() int {
 	h.mu.RLock()
@@ -260,7 +275,37 @@ func (c *Client) handleMessage(msg Message) {
 		log.Printf("Client %s subscribed to: %v", c.id, msg.Data)
 
 	default:
-		log.Printf("Unknown message type from %s: %s", c.id, msg.Type)
+		c.hub.mu.RLock()
+		fn, ok := c.hub.commands[msg.Type]
+		c.hub.mu.RUnlock()
+		if !ok {
+			log.Printf("Unknown message type from %s: %s", c.id, msg.Type)
+			return
+		}
+		c.runCommand(msg, fn)
+	}
+}
+
+// runCommand executes a registered command and replies with its result
+func (c *Client) runCommand(msg Message, fn CommandFunc) {
+	response := Message{
+		Type:      msg.Type + ".result",
+		Timestamp: time.Now().UTC(),
+	}
+
+	data, err := json.Marshal(msg.Data)
+	if err == nil {
+		var result any
+		result, err = fn(data)
+		response.Data = result
+	}
+	if err != nil {
+		response.Type = msg.Type + ".error"
+		response.Data = map[string]any{"error": err.Error()}
+	}
+
+	if bytes, err := json.Marshal(response); err == nil {
+		c.send <- bytes
 	}
 }
 
@@ -284,6 +329,11 @@ const (
 	EventCaptureCompleted = "capture.completed"
 	EventCaptureFailed    = "capture.failed"
 
+	EventCameraExposureStarted  = camera.EventExposureStarted
+	EventCameraExposureComplete = camera.EventExposureComplete
+	EventCameraExposureAborted  = camera.EventExposureAborted
+	EventCameraCooler           = camera.EventCoolerStatus
+
 	EventFocusStarted   = "focus.started"
 	EventFocusCompleted = "focus.completed"
 	EventFocusStep      = "focus.step"
@@ -293,7 +343,25 @@ const (
 	EventGuideCorrection = "guide.correction"
 
 	EventMountPosition        = "mount.position"
-	EventMountSlewStarted     = "mount.slew.started"
-	EventMountSlewCompleted   = "mount.slew.completed"
+	EventMountSlewStarted     = mount.EventSlewStarted
+	EventMountSlewCompleted   = mount.EventSlewCompleted
 	EventMountTrackingChanged = "mount.tracking.changed"
+
+	EventMountMeridianApproaching   = mount.EventMeridianApproaching
+	EventMountMeridianLimit         = mount.EventMeridianLimit
+	EventMountMeridianFlipStarted   = mount.EventMeridianFlipStarted
+	EventMountMeridianFlipCompleted = mount.EventMeridianFlipCompleted
+	EventMountLimitReached          = mount.EventLimitReached
+	EventMountSynced                = mount.EventSynced
+
+	EventMountParkStarted = mount.EventParkStarted
+	EventMountParked      = mount.EventParked
+	EventMountHomeStarted = mount.EventHomeStarted
+	EventMountHomeFound   = mount.EventHomeFound
+)
+
+// Command types accepted from clients
+const (
+	CommandMountPulseGuide    = "mount.pulse_guide"
+	CommandMountSetGuideRates = "mount.set_guide_rates"
 )
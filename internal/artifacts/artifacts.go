@@ -0,0 +1,189 @@
+// Package artifacts synthesizes the defects real frames carry, for the
+// lessons on rejecting and calibrating them out: satellite and aircraft
+// trails, cosmic ray hits, hot and cold pixels, and light-pollution
+// gradients. Artifacts are planned from a scene, describing where and when
+// a frame was taken, and drawn into image buffers. Everything comes from a
+// seeded random number generator, so the same seed and scenes give the same
+// artifacts.
+package artifacts
+
+import (
+	"math"
+	"math/rand"
+	"slices"
+	"time"
+
+	"github.com/darkdragonsastro/draco-simulator/internal/catalog"
+)
+
+const (
+	// Defaults for the scene's optional fields.
+	DefaultFWHM            = 3.0   // pixels
+	DefaultPixelScale      = 1.5   // arcsec/pixel
+	DefaultPixelSize       = 3.76  // microns
+	DefaultZeroPoint       = 1.0e7 // ADU/s from a magnitude 0 source
+	DefaultElectronsPerADU = 1.0
+
+	// backgroundSamples is about how many pixels a buffer's sky level is
+	// judged from when the scene doesn't give it.
+	backgroundSamples = 100000
+)
+
+// Buffer is an image to draw artifacts into.
+type Buffer struct {
+	Width  int
+	Height int
+	Pixels []float32 // row by row from the top, in the ADU of the scene's ZeroPoint
+
+	Saturation float64 // ADU at which pixels clip; 0 means 65535
+}
+
+// add adds v to pixel (x, y), clipping at saturation. Pixels off the
+// buffer are ignored.
+func (b *Buffer) add(x, y int, v float64) {
+	if x < 0 || y < 0 || x >= b.Width || y >= b.Height {
+		return
+	}
+	i := y*b.Width + x
+	b.Pixels[i] = float32(min(float64(b.Pixels[i])+v, b.saturation()))
+}
+
+// saturation returns the level at which the buffer's pixels clip.
+func (b *Buffer) saturation() float64 {
+	if b.Saturation > 0 {
+		return b.Saturation
+	}
+	return math.MaxUint16
+}
+
+// background returns the median of an even sampling of the pixels.
+func (b *Buffer) background() float64 {
+	if len(b.Pixels) == 0 {
+		return 0
+	}
+	step := max(1, len(b.Pixels)/backgroundSamples)
+	values := make([]float64, 0, len(b.Pixels)/step+1)
+	for i := 0; i < len(b.Pixels); i += step {
+		values = append(values, float64(b.Pixels[i]))
+	}
+	slices.Sort(values)
+	return values[len(values)/2]
+}
+
+// Scene is where, when and how a frame was taken. Zero values of the
+// optional fields take the defaults.
+type Scene struct {
+	Width  int
+	Height int
+
+	Time     time.Time        // when the shutter opened
+	Exposure float64          // seconds
+	Observer catalog.Observer // the site, whose elevation raises the cosmic ray rate
+
+	// Where the frame points, in degrees, and which way its up direction
+	// lies: Rotation is the angle in degrees, counterclockwise, from the
+	// frame's up to the direction of the zenith
+	Altitude float64
+	Azimuth  float64
+	Rotation float64
+
+	PixelScale      float64 // arcsec/pixel; optional
+	PixelSize       float64 // microns, for the sensor area cosmic rays strike; optional
+	FWHM            float64 // pixels, of stars and so of trails; optional
+	ZeroPoint       float64 // ADU/s a magnitude 0 source delivers; optional
+	ElectronsPerADU float64 // optional
+
+	// Background is the sky level in ADU gradients brighten in proportion
+	// to; Inject measures it from the buffer when zero
+	Background float64
+
+	BortleClass int // 1-9
+}
+
+// withDefaults returns the scene with its optional fields filled in.
+func (s Scene) withDefaults() Scene {
+	if s.PixelScale <= 0 {
+		s.PixelScale = DefaultPixelScale
+	}
+	if s.PixelSize <= 0 {
+		s.PixelSize = DefaultPixelSize
+	}
+	if s.FWHM <= 0 {
+		s.FWHM = DefaultFWHM
+	}
+	if s.ZeroPoint <= 0 {
+		s.ZeroPoint = DefaultZeroPoint
+	}
+	if s.ElectronsPerADU <= 0 {
+		s.ElectronsPerADU = DefaultElectronsPerADU
+	}
+	s.BortleClass = max(1, min(len(domeBrightness), s.BortleClass))
+	return s
+}
+
+// area returns the frame's field of view in square degrees.
+func (s Scene) area() float64 {
+	return float64(s.Width*s.Height) * s.PixelScale * s.PixelScale / (3600 * 3600)
+}
+
+// Generator synthesizes artifacts.
+type Generator struct {
+	seed int64
+	rng  *rand.Rand
+
+	// dome is the azimuth in degrees of the brightest horizon, where the
+	// nearest town's light dome stands; it belongs to the site, so it's
+	// drawn once
+	dome float64
+}
+
+// NewGenerator returns a generator seeded with seed.
+func NewGenerator(seed int64) *Generator {
+	g := &Generator{seed: seed, rng: rand.New(rand.NewSource(seed))}
+	g.dome = 360 * g.rng.Float64()
+	return g
+}
+
+// DomeAzimuth returns the azimuth in degrees of the brightest horizon.
+func (g *Generator) DomeAzimuth() float64 {
+	return g.dome
+}
+
+// Report lists the artifacts injected into a frame.
+type Report struct {
+	Trails     []Trail     `json:"trails"`
+	CosmicRays []CosmicRay `json:"cosmic_rays"`
+	Gradient   Gradient    `json:"gradient"`
+	HotPixels  int         `json:"hot_pixels"`
+	ColdPixels int         `json:"cold_pixels"`
+}
+
+// Inject draws every kind of artifact into buf for scene: the gradient and
+// trails on the sky, then the sensor's defective pixels and the cosmic rays
+// that struck it. The scene's dimensions are taken from buf.
+func (g *Generator) Inject(buf *Buffer, scene Scene) (*Report, error) {
+	if buf.Width <= 0 || buf.Height <= 0 || len(buf.Pixels) != buf.Width*buf.Height {
+		return nil, errBadDimensions
+	}
+	scene.Width, scene.Height = buf.Width, buf.Height
+	if scene.Background <= 0 {
+		scene.Background = buf.background()
+	}
+
+	report := &Report{
+		Gradient:   g.Gradient(scene),
+		Trails:     g.Trails(scene),
+		CosmicRays: g.CosmicRays(scene),
+	}
+	report.Gradient.Apply(buf)
+	for _, t := range report.Trails {
+		t.Apply(buf)
+	}
+	defects := g.PixelMap(buf.Width, buf.Height)
+	defects.Apply(buf, scene.Exposure)
+	report.HotPixels, report.ColdPixels = len(defects.Hot), len(defects.Cold)
+	for _, c := range report.CosmicRays {
+		c.Apply(buf)
+	}
+	return report, nil
+}
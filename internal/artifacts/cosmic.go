@@ -0,0 +1,92 @@
+package artifacts
+
+import (
+	"math"
+
+	"github.com/darkdragonsastro/draco-simulator/internal/calibration"
+)
+
+const (
+	// cosmicRate is how many cosmic rays strike a square centimetre of
+	// sensor a minute at sea level; the rate climbs e-fold every
+	// cosmicScaleHeight meters of elevation.
+	cosmicRate        = 2.0
+	cosmicScaleHeight = 2000.0
+
+	// A ray frees about chargePerMicron electrons for each micron it
+	// crosses of the sensor's depth microns of sensitive silicon, varying
+	// from hit to hit by a lognormal spread of chargeSpread.
+	chargePerMicron = 80.0
+	depth           = 10.0
+	chargeSpread    = 0.5
+
+	// maxTrackLength caps the pixels a grazing ray's track runs across.
+	maxTrackLength = 50.0
+
+	// trackStep is how finely, in pixels, a track's charge is laid down.
+	trackStep = 0.25
+)
+
+// CosmicRay is a cosmic ray's hit on the sensor.
+type CosmicRay struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Length float64 `json:"length"` // pixels its track runs across; 0 for a head-on hit
+	Angle  float64 `json:"angle"`  // degrees from the x axis towards the y axis
+	Charge float64 `json:"charge"` // ADU it leaves, in all
+}
+
+// CosmicRays plans the cosmic rays striking the sensor over the scene's
+// exposure. Their number grows with the exposure, the sensor's area and
+// the site's elevation; most arrive steeply and leave a spot or a short
+// worm, a few graze the sensor and leave a long track.
+func (g *Generator) CosmicRays(scene Scene) []CosmicRay {
+	scene = scene.withDefaults()
+	area := float64(scene.Width*scene.Height) * scene.PixelSize * scene.PixelSize * 1e-8 // cm²
+	lambda := cosmicRate * math.Exp(scene.Observer.Elevation/cosmicScaleHeight) * area * scene.Exposure / 60
+
+	rays := []CosmicRay{}
+	for n := int(calibration.Poisson(g.rng, lambda)); n > 0; n-- {
+		// Arriving from all over the sky, rays cross the sensor's face with
+		// cos θ distributed as the square root of a uniform draw
+		cos := math.Sqrt(g.rng.Float64())
+		cos = max(cos, 1e-3)
+		sin := math.Sqrt(1 - cos*cos)
+		electrons := chargePerMicron * depth / cos * math.Exp(chargeSpread*g.rng.NormFloat64())
+		rays = append(rays, CosmicRay{
+			X:      g.rng.Float64()*float64(scene.Width) - 0.5,
+			Y:      g.rng.Float64()*float64(scene.Height) - 0.5,
+			Length: min(maxTrackLength, depth*sin/cos/scene.PixelSize),
+			Angle:  360 * g.rng.Float64(),
+			Charge: electrons / scene.ElectronsPerADU,
+		})
+	}
+	return rays
+}
+
+// Apply lays the ray's charge into buf evenly along its track, each bit
+// shared among the four pixels around it.
+func (c CosmicRay) Apply(buf *Buffer) {
+	steps := int(math.Ceil(c.Length/trackStep)) + 1
+	dy, dx := math.Sincos(c.Angle * math.Pi / 180)
+	share := c.Charge / float64(steps)
+	for i := 0; i < steps; i++ {
+		s := 0.0
+		if steps > 1 {
+			s = c.Length * (float64(i)/float64(steps-1) - 0.5)
+		}
+		splat(buf, c.X+s*dx, c.Y+s*dy, share)
+	}
+}
+
+// splat adds v at (x, y), shared bilinearly among the four pixels whose
+// centers surround it.
+func splat(buf *Buffer, x, y, v float64) {
+	x0, y0 := math.Floor(x), math.Floor(y)
+	tx, ty := x-x0, y-y0
+	ix, iy := int(x0), int(y0)
+	buf.add(ix, iy, v*(1-tx)*(1-ty))
+	buf.add(ix+1, iy, v*tx*(1-ty))
+	buf.add(ix, iy+1, v*(1-tx)*ty)
+	buf.add(ix+1, iy+1, v*tx*ty)
+}
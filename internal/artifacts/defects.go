@@ -0,0 +1,81 @@
+package artifacts
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/darkdragonsastro/draco-simulator/internal/calibration"
+)
+
+const (
+	// hotFraction of a sensor's pixels are hot, leaking charge at a rate
+	// spread lognormally by hotSpread around hotRate ADU/s.
+	hotFraction = 2e-4
+	hotRate     = 5.0
+	hotSpread   = 1.0
+
+	// coldFraction of a sensor's pixels are cold, answering light with at
+	// most maxColdResponse of the sensitivity of the rest.
+	coldFraction    = 5e-5
+	maxColdResponse = 0.5
+)
+
+// Defect is a defective pixel.
+type Defect struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+
+	// Value is a hot pixel's dark current in ADU/s, or the fraction of the
+	// light a cold pixel responds to
+	Value float64 `json:"value"`
+}
+
+// PixelMap is a sensor's hot and cold pixels.
+type PixelMap struct {
+	Width  int      `json:"width"`
+	Height int      `json:"height"`
+	Hot    []Defect `json:"hot"`
+	Cold   []Defect `json:"cold"`
+}
+
+// PixelMap returns the defective pixels of a width×height sensor. They
+// belong to the sensor, not the frame, so every call with the same
+// dimensions gives the same map, whatever else the generator has drawn;
+// dark frames can calibrate them out.
+func (g *Generator) PixelMap(width, height int) PixelMap {
+	rng := rand.New(rand.NewSource(g.seed))
+	m := PixelMap{Width: width, Height: height, Hot: []Defect{}, Cold: []Defect{}}
+	if width <= 0 || height <= 0 {
+		return m
+	}
+	pixels := float64(width * height)
+	for n := int(calibration.Poisson(rng, hotFraction*pixels)); n > 0; n-- {
+		m.Hot = append(m.Hot, Defect{
+			X:     rng.Intn(width),
+			Y:     rng.Intn(height),
+			Value: hotRate * math.Exp(hotSpread*rng.NormFloat64()),
+		})
+	}
+	for n := int(calibration.Poisson(rng, coldFraction*pixels)); n > 0; n-- {
+		m.Cold = append(m.Cold, Defect{
+			X:     rng.Intn(width),
+			Y:     rng.Intn(height),
+			Value: maxColdResponse * rng.Float64(),
+		})
+	}
+	return m
+}
+
+// Apply adds the hot pixels' dark current over exposure seconds to buf and
+// dims its cold pixels. Defects that fall off buf are ignored.
+func (m PixelMap) Apply(buf *Buffer, exposure float64) {
+	for _, d := range m.Hot {
+		buf.add(d.X, d.Y, d.Value*exposure)
+	}
+	for _, d := range m.Cold {
+		if d.X < buf.Width && d.Y < buf.Height {
+			i := d.Y*buf.Width + d.X
+			buf.Pixels[i] *= float32(d.Value)
+		}
+	}
+}
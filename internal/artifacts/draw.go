@@ -0,0 +1,68 @@
+package artifacts
+
+import "math"
+
+const (
+	// fwhmPerSigma converts a Gaussian's standard deviation to its full
+	// width at half maximum.
+	fwhmPerSigma = 2.3548
+
+	// profileReach is how many standard deviations out a profile is drawn.
+	profileReach = 4.0
+)
+
+// drawSegment draws a line from (x0, y0) to (x1, y1) with a Gaussian
+// cross-section of standard deviation sigma, perLength ADU per pixel of its
+// length summed across it. The ends are rounded, as a star's image swept
+// along the line is.
+func drawSegment(buf *Buffer, x0, y0, x1, y1, sigma, perLength float64) {
+	length := math.Hypot(x1-x0, y1-y0)
+	if length == 0 || perLength <= 0 {
+		return
+	}
+	ux, uy := (x1-x0)/length, (y1-y0)/length
+	reach := profileReach * sigma
+	peak := perLength / (math.Sqrt(2*math.Pi) * sigma)
+
+	top := max(0, int(math.Floor(min(y0, y1)-reach)))
+	bottom := min(buf.Height-1, int(math.Ceil(max(y0, y1)+reach)))
+	left := max(0, int(math.Floor(min(x0, x1)-reach)))
+	right := min(buf.Width-1, int(math.Ceil(max(x0, x1)+reach)))
+	for y := top; y <= bottom; y++ {
+		// Only the stretch of the row within reach of the line needs a look
+		from, to := left, right
+		if math.Abs(uy) > 1e-9 {
+			cx := x0 + (float64(y)-y0)*ux/uy
+			half := reach / math.Abs(uy)
+			from = max(from, int(math.Floor(cx-half)))
+			to = min(to, int(math.Ceil(cx+half)))
+		}
+		for x := from; x <= to; x++ {
+			dx, dy := float64(x)-x0, float64(y)-y0
+			along := max(0, min(length, dx*ux+dy*uy))
+			d2 := (dx-along*ux)*(dx-along*ux) + (dy-along*uy)*(dy-along*uy)
+			if d2 > reach*reach {
+				continue
+			}
+			buf.add(x, y, peak*math.Exp(-d2/(2*sigma*sigma)))
+		}
+	}
+}
+
+// drawBlob draws a Gaussian spot of standard deviation sigma and total
+// flux ADU centered on (cx, cy).
+func drawBlob(buf *Buffer, cx, cy, sigma, flux float64) {
+	if flux <= 0 {
+		return
+	}
+	reach := profileReach * sigma
+	peak := flux / (2 * math.Pi * sigma * sigma)
+	for y := max(0, int(math.Floor(cy-reach))); y <= min(buf.Height-1, int(math.Ceil(cy+reach))); y++ {
+		for x := max(0, int(math.Floor(cx-reach))); x <= min(buf.Width-1, int(math.Ceil(cx+reach))); x++ {
+			d2 := (float64(x)-cx)*(float64(x)-cx) + (float64(y)-cy)*(float64(y)-cy)
+			if d2 <= reach*reach {
+				buf.add(x, y, peak*math.Exp(-d2/(2*sigma*sigma)))
+			}
+		}
+	}
+}
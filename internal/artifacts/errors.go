@@ -0,0 +1,5 @@
+package artifacts
+
+import "errors"
+
+var errBadDimensions = errors.New("buffer dimensions do not match pixel count")
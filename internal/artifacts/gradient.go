@@ -0,0 +1,100 @@
+package artifacts
+
+import "math"
+
+const (
+	// domeHeight is the altitude in degrees over which a town's light dome
+	// fades by a factor of e.
+	domeHeight = 15.0
+
+	// domeSurround is the share of the glow on the horizon beneath the
+	// dome that lies all around the horizon, from light scattered further
+	// and from more distant towns.
+	domeSurround = 0.25
+)
+
+// domeBrightness is how bright the light dome on the horizon beneath it is
+// for each Bortle class, 1 through 9, as a multiple of the zenith sky.
+var domeBrightness = [...]float64{0.05, 0.1, 0.2, 0.4, 0.7, 1.0, 1.5, 2.2, 3.0}
+
+// Gradient is a light-pollution gradient across a frame: a ramp from the
+// darkest corner, where it adds nothing, to Amplitude ADU at the corner
+// nearest the light dome.
+type Gradient struct {
+	Azimuth   float64 `json:"azimuth"`   // degrees, of the light dome
+	Angle     float64 `json:"angle"`     // degrees counterclockwise from the frame's up to where the sky brightens
+	Amplitude float64 `json:"amplitude"` // ADU
+}
+
+// Gradient plans the light-pollution gradient across a frame of scene. The
+// sky brightens towards the light dome standing on the brightest horizon,
+// the more so the worse the Bortle class and the lower the frame points.
+func (g *Generator) Gradient(scene Scene) Gradient {
+	scene = scene.withDefaults()
+	grad := Gradient{Azimuth: g.dome}
+
+	// The dome's brightness, relative to the zenith sky, and how fast it
+	// changes per degree up and per degree of sky along the horizon
+	d := domeBrightness[scene.BortleClass-1]
+	alt := scene.Altitude * math.Pi / 180
+	delta := (scene.Azimuth - g.dome) * math.Pi / 180
+	facing := (1 + math.Cos(delta)) / 2
+	horizon := d * math.Exp(-scene.Altitude/domeHeight)
+	b := horizon * (domeSurround + (1-domeSurround)*facing*facing)
+	dAlt := -b / domeHeight
+	dAz := -horizon * (1 - domeSurround) * facing * math.Sin(delta) * math.Pi / 180 / math.Max(math.Cos(alt), 0.01)
+
+	// In the frame the zenith lies Rotation counterclockwise from up, and
+	// azimuth increases a quarter turn clockwise from there
+	sinR, cosR := math.Sincos(scene.Rotation * math.Pi / 180)
+	perPixel := scene.PixelScale / 3600
+	gx := (dAlt*-sinR + dAz*cosR) * perPixel
+	gy := (dAlt*-cosR + dAz*-sinR) * perPixel
+	if gx == 0 && gy == 0 {
+		return grad
+	}
+	grad.Angle = math.Mod(math.Atan2(-gx, -gy)*180/math.Pi+360, 360)
+
+	// The sky level already includes the dome where the frame points
+	dx, dy := grad.direction()
+	lo, hi := span(dx, dy, scene.Width, scene.Height)
+	grad.Amplitude = scene.Background / (1 + b) * math.Hypot(gx, gy) * (hi - lo)
+	return grad
+}
+
+// direction returns the unit vector in pixel coordinates along which the
+// sky brightens.
+func (g Gradient) direction() (dx, dy float64) {
+	sin, cos := math.Sincos(g.Angle * math.Pi / 180)
+	return -sin, -cos
+}
+
+// span returns the least and greatest distances along (dx, dy) of the
+// corners of a width×height frame.
+func span(dx, dy float64, width, height int) (lo, hi float64) {
+	w, h := float64(width-1), float64(height-1)
+	lo, hi = math.Inf(1), math.Inf(-1)
+	for _, corner := range [][2]float64{{0, 0}, {w, 0}, {0, h}, {w, h}} {
+		p := corner[0]*dx + corner[1]*dy
+		lo, hi = min(lo, p), max(hi, p)
+	}
+	return lo, hi
+}
+
+// Apply adds the gradient's ramp to buf.
+func (g Gradient) Apply(buf *Buffer) {
+	if g.Amplitude <= 0 || buf.Width <= 0 || buf.Height <= 0 {
+		return
+	}
+	dx, dy := g.direction()
+	lo, hi := span(dx, dy, buf.Width, buf.Height)
+	if hi <= lo {
+		return
+	}
+	scale := g.Amplitude / (hi - lo)
+	for y := 0; y < buf.Height; y++ {
+		for x := 0; x < buf.Width; x++ {
+			buf.add(x, y, (float64(x)*dx+float64(y)*dy-lo)*scale)
+		}
+	}
+}
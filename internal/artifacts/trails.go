@@ -0,0 +1,191 @@
+package artifacts
+
+import (
+	"math"
+	"time"
+
+	"github.com/darkdragonsastro/draco-simulator/internal/calibration"
+	"github.com/darkdragonsastro/draco-simulator/internal/catalog"
+)
+
+const (
+	// twilightScale is how many hours from nautical dusk or dawn the
+	// extra trails of twilight fall off over: low satellites slip into the
+	// Earth's shadow, and the evening's flights land.
+	twilightScale = 1.5
+
+	// Aircraft lights: the wingtip navigation lights mark the trail's two
+	// edges, a wingspan apart at the aircraft's distance, and the strobe
+	// flashes for strobeDuration every strobePeriod seconds.
+	wingspan        = 35.0 // meters
+	strobeDuration  = 0.05
+	strobePeriod    = 1.0
+	strobeMagnitude = -3.0
+)
+
+// TrailKind is what left a trail.
+type TrailKind string
+
+// Trail kinds.
+const (
+	Satellite TrailKind = "satellite"
+	Aircraft  TrailKind = "aircraft"
+)
+
+// trailSource is the traffic one kind of trail comes from.
+type trailSource struct {
+	kind TrailKind
+
+	rate  float64 // trails per square degree per hour in the middle of the night
+	boost float64 // how many times more, on top, at twilight
+
+	minSpeed, maxSpeed float64 // degrees/second across the sky
+	minMag, maxMag     float64 // brightness of the satellite or lights
+
+	minDistance, maxDistance float64 // aircraft's, in meters
+}
+
+// trailSources are the traffic trails come from. Satellites in low orbit
+// catch the sunlight only while the Sun is a little below the horizon,
+// so they swarm around twilight.
+var trailSources = []trailSource{
+	{kind: Satellite, rate: 0.5, boost: 10, minSpeed: 0.3, maxSpeed: 1.2, minMag: 3, maxMag: 7},
+	{kind: Aircraft, rate: 0.05, boost: 2, minSpeed: 0.2, maxSpeed: 1.0, minMag: 0, maxMag: 2, minDistance: 10000, maxDistance: 40000},
+}
+
+// Trail is a satellite or aircraft trail across the frame.
+type Trail struct {
+	Kind TrailKind `json:"kind"`
+
+	// Where the trail starts and ends, in pixels
+	X0 float64 `json:"x0"`
+	Y0 float64 `json:"y0"`
+	X1 float64 `json:"x1"`
+	Y1 float64 `json:"y1"`
+
+	Width      float64 `json:"width"`      // pixels FWHM
+	Brightness float64 `json:"brightness"` // ADU per pixel of length, summed across the trail
+
+	// An aircraft's trail is a pair, one from each wingtip light,
+	// Separation pixels apart, and is dotted with strobe flashes every
+	// StrobeSpacing pixels from StrobePhase pixels along it
+	Separation       float64 `json:"separation,omitempty"`
+	StrobeSpacing    float64 `json:"strobe_spacing,omitempty"`
+	StrobePhase      float64 `json:"strobe_phase,omitempty"`
+	StrobeBrightness float64 `json:"strobe_brightness,omitempty"` // ADU per flash
+}
+
+// Trails plans the satellite and aircraft trails crossing a frame of
+// scene. How many there are follows the field of view and the exposure,
+// rising towards twilight.
+func (g *Generator) Trails(scene Scene) []Trail {
+	scene = scene.withDefaults()
+	trails := []Trail{}
+	for _, src := range trailSources {
+		lambda := src.rate * scene.area() * scene.Exposure / 3600 * twilightFactor(scene, src.boost)
+		for n := int(calibration.Poisson(g.rng, lambda)); n > 0; n-- {
+			if t, ok := g.trail(scene, src); ok {
+				trails = append(trails, t)
+			}
+		}
+	}
+	return trails
+}
+
+// twilightFactor returns how many times more trails show at the scene's
+// time than in the middle of the night: 1 plus boost falling off with the
+// hours from the nearest nautical dusk or dawn.
+func twilightFactor(scene Scene, boost float64) float64 {
+	if scene.Time.IsZero() {
+		return 1
+	}
+	nearest := math.Inf(1)
+	for day := -1; day <= 1; day++ {
+		tw := catalog.CalculateTwilight(&scene.Observer, scene.Time.AddDate(0, 0, day))
+		for _, event := range []time.Time{tw.NauticalDusk, tw.NauticalDawn} {
+			if !event.IsZero() {
+				nearest = min(nearest, math.Abs(scene.Time.Sub(event).Hours()))
+			}
+		}
+	}
+	if math.IsInf(nearest, 1) {
+		// No twilight: the Sun never sets or never rises far enough
+		return 1
+	}
+	return 1 + boost*math.Exp(-nearest/twilightScale)
+}
+
+// trail plans one trail from src on a random line across the frame. A fast
+// crossing fills the exposure's whole chord; a short exposure may catch it
+// entering or leaving. It reports false when the exposure misses it.
+func (g *Generator) trail(scene Scene, src trailSource) (Trail, bool) {
+	px, py := g.rng.Float64()*float64(scene.Width)-0.5, g.rng.Float64()*float64(scene.Height)-0.5
+	dy, dx := math.Sincos(g.rng.Float64() * math.Pi * 2)
+	enter, leave := chord(px, py, dx, dy, scene.Width, scene.Height)
+
+	speed := (src.minSpeed + g.rng.Float64()*(src.maxSpeed-src.minSpeed)) * 3600 / scene.PixelScale
+	crossing := (leave - enter) / speed
+	t0 := -crossing + g.rng.Float64()*(crossing+scene.Exposure) // when it enters
+	from := max(enter, enter-speed*t0)
+	to := min(leave, enter+speed*(scene.Exposure-t0))
+	if to-from < 1 {
+		return Trail{}, false
+	}
+
+	mag := src.minMag + g.rng.Float64()*(src.maxMag-src.minMag)
+	t := Trail{
+		Kind:       src.kind,
+		X0:         px + from*dx,
+		Y0:         py + from*dy,
+		X1:         px + to*dx,
+		Y1:         py + to*dy,
+		Width:      scene.FWHM,
+		Brightness: scene.ZeroPoint * math.Pow(10, -0.4*mag) / speed,
+	}
+	if src.kind == Aircraft {
+		distance := src.minDistance + g.rng.Float64()*(src.maxDistance-src.minDistance)
+		t.Separation = wingspan / distance * 206265 / scene.PixelScale
+		t.StrobeSpacing = speed * strobePeriod
+		t.StrobePhase = g.rng.Float64() * t.StrobeSpacing
+		t.StrobeBrightness = scene.ZeroPoint * math.Pow(10, -0.4*strobeMagnitude) * strobeDuration
+	}
+	return t, true
+}
+
+// chord returns the range of s for which (px + s·dx, py + s·dy) lies on
+// the width×height frame.
+func chord(px, py, dx, dy float64, width, height int) (enter, leave float64) {
+	enter, leave = math.Inf(-1), math.Inf(1)
+	for _, axis := range []struct{ p, d, size float64 }{{px, dx, float64(width)}, {py, dy, float64(height)}} {
+		if axis.d == 0 {
+			continue
+		}
+		a, b := (-0.5-axis.p)/axis.d, (axis.size-0.5-axis.p)/axis.d
+		enter = max(enter, min(a, b))
+		leave = min(leave, max(a, b))
+	}
+	return enter, leave
+}
+
+// Apply draws the trail into buf.
+func (t Trail) Apply(buf *Buffer) {
+	sigma := t.Width / fwhmPerSigma
+	if t.Separation == 0 {
+		drawSegment(buf, t.X0, t.Y0, t.X1, t.Y1, sigma, t.Brightness)
+		return
+	}
+
+	length := math.Hypot(t.X1-t.X0, t.Y1-t.Y0)
+	if length == 0 {
+		return
+	}
+	ux, uy := (t.X1-t.X0)/length, (t.Y1-t.Y0)/length
+	ox, oy := -uy*t.Separation/2, ux*t.Separation/2
+	drawSegment(buf, t.X0+ox, t.Y0+oy, t.X1+ox, t.Y1+oy, sigma, t.Brightness)
+	drawSegment(buf, t.X0-ox, t.Y0-oy, t.X1-ox, t.Y1-oy, sigma, t.Brightness)
+	if t.StrobeSpacing > 0 {
+		for s := t.StrobePhase; s <= length; s += t.StrobeSpacing {
+			drawBlob(buf, t.X0+s*ux, t.Y0+s*uy, sigma, t.StrobeBrightness)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+// Package astrometry converts catalog coordinates (mean place, equinox
+// J2000) to the apparent place of date (JNow) and to observed altitude and
+// azimuth, including atmospheric refraction.
+//
+// The algorithms follow Meeus, Astronomical Algorithms (2nd ed.): IAU 1976
+// precession, the low-precision nutation series and annual aberration. The
+// result is good to about an arcsecond, well below seeing, which is what a
+// real mount expecting JNow coordinates needs.
+//
+// All angles are in degrees unless noted otherwise.
+package astrometry
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	// J2000 is the Julian Date of the J2000.0 epoch.
+	J2000 = 2451545.0
+
+	deg2rad = math.Pi / 180.0
+	rad2deg = 180.0 / math.Pi
+
+	// arcsec2deg converts arcseconds to degrees.
+	arcsec2deg = 1.0 / 3600.0
+
+	// aberrationConstant is the constant of annual aberration in arcseconds.
+	aberrationConstant = 20.49552
+)
+
+// Atmosphere holds the conditions that drive refraction.
+type Atmosphere struct {
+	Temperature float64 `json:"temperature"` // Celsius
+	Pressure    float64 `json:"pressure"`    // hPa
+}
+
+// StandardAtmosphere returns 10°C and the standard pressure at the given
+// elevation in meters.
+func StandardAtmosphere(elevation float64) Atmosphere {
+	return Atmosphere{
+		Temperature: 10,
+		Pressure:    1013.25 * math.Exp(-elevation/8434.0),
+	}
+}
+
+// JulianDate returns the Julian Date (UT) of t.
+func JulianDate(t time.Time) float64 {
+	return float64(t.UTC().UnixNano())/86400e9 + 2440587.5
+}
+
+// centuries returns Julian centuries since J2000.0.
+func centuries(jd float64) float64 {
+	return (jd - J2000) / 36525.0
+}
+
+// Precess converts mean coordinates from equinox J2000 to the mean equinox
+// of the given Julian Date.
+func Precess(ra, dec, jd float64) (float64, float64) {
+	t := centuries(jd)
+	zeta := (2306.2181*t + 0.30188*t*t + 0.017998*t*t*t) * arcsec2deg * deg2rad
+	z := (2306.2181*t + 1.09468*t*t + 0.018203*t*t*t) * arcsec2deg * deg2rad
+	theta := (2004.3109*t - 0.42665*t*t - 0.041833*t*t*t) * arcsec2deg * deg2rad
+
+	a0 := ra * deg2rad
+	d0 := dec * deg2rad
+
+	a := math.Cos(d0) * math.Sin(a0+zeta)
+	b := math.Cos(theta)*math.Cos(d0)*math.Cos(a0+zeta) - math.Sin(theta)*math.Sin(d0)
+	c := math.Sin(theta)*math.Cos(d0)*math.Cos(a0+zeta) + math.Cos(theta)*math.Sin(d0)
+
+	raOut := (math.Atan2(a, b) + z) * rad2deg
+	decOut := math.Asin(math.Max(-1, math.Min(1, c))) * rad2deg
+	return wrap360(raOut), decOut
+}
+
+// Nutation returns the nutation in longitude and obliquity and the true
+// obliquity of the ecliptic for the given Julian Date, all in degrees.
+func Nutation(jd float64) (dPsi, dEps, eps float64) {
+	t := centuries(jd)
+	omega := (125.04452 - 1934.136261*t) * deg2rad
+	l := (280.4665 + 36000.7698*t) * deg2rad
+	lm := (218.3165 + 481267.8813*t) * deg2rad
+
+	dPsi = (-17.20*math.Sin(omega) - 1.32*math.Sin(2*l) - 0.23*math.Sin(2*lm) + 0.21*math.Sin(2*omega)) * arcsec2deg
+	dEps = (9.20*math.Cos(omega) + 0.57*math.Cos(2*l) + 0.10*math.Cos(2*lm) - 0.09*math.Cos(2*omega)) * arcsec2deg
+
+	eps0 := 23.0 + 26.0/60.0 + (21.448-46.8150*t-0.00059*t*t+0.001813*t*t*t)/3600.0
+	return dPsi, dEps, eps0 + dEps
+}
+
+// J2000ToApparent converts a J2000 mean position to the apparent place at
+// time t by applying precession, nutation and annual aberration.
+func J2000ToApparent(ra, dec float64, t time.Time) (float64, float64) {
+	jd := JulianDate(t)
+	ra, dec = Precess(ra, dec, jd)
+
+	dPsi, dEps, eps := Nutation(jd)
+	dE1, dN1 := nutationOffset(ra, dec, dPsi, dEps, eps)
+	dE2, dN2 := aberrationOffset(ra, dec, jd, eps)
+
+	// Displace along the local east and north directions, which stay finite
+	// at the poles where an RA offset would not
+	a, d := ra*deg2rad, dec*deg2rad
+	dE, dN := (dE1+dE2)*deg2rad, (dN1+dN2)*deg2rad
+	v := unitVector(ra, dec)
+	v[0] += -dE*math.Sin(a) - dN*math.Sin(d)*math.Cos(a)
+	v[1] += dE*math.Cos(a) - dN*math.Sin(d)*math.Sin(a)
+	v[2] += dN * math.Cos(d)
+	return fromUnitVector(v)
+}
+
+// ApparentToJ2000 inverts J2000ToApparent, e.g. for positions read back
+// from a mount that works in JNow. The iteration runs on unit vectors so it
+// converges at the poles, where RA is degenerate.
+func ApparentToJ2000(ra, dec float64, t time.Time) (float64, float64) {
+	target := unitVector(ra, dec)
+	mean := target
+	for i := 0; i < 4; i++ {
+		meanRA, meanDec := fromUnitVector(mean)
+		app := unitVector(J2000ToApparent(meanRA, meanDec, t))
+		for k := range mean {
+			mean[k] += target[k] - app[k]
+		}
+	}
+	return fromUnitVector(mean)
+}
+
+// unitVector returns the direction of ra/dec (degrees) as a unit vector.
+func unitVector(ra, dec float64) [3]float64 {
+	a, d := ra*deg2rad, dec*deg2rad
+	return [3]float64{math.Cos(d) * math.Cos(a), math.Cos(d) * math.Sin(a), math.Sin(d)}
+}
+
+// fromUnitVector returns the ra/dec (degrees) of a vector of any length.
+func fromUnitVector(v [3]float64) (ra, dec float64) {
+	ra = math.Atan2(v[1], v[0]) * rad2deg
+	dec = math.Atan2(v[2], math.Hypot(v[0], v[1])) * rad2deg
+	return wrap360(ra), dec
+}
+
+// nutationOffset returns the nutation correction (Meeus 23.1) as degrees on
+// the sky towards the east and north: the RA correction times cos(dec).
+func nutationOffset(ra, dec, dPsi, dEps, eps float64) (dEast, dNorth float64) {
+	a := ra * deg2rad
+	d := dec * deg2rad
+	e := eps * deg2rad
+
+	dEast = (math.Cos(e)*math.Cos(d)+math.Sin(e)*math.Sin(a)*math.Sin(d))*dPsi - math.Cos(a)*math.Sin(d)*dEps
+	dNorth = math.Sin(e)*math.Cos(a)*dPsi + math.Sin(a)*dEps
+	return dEast, dNorth
+}
+
+// aberrationOffset returns the annual aberration (Meeus 23.3) as degrees on
+// the sky towards the east and north.
+func aberrationOffset(ra, dec, jd, eps float64) (dEast, dNorth float64) {
+	t := centuries(jd)
+	a := ra * deg2rad
+	d := dec * deg2rad
+	e := eps * deg2rad
+
+	// Sun's true longitude
+	l0 := 280.46646 + 36000.76983*t
+	m := (357.52911 + 35999.05029*t) * deg2rad
+	c := (1.914602-0.004817*t)*math.Sin(m) + (0.019993-0.000101*t)*math.Sin(2*m) + 0.000289*math.Sin(3*m)
+	sun := (l0 + c) * deg2rad
+
+	// Earth's orbital eccentricity and longitude of perihelion
+	ecc := 0.016708634 - 0.000042037*t
+	peri := (102.93735 + 1.71946*t) * deg2rad
+
+	k := aberrationConstant * arcsec2deg
+
+	dEast = -k*(math.Cos(a)*math.Cos(sun)*math.Cos(e)+math.Sin(a)*math.Sin(sun)) +
+		ecc*k*(math.Cos(a)*math.Cos(peri)*math.Cos(e)+math.Sin(a)*math.Sin(peri))
+
+	term := math.Tan(e)*math.Cos(d) - math.Sin(a)*math.Sin(d)
+	dNorth = -k*(math.Cos(sun)*math.Cos(e)*term+math.Cos(a)*math.Sin(d)*math.Sin(sun)) +
+		ecc*k*(math.Cos(peri)*math.Cos(e)*term+math.Cos(a)*math.Sin(d)*math.Sin(peri))
+	return dEast, dNorth
+}
+
+// LocalSiderealTime returns the local apparent sidereal time in hours at
+// time t for an east-positive longitude in degrees.
+func LocalSiderealTime(t time.Time, longitude float64) float64 {
+	jd := JulianDate(t)
+	tc := centuries(jd)
+
+	gmst := 280.46061837 + 360.98564736629*(jd-J2000) + 0.000387933*tc*tc - tc*tc*tc/38710000.0
+
+	// Equation of the equinoxes turns mean into apparent sidereal time
+	dPsi, _, eps := Nutation(jd)
+	gast := gmst + dPsi*math.Cos(eps*deg2rad)
+
+	return wrap360(gast+longitude) / 15.0
+}
+
+// Refraction returns how much the atmosphere raises an object at the given
+// true (airless) altitude, in degrees (Saemundsson's formula scaled for
+// temperature and pressure). Objects well below the horizon are not refracted.
+func Refraction(alt float64, atm Atmosphere) float64 {
+	if alt < -2 {
+		return 0
+	}
+	h := math.Max(alt, -1)
+	r := 1.02 / math.Tan((h+10.3/(h+5.11))*deg2rad) // arcminutes
+	r *= (atm.Pressure / 1010.0) * (283.0 / (273.0 + atm.Temperature))
+	return math.Max(0, r/60.0)
+}
+
+// Horizontal converts an hour angle (hours) and declination to altitude and
+// azimuth (north through east) for the given latitude, without refraction.
+func Horizontal(ha, dec, lat float64) (alt, az float64) {
+	h := ha * 15 * deg2rad
+	d := dec * deg2rad
+	phi := lat * deg2rad
+
+	sinAlt := math.Sin(d)*math.Sin(phi) + math.Cos(d)*math.Cos(phi)*math.Cos(h)
+	alt = math.Asin(math.Max(-1, math.Min(1, sinAlt))) * rad2deg
+
+	y := -math.Cos(d) * math.Sin(h)
+	x := math.Sin(d)*math.Cos(phi) - math.Cos(d)*math.Sin(phi)*math.Cos(h)
+	az = wrap360(math.Atan2(y, x) * rad2deg)
+	return alt, az
+}
+
+// Observed converts a J2000 position to the altitude and azimuth an observer
+// at the given latitude/longitude sees at time t, including refraction.
+func Observed(ra, dec float64, t time.Time, lat, lon float64, atm Atmosphere) (alt, az float64) {
+	appRA, appDec := J2000ToApparent(ra, dec, t)
+	ha := LocalSiderealTime(t, lon) - appRA/15.0
+	alt, az = Horizontal(ha, appDec, lat)
+	return alt + Refraction(alt, atm), az
+}
+
+func wrap360(deg float64) float64 {
+	deg = math.Mod(deg, 360)
+	if deg < 0 {
+		deg += 360
+	}
+	return deg
+}
@@ -0,0 +1,15 @@
+package calibration
+
+import "errors"
+
+var (
+	errNoFrames        = errors.New("no frames to integrate")
+	errMixedFrames     = errors.New("frames differ in size, type or readout settings")
+	errWrongType       = errors.New("frame is the wrong image type for this master")
+	errMixedExposures  = errors.New("dark frames must share one exposure time")
+	errMismatch        = errors.New("master does not match the light frame")
+	errNeedBias        = errors.New("scaling a dark to a different exposure needs a master bias")
+	errUnknownType     = errors.New("image type must be bias, dark or flat")
+	errFlatNotNormal   = errors.New("flat master is not normalized")
+	errInvalidExposure = errors.New("exposure must be positive")
+)
@@ -0,0 +1,170 @@
+package calibration
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+
+	"github.com/darkdragonsastro/draco-simulator/internal/debayer"
+	"github.com/darkdragonsastro/draco-simulator/internal/game"
+)
+
+// Image types.
+const (
+	TypeLight = "light"
+	TypeBias  = "bias"
+	TypeDark  = "dark"
+	TypeFlat  = "flat"
+)
+
+// Frame is an image as read out, with the settings that decide which
+// masters can calibrate it.
+type Frame struct {
+	Width  int
+	Height int
+	Pixels []float32 // ADU, row by row from the top
+
+	ImageType    string
+	Exposure     float64 // seconds
+	Temperature  float64 // sensor, Celsius
+	Filter       string
+	BayerPattern string // empty for mono
+
+	// Setup identifies the readout settings frames and their masters must
+	// share, such as binning, subframe, mode, gain and offset.
+	Setup string
+}
+
+// compatible reports whether f can be integrated with or calibrated by
+// frames like g: same geometry, mosaic and readout settings.
+func (f *Frame) compatible(g *Frame) bool {
+	return f.Width == g.Width && f.Height == g.Height &&
+		f.BayerPattern == g.BayerPattern && f.Setup == g.Setup
+}
+
+// FrameSpec describes a calibration frame to synthesize.
+type FrameSpec struct {
+	ImageType   string       // bias, dark or flat
+	Readout     game.Readout // binning and subframe
+	Exposure    float64      // seconds, ignored for bias frames
+	Temperature float64      // sensor, Celsius
+	Gain        float64      // electrons per ADU; 0 spreads the full well over the ADC range
+	Offset      float64      // ADU added to every pixel
+	Seed        int64        // seeds the frame's random noise
+}
+
+// Synthesize renders a calibration frame of the model's camera with shot
+// and read noise: a bias records only the fixed bias pattern, a dark adds
+// the dark current, and a flat evenly illuminates the sensor so the
+// brightest pixels reach half the full well. The frame is in ADU of the
+// camera's ADC.
+func (m *Model) Synthesize(spec FrameSpec) (*Frame, error) {
+	switch spec.ImageType {
+	case TypeBias:
+		spec.Exposure = 0
+	case TypeDark, TypeFlat:
+		if spec.Exposure <= 0 {
+			return nil, errInvalidExposure
+		}
+	default:
+		return nil, fmt.Errorf("%w: %q", errUnknownType, spec.ImageType)
+	}
+
+	sensor := m.sensor.WithReadout(spec.Readout)
+	bin := spec.Readout.Binning()
+	width, height := sensor.SensorWidth, sensor.SensorHeight
+	maxADU := math.Min(float64(int(1)<<sensor.BitDepth-1)*float64(bin*bin), math.MaxUint16)
+	gain := spec.Gain
+	if gain <= 0 {
+		gain = float64(m.sensor.FullWellCapacity) / float64(int(1)<<sensor.BitDepth-1)
+	}
+
+	// Unbinned color frames keep the mosaic, shifted by an odd subframe
+	// origin
+	sampler := m.Sampler(spec.Readout)
+	var pattern *debayer.Pattern
+	if p, err := debayer.ParsePattern(sensor.BayerPattern); err == nil && sensor.IsColor() && bin == 1 {
+		p = p.Shift(sampler.originX, sampler.originY)
+		pattern = &p
+	}
+
+	// Flats light each channel by its response to a white panel
+	var qe [3]float64
+	flatLevel := float64(sensor.FullWellCapacity) / 2
+	if spec.ImageType == TypeFlat {
+		for c := range qe {
+			qe[c] = sensor.ChannelQE(c, flatTemperature)
+		}
+		if pattern != nil {
+			flatLevel /= max(qe[0], qe[1], qe[2])
+		} else {
+			flatLevel /= cellMean(qe)
+		}
+	}
+
+	f := &Frame{
+		Width:        width,
+		Height:       height,
+		Pixels:       make([]float32, width*height),
+		ImageType:    spec.ImageType,
+		Exposure:     spec.Exposure,
+		Temperature:  spec.Temperature,
+		BayerPattern: patternName(pattern),
+	}
+	rng := rand.New(rand.NewSource(spec.Seed))
+	darkScale := DarkScale(spec.Temperature)
+	fullWell := float64(sensor.FullWellCapacity)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			p := sampler.At(x, y)
+			signal := p.DarkRate * darkScale * spec.Exposure
+			if spec.ImageType == TypeFlat {
+				channelQE := cellMean(qe)
+				if pattern != nil {
+					channelQE = qe[pattern.At(x, y)]
+				}
+				signal += flatLevel * channelQE * p.Response
+			}
+			e := math.Min(fullWell, Poisson(rng, signal))
+			e += p.Bias + rng.NormFloat64()*sensor.ReadNoise
+			adu := math.Round(e/gain + spec.Offset)
+			f.Pixels[y*width+x] = float32(math.Max(0, math.Min(maxADU, adu)))
+		}
+	}
+	return f, nil
+}
+
+// flatTemperature is the color temperature of a white flat panel, in
+// Kelvin.
+const flatTemperature = 6500.0
+
+// cellMean averages per-channel values over a Bayer cell, one red, two
+// green and one blue pixel.
+func cellMean(v [3]float64) float64 {
+	return (v[debayer.Red] + 2*v[debayer.Green] + v[debayer.Blue]) / 4
+}
+
+// patternName returns the name of pattern, empty for none.
+func patternName(pattern *debayer.Pattern) string {
+	if pattern == nil {
+		return ""
+	}
+	return pattern.String()
+}
+
+// Poisson draws from a Poisson distribution with mean lambda, using a normal
+// approximation for large means.
+func Poisson(rng *rand.Rand, lambda float64) float64 {
+	switch {
+	case lambda <= 0:
+		return 0
+	case lambda >= 30:
+		return math.Max(0, math.Round(lambda+math.Sqrt(lambda)*rng.NormFloat64()))
+	}
+	limit := math.Exp(-lambda)
+	k := 0.0
+	for p := rng.Float64(); p > limit; p *= rng.Float64() {
+		k++
+	}
+	return k
+}
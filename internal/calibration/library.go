@@ -0,0 +1,179 @@
+package calibration
+
+import (
+	"slices"
+	"sync"
+)
+
+// Library collects the calibration frames a camera takes into running
+// masters, one each of bias, dark and flat, and applies them to lights.
+// A frame whose settings differ from the master being built starts a new
+// one, as taking a new set of darks for tonight's exposure would. Masters
+// are running means, so the library holds one frame per type however many
+// are taken.
+type Library struct {
+	mu      sync.Mutex
+	masters map[string]*Master // by image type
+	flat    *Master            // normalized flat, nil until needed
+}
+
+// NewLibrary returns an empty library.
+func NewLibrary() *Library {
+	return &Library{masters: make(map[string]*Master)}
+}
+
+// MasterInfo describes a master in the library.
+type MasterInfo struct {
+	ImageType   string  `json:"image_type"`
+	Frames      int     `json:"frames"`
+	Width       int     `json:"width"`
+	Height      int     `json:"height"`
+	Exposure    float64 `json:"exposure"`    // seconds
+	Temperature float64 `json:"temperature"` // Celsius, mean over the frames
+	Filter      string  `json:"filter,omitempty"`
+	Setup       string  `json:"setup"`
+}
+
+// Add folds a bias, dark or flat frame into its master. It reports whether
+// the frame started a new master.
+func (l *Library) Add(f *Frame) (bool, error) {
+	switch f.ImageType {
+	case TypeBias, TypeDark, TypeFlat:
+	default:
+		return false, errUnknownType
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if f.ImageType == TypeBias || f.ImageType == TypeFlat {
+		l.flat = nil
+	}
+
+	m := l.masters[f.ImageType]
+	if m == nil || !sameSet(&m.Frame, f) {
+		m = &Master{Frame: *f, Frames: 1}
+		m.Pixels = slices.Clone(f.Pixels)
+		l.masters[f.ImageType] = m
+		return true, nil
+	}
+
+	m.Frames++
+	k := 1 / float32(m.Frames)
+	for i, v := range f.Pixels {
+		m.Pixels[i] += (v - m.Pixels[i]) * k
+	}
+	m.Temperature += (f.Temperature - m.Temperature) / float64(m.Frames)
+	return false, nil
+}
+
+// sameSet reports whether f belongs with the frames of master m: the same
+// readout, and for darks the same exposure and temperature, for flats the
+// same filter.
+func sameSet(m, f *Frame) bool {
+	if !m.compatible(f) {
+		return false
+	}
+	switch f.ImageType {
+	case TypeDark:
+		return sameExposure(m.Exposure, f.Exposure) && DarkMatches(m.Temperature, f.Temperature)
+	case TypeFlat:
+		return m.Filter == f.Filter
+	}
+	return true
+}
+
+// Status describes the library's masters.
+func (l *Library) Status() []MasterInfo {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	infos := []MasterInfo{}
+	for _, t := range []string{TypeBias, TypeDark, TypeFlat} {
+		if m := l.masters[t]; m != nil {
+			infos = append(infos, MasterInfo{
+				ImageType:   m.ImageType,
+				Frames:      m.Frames,
+				Width:       m.Width,
+				Height:      m.Height,
+				Exposure:    m.Exposure,
+				Temperature: m.Temperature,
+				Filter:      m.Filter,
+				Setup:       m.Setup,
+			})
+		}
+	}
+	return infos
+}
+
+// Matching returns how many frames went into each master that can
+// calibrate light, by image type.
+func (l *Library) Matching(light *Frame) map[string]int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	m := l.matching(light)
+	counts := make(map[string]int)
+	for _, master := range []*Master{m.Bias, m.Dark, m.Flat} {
+		if master != nil {
+			counts[master.ImageType] = master.Frames
+		}
+	}
+	return counts
+}
+
+// Calibrate applies the masters that match light and returns the
+// calibrated frame with the image types of the masters applied.
+func (l *Library) Calibrate(light *Frame) (*Frame, []string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	m := l.matching(light)
+	if m.Flat != nil {
+		if l.flat == nil {
+			flat, err := NormalizeFlat(m.Flat, m.Bias)
+			if err != nil {
+				return nil, nil, err
+			}
+			l.flat = flat
+		}
+		m.Flat = l.flat
+	}
+
+	out, err := Calibrate(light, m)
+	if err != nil {
+		return nil, nil, err
+	}
+	var applied []string
+	for _, master := range []*Master{m.Bias, m.Dark, m.Flat} {
+		if master != nil {
+			applied = append(applied, master.ImageType)
+		}
+	}
+	return out, applied, nil
+}
+
+// matching returns the masters that can calibrate light. A dark must have
+// been taken at the light's temperature, and at its exposure unless a bias
+// lets it be scaled. A bias is redundant alongside a dark of the light's
+// exposure except to calibrate the flat. Must be called with the lock
+// held.
+func (l *Library) matching(light *Frame) Masters {
+	var m Masters
+	if b := l.masters[TypeBias]; b != nil && b.compatible(light) {
+		m.Bias = b
+	}
+	if d := l.masters[TypeDark]; d != nil && d.compatible(light) && DarkMatches(d.Temperature, light.Temperature) {
+		if m.Bias != nil || sameExposure(d.Exposure, light.Exposure) {
+			m.Dark = d
+		}
+	}
+	if f := l.masters[TypeFlat]; f != nil && f.compatible(light) && f.Filter == light.Filter {
+		m.Flat = f
+	}
+	return m
+}
+
+// Reset empties the library.
+func (l *Library) Reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	clear(l.masters)
+	l.flat = nil
+}
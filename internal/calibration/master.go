@@ -0,0 +1,308 @@
+package calibration
+
+import (
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/darkdragonsastro/draco-simulator/internal/debayer"
+)
+
+const (
+	// clipSigma is how far from the median, in robust standard deviations,
+	// a pixel may stray before integration rejects it, as cosmic ray hits
+	// and satellite trails do.
+	clipSigma = 3.0
+
+	// minClipFrames is the fewest frames clipping is attempted with.
+	minClipFrames = 3
+
+	// darkTemperatureTolerance is how far apart, in Celsius, a dark and the
+	// light it calibrates may have been taken before the dark current no
+	// longer matches.
+	darkTemperatureTolerance = 2.0
+
+	// exposureTolerance is the relative difference in exposure below which
+	// two exposures count as equal.
+	exposureTolerance = 0.01
+
+	// minFlatResponse is the normalized flat level below which a pixel is
+	// left uncorrected rather than amplified into noise.
+	minFlatResponse = 0.05
+)
+
+// Master is an integrated calibration frame.
+type Master struct {
+	Frame
+
+	Frames         int  // how many frames were integrated
+	BiasSubtracted bool // darks and flats: the bias has been removed
+	Normalized     bool // flats: pixels are relative response, averaging 1
+}
+
+// Masters are the masters to calibrate a light frame with. Any may be nil.
+type Masters struct {
+	Bias *Master
+	Dark *Master
+	Flat *Master
+}
+
+// Integrate combines frames of one type and setup into a master, pixel by
+// pixel, as the mean of the values within clipSigma of the median.
+func Integrate(frames []*Frame) (*Master, error) {
+	if len(frames) == 0 {
+		return nil, errNoFrames
+	}
+	first := frames[0]
+	for _, f := range frames[1:] {
+		if !f.compatible(first) || f.ImageType != first.ImageType || len(f.Pixels) != len(first.Pixels) {
+			return nil, errMixedFrames
+		}
+	}
+
+	out := &Master{Frame: *first, Frames: len(frames)}
+	out.Pixels = make([]float32, len(first.Pixels))
+	var exposure, temperature float64
+	for _, f := range frames {
+		exposure += f.Exposure
+		temperature += f.Temperature
+	}
+	out.Exposure = exposure / float64(len(frames))
+	out.Temperature = temperature / float64(len(frames))
+
+	values := make([]float64, len(frames))
+	for i := range out.Pixels {
+		for j, f := range frames {
+			values[j] = float64(f.Pixels[i])
+		}
+		out.Pixels[i] = float32(clippedMean(values))
+	}
+	return out, nil
+}
+
+// clippedMean returns the mean of values after rejecting those more than
+// clipSigma robust standard deviations from the median. values is
+// reordered.
+func clippedMean(values []float64) float64 {
+	if len(values) < minClipFrames {
+		var sum float64
+		for _, v := range values {
+			sum += v
+		}
+		return sum / float64(len(values))
+	}
+	slices.Sort(values)
+	median := values[len(values)/2]
+
+	// The median absolute deviation, scaled to a standard deviation
+	dev := make([]float64, len(values))
+	for i, v := range values {
+		dev[i] = math.Abs(v - median)
+	}
+	slices.Sort(dev)
+	sigma := 1.4826 * dev[len(dev)/2]
+
+	var sum float64
+	var n int
+	for _, v := range values {
+		if sigma > 0 && math.Abs(v-median) > clipSigma*sigma {
+			continue
+		}
+		sum += v
+		n++
+	}
+	if n == 0 {
+		return median
+	}
+	return sum / float64(n)
+}
+
+// MasterBias integrates bias frames.
+func MasterBias(frames []*Frame) (*Master, error) {
+	if err := checkType(frames, TypeBias); err != nil {
+		return nil, err
+	}
+	return Integrate(frames)
+}
+
+// MasterDark integrates dark frames of one exposure. With a master bias
+// the bias is subtracted, leaving only dark current, so the master can be
+// scaled to lights of other exposures.
+func MasterDark(frames []*Frame, bias *Master) (*Master, error) {
+	if err := checkType(frames, TypeDark); err != nil {
+		return nil, err
+	}
+	for _, f := range frames[1:] {
+		if !sameExposure(f.Exposure, frames[0].Exposure) {
+			return nil, errMixedExposures
+		}
+	}
+	m, err := Integrate(frames)
+	if err != nil {
+		return nil, err
+	}
+	if bias != nil {
+		if !bias.compatible(&m.Frame) {
+			return nil, errMismatch
+		}
+		subtract(m.Pixels, bias.Pixels, 1)
+		m.BiasSubtracted = true
+	}
+	return m, nil
+}
+
+// MasterFlat integrates flat frames and normalizes them to relative
+// response. The bias, if given, is subtracted first; without it the
+// camera's offset is left in and slightly weakens the correction.
+func MasterFlat(frames []*Frame, bias *Master) (*Master, error) {
+	if err := checkType(frames, TypeFlat); err != nil {
+		return nil, err
+	}
+	m, err := Integrate(frames)
+	if err != nil {
+		return nil, err
+	}
+	return NormalizeFlat(m, bias)
+}
+
+// NormalizeFlat returns flat with the bias, if given, subtracted and each
+// pixel divided by the mean of its channel, so the flat corrects
+// vignetting and dust without shifting the color balance of mosaic
+// frames.
+func NormalizeFlat(flat *Master, bias *Master) (*Master, error) {
+	if flat.ImageType != TypeFlat {
+		return nil, errWrongType
+	}
+	if flat.Normalized {
+		return flat, nil
+	}
+	out := *flat
+	out.Pixels = slices.Clone(flat.Pixels)
+	if bias != nil && !flat.BiasSubtracted {
+		if !bias.compatible(&flat.Frame) {
+			return nil, errMismatch
+		}
+		subtract(out.Pixels, bias.Pixels, 1)
+		out.BiasSubtracted = true
+	}
+
+	pattern, err := debayer.ParsePattern(flat.BayerPattern)
+	mosaic := err == nil
+	var sum [3]float64
+	var n [3]int
+	for y := 0; y < out.Height; y++ {
+		for x := 0; x < out.Width; x++ {
+			c := 0
+			if mosaic {
+				c = int(pattern.At(x, y))
+			}
+			sum[c] += float64(out.Pixels[y*out.Width+x])
+			n[c]++
+		}
+	}
+	var mean [3]float64
+	for c := range mean {
+		if n[c] > 0 {
+			mean[c] = sum[c] / float64(n[c])
+		}
+	}
+	for y := 0; y < out.Height; y++ {
+		for x := 0; x < out.Width; x++ {
+			c := 0
+			if mosaic {
+				c = int(pattern.At(x, y))
+			}
+			i := y*out.Width + x
+			if mean[c] > 0 {
+				out.Pixels[i] = float32(float64(out.Pixels[i]) / mean[c])
+			}
+		}
+	}
+	out.Normalized = true
+	return &out, nil
+}
+
+// Calibrate returns light with the masters applied: the dark (or, without
+// one, the bias) subtracted and the result divided by the flat. A dark
+// taken at another exposure is scaled to the light's, which needs the bias
+// to separate it from the dark current. Calibrated pixels are in ADU above
+// zero and may be negative where noise dips below the removed signal.
+func Calibrate(light *Frame, m Masters) (*Frame, error) {
+	out := *light
+	out.Pixels = slices.Clone(light.Pixels)
+
+	for _, master := range []*Master{m.Bias, m.Dark, m.Flat} {
+		if master != nil && !master.compatible(light) {
+			return nil, fmt.Errorf("%w: %s", errMismatch, master.ImageType)
+		}
+	}
+
+	switch {
+	case m.Dark != nil && !m.Dark.BiasSubtracted && sameExposure(m.Dark.Exposure, light.Exposure):
+		// The dark carries the bias along with the matching dark current
+		subtract(out.Pixels, m.Dark.Pixels, 1)
+	case m.Dark != nil:
+		if m.Bias == nil {
+			return nil, errNeedBias
+		}
+		subtract(out.Pixels, m.Bias.Pixels, 1)
+		scale := 0.0
+		if m.Dark.Exposure > 0 {
+			scale = light.Exposure / m.Dark.Exposure
+		}
+		if m.Dark.BiasSubtracted {
+			subtract(out.Pixels, m.Dark.Pixels, scale)
+		} else {
+			for i := range out.Pixels {
+				out.Pixels[i] -= float32(scale) * (m.Dark.Pixels[i] - m.Bias.Pixels[i])
+			}
+		}
+	case m.Bias != nil:
+		subtract(out.Pixels, m.Bias.Pixels, 1)
+	}
+
+	if m.Flat != nil {
+		if !m.Flat.Normalized {
+			return nil, errFlatNotNormal
+		}
+		for i, f := range m.Flat.Pixels {
+			if f >= minFlatResponse {
+				out.Pixels[i] /= f
+			}
+		}
+	}
+	return &out, nil
+}
+
+// DarkMatches reports whether a dark taken at darkTemp Celsius calibrates
+// a light taken at lightTemp.
+func DarkMatches(darkTemp, lightTemp float64) bool {
+	return math.Abs(darkTemp-lightTemp) <= darkTemperatureTolerance
+}
+
+// checkType reports an error unless every frame is of imageType.
+func checkType(frames []*Frame, imageType string) error {
+	if len(frames) == 0 {
+		return errNoFrames
+	}
+	for _, f := range frames {
+		if f.ImageType != imageType {
+			return fmt.Errorf("%w: %s in a master %s", errWrongType, f.ImageType, imageType)
+		}
+	}
+	return nil
+}
+
+// sameExposure reports whether two exposures match within
+// exposureTolerance.
+func sameExposure(a, b float64) bool {
+	return math.Abs(a-b) <= exposureTolerance*math.Max(a, b)
+}
+
+// subtract removes scale·b from a, pixel by pixel.
+func subtract(a, b []float32, scale float64) {
+	s := float32(scale)
+	for i := range a {
+		a[i] -= s * b[i]
+	}
+}
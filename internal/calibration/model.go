@@ -0,0 +1,272 @@
+// Package calibration models the fixed-pattern defects of a camera and its
+// optics, synthesizes the bias, dark and flat frames that record them, and
+// builds master frames that remove them from light frames.
+//
+// Every defect is a deterministic function of the camera's seed and the
+// pixel, so the same camera always shows the same hot pixels, amp glow and
+// dust, and masters built from one night's frames keep working.
+package calibration
+
+import (
+	"math"
+
+	"github.com/darkdragonsastro/draco-simulator/internal/game"
+)
+
+const (
+	// DarkDoubling is how many degrees Celsius double the dark current.
+	DarkDoubling = 6.0
+
+	// Fixed-pattern bias, as fractions of the read noise: an offset per
+	// column (banding) and per pixel.
+	columnBiasNoise = 0.4
+	pixelBiasNoise  = 0.25
+
+	// darkNonUniformity is the pixel-to-pixel spread of dark current.
+	darkNonUniformity = 0.3
+
+	// hotPixelFraction of pixels leak far more dark current than the rest,
+	// between hotPixelMin and hotPixelMin·2^hotPixelOctaves times as much.
+	hotPixelFraction = 3e-4
+	hotPixelMin      = 100.0
+	hotPixelOctaves  = 5.0
+
+	// ampGlow is the extra dark current at the glowing corner, as a
+	// multiple of the nominal dark current, fading over ampGlowReach of the
+	// sensor diagonal.
+	ampGlow      = 10.0
+	ampGlowReach = 0.1
+
+	// responseNonUniformity is the pixel-to-pixel spread of sensitivity
+	// (PRNU).
+	responseNonUniformity = 0.01
+
+	// Dust motes sit this far in front of the sensor, on its window and
+	// filters, in microns, and block up to maxDustDepth of the light.
+	minDustDistance = 1000.0
+	maxDustDistance = 6000.0
+	minDustDepth    = 0.02
+	maxDustDepth    = 0.08
+	maxDustMotes    = 6
+)
+
+// Salts separating the hashes of each defect.
+const (
+	saltColumnBias uint64 = iota + 1
+	saltPixelBias
+	saltDark
+	saltHot
+	saltHotLevel
+	saltResponse
+	saltDust
+)
+
+// dust is a mote's shadow on the sensor, in native pixels.
+type dust struct {
+	x, y   float64
+	radius float64
+	inner  float64 // radius of the hole a central obstruction leaves
+	depth  float64 // fraction of light blocked
+}
+
+// Model is the fixed pattern of one camera on one telescope, at native
+// resolution: bias structure, dark current with hot pixels and amp glow,
+// and the light response shaped by sensitivity variations, vignetting and
+// dust shadows.
+type Model struct {
+	sensor game.VirtualCameraConfig
+	optics game.VirtualTelescopeConfig
+	seed   uint64
+
+	columnBias []float32 // electrons, per native column
+	glowX      float64   // corner the amp glow spreads from
+	glowY      float64
+	dust       []dust
+}
+
+// NewModel returns the fixed pattern of a camera, identified by seed, on
+// the given optics. The sensor is taken at native resolution.
+func NewModel(sensor game.VirtualCameraConfig, optics game.VirtualTelescopeConfig, seed int64) *Model {
+	m := &Model{sensor: sensor, optics: optics, seed: uint64(seed)}
+
+	m.columnBias = make([]float32, sensor.SensorWidth)
+	for x := range m.columnBias {
+		m.columnBias[x] = float32(columnBiasNoise * sensor.ReadNoise * m.gaussian(saltColumnBias, x, 0))
+	}
+
+	// Amp glow comes from the readout electronics at one corner
+	corner := m.hash(saltDark, -1, -1)
+	if corner&1 != 0 {
+		m.glowX = float64(sensor.SensorWidth)
+	}
+	if corner&2 != 0 {
+		m.glowY = float64(sensor.SensorHeight)
+	}
+
+	m.dust = m.dustMotes()
+	return m
+}
+
+// Sensor returns the camera the model describes, at native resolution.
+func (m *Model) Sensor() game.VirtualCameraConfig {
+	return m.sensor
+}
+
+// dustMotes places the dust shadows. Their size follows the focal ratio:
+// the light cone converging on the sensor is wider the further the mote
+// sits from it and the faster the optics. A central obstruction turns the
+// shadows into donuts.
+func (m *Model) dustMotes() []dust {
+	ratio := m.optics.FocalRatio
+	if ratio <= 0 && m.optics.Aperture > 0 {
+		ratio = m.optics.FocalLength / m.optics.Aperture
+	}
+	if ratio <= 0 || m.sensor.PixelSize <= 0 {
+		return nil
+	}
+	var obstruction float64
+	switch m.optics.OpticsType {
+	case "reflector":
+		obstruction = 0.35
+	case "catadioptric":
+		obstruction = 0.33
+	}
+
+	n := 1 + int(m.hash(saltDust, -1, 0)%maxDustMotes)
+	motes := make([]dust, n)
+	for i := range motes {
+		distance := minDustDistance + m.uniform(saltDust, i, 1)*(maxDustDistance-minDustDistance)
+		radius := distance / (2 * ratio) / m.sensor.PixelSize
+		motes[i] = dust{
+			x:      m.uniform(saltDust, i, 2) * float64(m.sensor.SensorWidth),
+			y:      m.uniform(saltDust, i, 3) * float64(m.sensor.SensorHeight),
+			radius: radius,
+			inner:  radius * obstruction,
+			depth:  minDustDepth + m.uniform(saltDust, i, 4)*(maxDustDepth-minDustDepth),
+		}
+	}
+	return motes
+}
+
+// Pixel is the fixed pattern of one pixel as read out.
+type Pixel struct {
+	Bias     float64 // electrons, added at readout
+	DarkRate float64 // electrons/second at 20 °C
+	Response float64 // fraction of the light reaching the pixel that it records
+}
+
+// Sampler evaluates the fixed pattern at the pixels of one readout, summing
+// the native pixels each binned pixel combines.
+type Sampler struct {
+	m                *Model
+	bin              int
+	originX, originY int // binned
+}
+
+// Sampler returns a sampler for frames read out with r.
+func (m *Model) Sampler(r game.Readout) Sampler {
+	s := Sampler{m: m, bin: r.Binning()}
+	if r.ROI != nil {
+		s.originX, s.originY = r.ROI.X, r.ROI.Y
+	}
+	return s
+}
+
+// At returns the fixed pattern of frame pixel (x, y). Binned pixels sum
+// the bias and dark current of the pixels they combine and average their
+// response.
+func (s Sampler) At(x, y int) Pixel {
+	var p Pixel
+	nx0, ny0 := (s.originX+x)*s.bin, (s.originY+y)*s.bin
+	for ny := ny0; ny < ny0+s.bin; ny++ {
+		for nx := nx0; nx < nx0+s.bin; nx++ {
+			q := s.m.native(nx, ny)
+			p.Bias += q.Bias
+			p.DarkRate += q.DarkRate
+			p.Response += q.Response
+		}
+	}
+	p.Response /= float64(s.bin * s.bin)
+	return p
+}
+
+// DarkScale returns how much the dark current at temperature Celsius
+// exceeds that at 20 °C.
+func DarkScale(temperature float64) float64 {
+	return math.Pow(2, (temperature-20)/DarkDoubling)
+}
+
+// native returns the fixed pattern of native pixel (x, y).
+func (m *Model) native(x, y int) Pixel {
+	var p Pixel
+	if x >= 0 && x < len(m.columnBias) {
+		p.Bias = float64(m.columnBias[x])
+	}
+	p.Bias += pixelBiasNoise * m.sensor.ReadNoise * m.gaussian(saltPixelBias, x, y)
+
+	dark := m.sensor.DarkCurrent
+	p.DarkRate = dark * math.Max(0, 1+darkNonUniformity*m.gaussian(saltDark, x, y))
+	if m.uniform(saltHot, x, y) < hotPixelFraction {
+		p.DarkRate += dark * hotPixelMin * math.Pow(2, hotPixelOctaves*m.uniform(saltHotLevel, x, y))
+	}
+	if dark > 0 {
+		diag := math.Hypot(float64(m.sensor.SensorWidth), float64(m.sensor.SensorHeight))
+		d := math.Hypot(float64(x)+0.5-m.glowX, float64(y)+0.5-m.glowY)
+		p.DarkRate += dark * ampGlow * math.Exp(-d/(ampGlowReach*diag))
+	}
+
+	p.Response = (1 + responseNonUniformity*m.gaussian(saltResponse, x, y)) * m.illumination(float64(x)+0.5, float64(y)+0.5)
+	return p
+}
+
+// illumination returns the fraction of the light reaching native position
+// (x, y) past vignetting and dust.
+func (m *Model) illumination(x, y float64) float64 {
+	light := 1.0
+	cx, cy := float64(m.sensor.SensorWidth)/2, float64(m.sensor.SensorHeight)/2
+	if loss := m.optics.Vignetting / 100; loss > 0 && cx > 0 && cy > 0 {
+		dx, dy := x-cx, y-cy
+		light -= loss * (dx*dx + dy*dy) / (cx*cx + cy*cy)
+	}
+	for _, d := range m.dust {
+		r := math.Hypot(x-d.x, y-d.y)
+		if r >= d.radius {
+			continue
+		}
+		// Soft edges a tenth of the radius wide
+		edge := 0.1 * d.radius
+		shadow := math.Min(1, (d.radius-r)/edge)
+		if d.inner > 0 {
+			shadow *= math.Max(0, math.Min(1, (r-d.inner)/edge))
+		}
+		light *= 1 - d.depth*shadow
+	}
+	return light
+}
+
+// hash returns a well-mixed 64-bit value for pixel (x, y) under salt
+// (SplitMix64).
+func (m *Model) hash(salt uint64, x, y int) uint64 {
+	z := m.seed ^ salt*0x9e3779b97f4a7c15 ^ uint64(uint32(x))<<32 ^ uint64(uint32(y))
+	z += 0x9e3779b97f4a7c15
+	z = (z ^ z>>30) * 0xbf58476d1ce4e5b9
+	z = (z ^ z>>27) * 0x94d049bb133111eb
+	return z ^ z>>31
+}
+
+// uniform returns a value in [0, 1) for pixel (x, y) under salt.
+func (m *Model) uniform(salt uint64, x, y int) float64 {
+	return float64(m.hash(salt, x, y)>>11) / (1 << 53)
+}
+
+// gaussian returns an approximately standard normal value for pixel (x, y)
+// under salt, summing the four 16-bit uniforms of one hash.
+func (m *Model) gaussian(salt uint64, x, y int) float64 {
+	h := m.hash(salt, x, y)
+	var sum float64
+	for i := 0; i < 4; i++ {
+		sum += float64(h>>(16*i)&0xffff) / 0xffff
+	}
+	// Four uniforms sum to mean 2, variance 1/3
+	return (sum - 2) * math.Sqrt(3)
+}
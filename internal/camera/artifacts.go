@@ -0,0 +1,93 @@
+package camera
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+
+	"github.com/darkdragonsastro/draco-simulator/internal/artifacts"
+	"github.com/darkdragonsastro/draco-simulator/internal/catalog"
+)
+
+// sunTemperature is the color temperature of sunlight, which satellites
+// shine by.
+const sunTemperature = 5800.0
+
+// addArtifacts adds the defects a frame asked for to its electrons: cosmic
+// ray hits, and on lights satellite and aircraft trails and the
+// light-pollution gradient. The sensor's own hot and cold pixels are
+// already in its fixed pattern. The light dome belongs to the site, so its
+// generator is seeded by the site; the rest are seeded by the camera and
+// when the shutter opened, so a frame's artifacts can be reproduced from
+// its header.
+func (r *renderer) addArtifacts(electrons []float32, info *FrameInfo) *artifacts.Report {
+	buf := &artifacts.Buffer{
+		Width:      r.width,
+		Height:     r.height,
+		Pixels:     electrons,
+		Saturation: math.MaxFloat32,
+	}
+	scene := artifacts.Scene{
+		Width:    r.width,
+		Height:   r.height,
+		Time:     info.StartTime,
+		Exposure: info.Duration,
+		Observer: catalog.Observer{
+			Latitude:  info.Site.Latitude,
+			Longitude: info.Site.Longitude,
+			Elevation: info.Site.Elevation,
+		},
+		Altitude:        info.Altitude,
+		Azimuth:         info.Azimuth,
+		Rotation:        parallacticAngle(info) - info.Rotation,
+		PixelScale:      r.scale,
+		PixelSize:       info.PixelSize,
+		FWHM:            info.FWHM,
+		ZeroPoint:       zeroPointFlux * r.collectingArea() * r.channelQE(sunTemperature).mean(),
+		ElectronsPerADU: 1, // the buffer holds electrons
+		Background:      info.SkyBackground,
+		BortleClass:     r.cond.BortleClass,
+	}
+
+	frame := artifacts.NewGenerator(r.artifactSeed(info))
+	report := &artifacts.Report{CosmicRays: frame.CosmicRays(scene), Trails: []artifacts.Trail{}}
+	if r.exp.imageType == ImageLight && info.Altitude > 0 {
+		report.Trails = frame.Trails(scene)
+		report.Gradient = siteGenerator(info.Site).Gradient(scene)
+		report.Gradient.Apply(buf)
+		for _, t := range report.Trails {
+			t.Apply(buf)
+		}
+	}
+	for _, c := range report.CosmicRays {
+		c.Apply(buf)
+	}
+	return report
+}
+
+// artifactSeed returns the seed of a frame's artifacts, from the camera
+// and the simulated time its shutter opened.
+func (r *renderer) artifactSeed(info *FrameInfo) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(r.cameraName))
+	binary.Write(h, binary.LittleEndian, info.StartTime.UnixNano())
+	return int64(h.Sum64())
+}
+
+// siteGenerator returns the artifact generator of a site, whose light
+// dome stands on the same horizon every night.
+func siteGenerator(site Site) *artifacts.Generator {
+	h := fnv.New64a()
+	binary.Write(h, binary.LittleEndian, [2]float64{site.Latitude, site.Longitude})
+	return artifacts.NewGenerator(int64(h.Sum64()))
+}
+
+// parallacticAngle returns the position angle in degrees, east of north,
+// of the direction to the zenith from the frame's centre.
+func parallacticAngle(info *FrameInfo) float64 {
+	const rad = math.Pi / 180
+	sinA, _ := math.Sincos(info.Azimuth * rad)
+	sinLat, cosLat := math.Sincos(info.Site.Latitude * rad)
+	sinAlt, cosAlt := math.Sincos(info.Altitude * rad)
+	return math.Atan2(-sinA*cosLat*cosAlt, sinLat-math.Sin(info.Dec*rad)*sinAlt) / rad
+}
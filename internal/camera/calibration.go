@@ -0,0 +1,108 @@
+package camera
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+
+	"github.com/darkdragonsastro/draco-simulator/internal/calibration"
+	"github.com/darkdragonsastro/draco-simulator/internal/game"
+)
+
+// EventCalibration reports the calibration library after a bias, dark or
+// flat frame is folded into its master.
+const EventCalibration = "camera.calibration"
+
+// calibratedPedestal is added to calibrated pixels, in 16-bit ADU, so the
+// noise around a background calibrated to zero survives as unsigned pixels.
+const calibratedPedestal = 100
+
+// newModel returns the fixed pattern of the loadout's camera on its
+// telescope. A camera's defects are seeded by its ID, so each model of
+// camera keeps the same hot pixels and dust from session to session.
+func newModel(loadout game.EquipmentLoadout, sensor game.VirtualCameraConfig, optics game.VirtualTelescopeConfig) *calibration.Model {
+	h := fnv.New64a()
+	h.Write([]byte(loadout.Camera))
+	return calibration.NewModel(sensor, optics, int64(h.Sum64()))
+}
+
+// calibrationFrame returns the frame for the calibration library, in
+// 16-bit ADU.
+func (f *Frame) calibrationFrame() *calibration.Frame {
+	info := f.Info
+	cf := &calibration.Frame{
+		Width:        f.Width,
+		Height:       f.Height,
+		Pixels:       make([]float32, len(f.Pixels)),
+		ImageType:    info.ImageType,
+		Exposure:     info.Duration,
+		Temperature:  info.SensorTemp,
+		Filter:       info.Filter,
+		BayerPattern: info.BayerPattern,
+		Setup: fmt.Sprintf("%s %dx%d+%d+%d bin%d %s gain %d offset %d", info.Camera,
+			f.Width, f.Height, info.SubframeX, info.SubframeY, info.BinX, info.ReadoutMode, info.Gain, info.Offset),
+	}
+	for i, v := range f.Pixels {
+		cf.Pixels[i] = float32(v)
+	}
+	return cf
+}
+
+// CalibrationStatus describes the masters built from the calibration frames
+// taken so far.
+func (s *Simulator) CalibrationStatus() []calibration.MasterInfo {
+	return s.library.Status()
+}
+
+// ResetCalibration discards the calibration masters.
+func (s *Simulator) ResetCalibration() {
+	s.library.Reset()
+	s.emit(EventCalibration, s.library.Status())
+}
+
+// CalibratedFrame returns the last light frame calibrated with whichever
+// masters match it. Info.Calibration lists the masters applied.
+func (s *Simulator) CalibratedFrame() (*Frame, error) {
+	frame, err := s.LastFrame()
+	if err != nil {
+		return nil, err
+	}
+	if frame.Info.ImageType != ImageLight {
+		return nil, errNotLight
+	}
+
+	calibrated, applied, err := s.library.Calibrate(frame.calibrationFrame())
+	if err != nil {
+		return nil, err
+	}
+	if len(applied) == 0 {
+		return nil, errNoMasters
+	}
+
+	out := &Frame{
+		Width:  frame.Width,
+		Height: frame.Height,
+		Pixels: make([]uint16, len(frame.Pixels)),
+		Info:   frame.Info,
+	}
+	out.Info.Calibration = applied
+	out.Info.Pedestal = calibratedPedestal
+	for i, v := range calibrated.Pixels {
+		out.Pixels[i] = uint16(math.Max(0, math.Min(math.MaxUint16, math.Round(float64(v)+calibratedPedestal))))
+	}
+	return out, nil
+}
+
+// addCalibration folds a finished calibration frame into the library, or
+// for a light reports which masters match it, by image type with their
+// frame counts.
+func (s *Simulator) addCalibration(frame *Frame) map[string]int {
+	cf := frame.calibrationFrame()
+	if frame.Info.ImageType == ImageLight {
+		return s.library.Matching(cf)
+	}
+	if _, err := s.library.Add(cf); err == nil {
+		s.emit(EventCalibration, s.library.Status())
+	}
+	return nil
+}
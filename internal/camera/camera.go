@@ -0,0 +1,660 @@
+// Package camera simulates an astronomical camera. Frames are rendered from
+// the star catalog around wherever the mount points, with a PSF set by the
+// seeing and optics and the noise of the equipped sensor.
+package camera
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/darkdragonsastro/draco-simulator/internal/calibration"
+	"github.com/darkdragonsastro/draco-simulator/internal/catalog"
+	"github.com/darkdragonsastro/draco-simulator/internal/game"
+	"github.com/darkdragonsastro/draco-simulator/internal/mount"
+	"github.com/darkdragonsastro/draco-simulator/internal/simclock"
+)
+
+// Event types published through the event handler.
+const (
+	EventExposureStarted  = "capture.exposure.started"
+	EventExposureComplete = "capture.exposure.complete"
+	EventExposureAborted  = "capture.exposure.aborted"
+)
+
+// Image types.
+const (
+	ImageLight = "light"
+	ImageDark  = "dark"
+	ImageBias  = "bias"
+	ImageFlat  = "flat"
+)
+
+// Camera states, following ASCOM CameraStates.
+const (
+	StateIdle     = "idle"
+	StateExposing = "exposing"
+	StateReading  = "reading"
+)
+
+// maxExposure is the longest exposure accepted, in seconds.
+const maxExposure = 3600.0
+
+// Mount reports where the telescope points.
+type Mount interface {
+	GetStatus() mount.MountStatus
+}
+
+// Conditions are the sky conditions a frame is taken under.
+type Conditions struct {
+	Seeing       float64 // arcseconds FWHM
+	Transparency float64 // 0-1
+	CloudCover   float64 // 0-1
+	BortleClass  int     // 1-9
+	Temperature  float64 // ambient, Celsius
+}
+
+// DefaultConditions returns a clear suburban night.
+func DefaultConditions() Conditions {
+	return Conditions{Seeing: 2.5, Transparency: 0.8, BortleClass: 6, Temperature: 15}
+}
+
+// Site is where the observatory stands, for frame headers.
+type Site struct {
+	Latitude  float64 `json:"latitude"`  // degrees, positive north
+	Longitude float64 `json:"longitude"` // degrees, positive east
+	Elevation float64 `json:"elevation"` // meters
+}
+
+// Config holds camera simulator configuration.
+type Config struct {
+	// Loadout selects the camera and telescope; the field of view and
+	// sensor characteristics follow from them
+	Loadout game.EquipmentLoadout
+
+	// Stars is the catalog frames are rendered from
+	Stars catalog.StarCatalog
+
+	// Mount supplies the pointing; nil renders an empty sky
+	Mount Mount
+
+	// Clock supplies simulated time; nil follows real time
+	Clock *simclock.Clock
+}
+
+// Status is a snapshot of the camera.
+type Status struct {
+	Connected bool   `json:"connected"`
+	State     string `json:"state"` // "idle"|"exposing"|"reading"
+
+	ImageType        string  `json:"image_type,omitempty"`
+	ExposureDuration float64 `json:"exposure_duration"` // seconds
+	ExposureProgress float64 `json:"exposure_progress"` // 0-1
+	ImageReady       bool    `json:"image_ready"`
+
+	Camera       string  `json:"camera"`
+	Telescope    string  `json:"telescope"`
+	SensorWidth  int     `json:"sensor_width"`  // pixels
+	SensorHeight int     `json:"sensor_height"` // pixels
+	PixelSize    float64 `json:"pixel_size"`    // microns
+	BitDepth     int     `json:"bit_depth"`
+	BayerPattern string  `json:"bayer_pattern,omitempty"` // empty for mono
+
+	// Readout and the frames it produces
+	Readout      game.Readout       `json:"readout"`
+	MaxBin       int                `json:"max_bin"`
+	ReadoutModes []game.ReadoutMode `json:"readout_modes"`
+	FrameWidth   int                `json:"frame_width"`  // pixels, binned
+	FrameHeight  int                `json:"frame_height"` // pixels, binned
+	PixelScale   float64            `json:"pixel_scale"`  // arcsec/pixel, binned
+	FOVWidth     float64            `json:"fov_width"`    // arcmin, of the frame
+	FOVHeight    float64            `json:"fov_height"`   // arcmin, of the frame
+
+	Gain      int     `json:"gain"`
+	Offset    int     `json:"offset"`
+	GainRange [2]int  `json:"gain_range"`
+	Defocus   float64 `json:"defocus"` // pixels HFR added by focus error
+
+	SensorTemperature float64      `json:"sensor_temperature"` // Celsius
+	HasCooler         bool         `json:"has_cooler"`
+	Cooler            CoolerStatus `json:"cooler"`
+}
+
+// ExposureRequest starts an exposure.
+type ExposureRequest struct {
+	Duration  float64 `json:"duration"`         // seconds, ignored for bias frames
+	ImageType string  `json:"image_type"`       // light|dark|bias|flat, default light
+	Gain      *int    `json:"gain,omitempty"`   // nil keeps the current gain
+	Offset    *int    `json:"offset,omitempty"` // nil keeps the current offset
+	Object    string  `json:"object,omitempty"` // target name for the frame header
+	Filter    string  `json:"filter,omitempty"` // filter name, default "L"
+
+	// Artifacts adds satellite and aircraft trails, cosmic ray hits and
+	// the light-pollution gradient to the frame
+	Artifacts bool `json:"artifacts,omitempty"`
+}
+
+// defaultFilter is the filter a mono camera shoots through unless told
+// otherwise: the broadband luminance the renderer models.
+const defaultFilter = "L"
+
+// Simulator is a simulated camera on the simulated telescope.
+type Simulator struct {
+	mu     sync.RWMutex
+	config Config
+	clock  *simclock.Clock
+	rng    *rand.Rand
+
+	sensor    game.VirtualCameraConfig
+	optics    game.VirtualTelescopeConfig
+	camera    *game.Equipment
+	telescope *game.Equipment
+	model     *calibration.Model   // fixed-pattern defects of the camera on the telescope
+	library   *calibration.Library // masters from the calibration frames taken
+
+	conditions Conditions
+	site       Site
+	connected  bool
+	gain       int
+	offset     int
+	readout    game.Readout
+	defocus    float64
+	cooler     cooler
+	coolerDone chan struct{} // stops the cooler status stream
+
+	state    string
+	exposure *exposure // active exposure, nil when idle
+	cancel   context.CancelFunc
+	last     *Frame
+
+	onEvent func(eventType string, data any)
+}
+
+// exposure is an exposure in progress.
+type exposure struct {
+	imageType string
+	duration  float64
+	gain      int
+	offset    int
+	readout   game.Readout
+	start     time.Time  // simulated time
+	cond      Conditions // sky at the start of the exposure
+	site      Site
+	object    string
+	filter    string
+	artifacts bool
+
+	sensorTemp float64            // Celsius, averaged while the shutter was open
+	cooler     CoolerStatus       // when the shutter opened
+	mount      *mount.MountStatus // as the mount reported itself when the shutter opened
+	path       path               // where the optics pointed while the shutter was open
+	sampled    time.Time          // simulated time of the last path sample
+}
+
+// NewSimulator creates a camera simulator.
+func NewSimulator(config Config) *Simulator {
+	if config.Clock == nil {
+		config.Clock = simclock.New()
+	}
+	s := &Simulator{
+		config:     config,
+		clock:      config.Clock,
+		rng:        rand.New(rand.NewSource(time.Now().UnixNano())),
+		conditions: DefaultConditions(),
+		state:      StateIdle,
+		library:    calibration.NewLibrary(),
+	}
+	s.cooler = cooler{
+		setpoint:    defaultSetpoint,
+		rampRate:    defaultRampRate,
+		temperature: s.conditions.Temperature,
+		updated:     s.clock.Now(),
+	}
+	s.applyLoadout(config.Loadout)
+	return s
+}
+
+// SetLoadout switches to the loadout's camera and telescope, e.g. when the
+// player equips new gear. Gain and offset are clamped to the new camera,
+// and the cooler is switched off if the new camera has none.
+func (s *Simulator) SetLoadout(loadout game.EquipmentLoadout) {
+	s.mu.Lock()
+	s.advanceCooler(s.clock.Now())
+	s.applyLoadout(loadout)
+	if !s.sensor.HasCooling {
+		s.cooler.on = false
+	}
+	s.mu.Unlock()
+}
+
+// applyLoadout resolves the loadout's equipment. Must be called with the
+// write lock held (or before the simulator is shared).
+func (s *Simulator) applyLoadout(loadout game.EquipmentLoadout) {
+	virtual := game.LoadoutToVirtualConfig(loadout)
+	if loadout.Camera != s.config.Loadout.Camera || loadout.Telescope != s.config.Loadout.Telescope {
+		// Masters record the defects of the old equipment
+		s.library.Reset()
+	}
+	s.config.Loadout = loadout
+	s.sensor = virtual.Camera
+	s.optics = virtual.Telescope
+	s.camera = game.GetEquipment(loadout.Camera)
+	s.telescope = game.GetEquipment(loadout.Telescope)
+	s.model = newModel(loadout, s.sensor, s.optics)
+
+	s.gain = clampInt(s.gain, int(s.sensor.GainRange[0]), int(s.sensor.GainRange[1]))
+	s.offset = clampInt(s.offset, s.sensor.OffsetRange[0], s.sensor.OffsetRange[1])
+	if s.offset == s.sensor.OffsetRange[0] && s.sensor.OffsetRange[1] > 0 {
+		// Keep the bias clear of zero so read noise isn't clipped
+		s.offset = min(10, s.sensor.OffsetRange[1])
+	}
+	if s.checkReadout(s.readout) != nil {
+		s.readout = game.Readout{}
+	}
+}
+
+// SetConditions updates the sky conditions used for new exposures. The
+// ambient temperature also drives the sensor's thermal model.
+func (s *Simulator) SetConditions(cond Conditions) {
+	s.mu.Lock()
+	s.advanceCooler(s.clock.Now())
+	s.conditions = cond
+	s.mu.Unlock()
+}
+
+// SetSite sets the observatory location recorded in frame headers.
+func (s *Simulator) SetSite(site Site) {
+	s.mu.Lock()
+	s.site = site
+	s.mu.Unlock()
+}
+
+// SetReadout sets the binning, subframe and readout mode of the following
+// exposures.
+func (s *Simulator) SetReadout(readout game.Readout) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.exposure != nil {
+		return errExposing
+	}
+	if err := s.checkReadout(readout); err != nil {
+		return err
+	}
+	readout.Bin = readout.Binning()
+	if readout.ROI != nil {
+		roi := *readout.ROI
+		readout.ROI = &roi
+	}
+	s.readout = readout
+	return nil
+}
+
+// checkReadout validates a readout against the camera. Must be called with
+// at least a read lock.
+func (s *Simulator) checkReadout(readout game.Readout) error {
+	bin := readout.Binning()
+	if readout.Bin < 0 || bin > max(1, s.sensor.MaxBin) {
+		return errInvalidBinning
+	}
+	if _, ok := s.sensor.ReadoutMode(readout.Mode); !ok {
+		return errInvalidMode
+	}
+	if roi := readout.ROI; roi != nil {
+		width, height := s.sensor.BinnedSize(bin)
+		if roi.X < 0 || roi.Y < 0 || roi.Width <= 0 || roi.Height <= 0 ||
+			roi.X+roi.Width > width || roi.Y+roi.Height > height {
+			return errInvalidROI
+		}
+	}
+	return nil
+}
+
+// SetDefocus sets how far out of focus the camera is, as the HFR in pixels
+// the focus error adds to the stars. Zero is perfect focus.
+func (s *Simulator) SetDefocus(hfr float64) error {
+	if hfr < 0 || math.IsNaN(hfr) {
+		return errInvalidDefocus
+	}
+	s.mu.Lock()
+	s.defocus = hfr
+	s.mu.Unlock()
+	return nil
+}
+
+// SetEventHandler sets the callback for camera events.
+func (s *Simulator) SetEventHandler(fn func(eventType string, data any)) {
+	s.mu.Lock()
+	s.onEvent = fn
+	s.mu.Unlock()
+}
+
+// Connect connects the camera and starts streaming the cooler status.
+func (s *Simulator) Connect(_ context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.connected {
+		return nil
+	}
+	s.connected = true
+	s.advanceCooler(s.clock.Now())
+	s.coolerDone = make(chan struct{})
+	go s.runCooler(s.coolerDone)
+	return nil
+}
+
+// Disconnect disconnects the camera, aborting any exposure. The cooler
+// switches off and the sensor warms back to ambient.
+func (s *Simulator) Disconnect() error {
+	s.AbortExposure()
+	s.mu.Lock()
+	s.connected = false
+	s.advanceCooler(s.clock.Now())
+	s.cooler.on = false
+	if s.coolerDone != nil {
+		close(s.coolerDone)
+		s.coolerDone = nil
+	}
+	s.mu.Unlock()
+	return nil
+}
+
+// GetStatus returns the current camera status.
+func (s *Simulator) GetStatus() Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.advanceCooler(s.clock.Now())
+
+	fovW, fovH := game.CalculateReadoutFieldOfView(s.telescope, s.camera, s.readout)
+	frame := s.sensor.WithReadout(s.readout)
+	status := Status{
+		Connected:         s.connected,
+		State:             s.state,
+		ImageReady:        s.last != nil,
+		SensorWidth:       s.sensor.SensorWidth,
+		SensorHeight:      s.sensor.SensorHeight,
+		PixelSize:         s.sensor.PixelSize,
+		BitDepth:          s.sensor.BitDepth,
+		BayerPattern:      s.sensor.BayerPattern,
+		Readout:           s.readout,
+		MaxBin:            max(1, s.sensor.MaxBin),
+		ReadoutModes:      s.sensor.ReadoutModes,
+		FrameWidth:        frame.SensorWidth,
+		FrameHeight:       frame.SensorHeight,
+		PixelScale:        s.pixelScale(),
+		FOVWidth:          fovW,
+		FOVHeight:         fovH,
+		Gain:              s.gain,
+		Offset:            s.offset,
+		GainRange:         [2]int{int(s.sensor.GainRange[0]), int(s.sensor.GainRange[1])},
+		Defocus:           s.defocus,
+		SensorTemperature: round(s.cooler.temperature, 2),
+		HasCooler:         s.sensor.HasCooling,
+		Cooler:            s.coolerStatus(),
+	}
+	if s.camera != nil {
+		status.Camera = s.camera.Name
+	}
+	if s.telescope != nil {
+		status.Telescope = s.telescope.Name
+	}
+	if exp := s.exposure; exp != nil {
+		status.ImageType = exp.imageType
+		status.ExposureDuration = exp.duration
+		if exp.duration > 0 {
+			elapsed := s.clock.Now().Sub(exp.start).Seconds()
+			status.ExposureProgress = math.Max(0, math.Min(1, elapsed/exp.duration))
+		} else {
+			status.ExposureProgress = 1
+		}
+	}
+	return status
+}
+
+// StartExposure opens the shutter. The exposure runs on simulated time; when
+// it completes the frame is rendered and EventExposureComplete published.
+func (s *Simulator) StartExposure(req ExposureRequest) error {
+	imageType := req.ImageType
+	if imageType == "" {
+		imageType = ImageLight
+	}
+	duration := req.Duration
+	if req.Filter == "" {
+		req.Filter = defaultFilter
+	}
+	switch imageType {
+	case ImageLight, ImageDark, ImageFlat:
+		if duration <= 0 || duration > maxExposure {
+			return errInvalidDuration
+		}
+	case ImageBias:
+		duration = 0
+	default:
+		return errInvalidImageType
+	}
+
+	s.mu.Lock()
+	if !s.connected {
+		s.mu.Unlock()
+		return errNotConnected
+	}
+	if s.exposure != nil {
+		s.mu.Unlock()
+		return errExposing
+	}
+	if req.Gain != nil {
+		if *req.Gain < int(s.sensor.GainRange[0]) || *req.Gain > int(s.sensor.GainRange[1]) {
+			s.mu.Unlock()
+			return errInvalidGain
+		}
+		s.gain = *req.Gain
+	}
+	if req.Offset != nil {
+		if *req.Offset < s.sensor.OffsetRange[0] || *req.Offset > s.sensor.OffsetRange[1] {
+			s.mu.Unlock()
+			return errInvalidOffset
+		}
+		s.offset = *req.Offset
+	}
+
+	now := s.clock.Now()
+	s.advanceCooler(now)
+	exp := &exposure{
+		imageType:  imageType,
+		duration:   duration,
+		gain:       s.gain,
+		offset:     s.offset,
+		readout:    s.readout,
+		start:      now,
+		cond:       s.conditions,
+		site:       s.site,
+		object:     req.Object,
+		filter:     req.Filter,
+		artifacts:  req.Artifacts,
+		sensorTemp: s.cooler.temperature,
+		cooler:     s.coolerStatus(),
+		sampled:    now,
+	}
+	s.sample(exp, now)
+	s.exposure = exp
+	s.state = StateExposing
+
+	// Use background context so the exposure outlives the HTTP request
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.mu.Unlock()
+
+	s.emit(EventExposureStarted, map[string]any{
+		"duration":   duration,
+		"image_type": imageType,
+		"gain":       exp.gain,
+	})
+
+	go s.runExposure(ctx, exp)
+	return nil
+}
+
+// AbortExposure discards the exposure in progress.
+func (s *Simulator) AbortExposure() error {
+	s.mu.Lock()
+	if s.exposure == nil {
+		s.mu.Unlock()
+		return errNotExposing
+	}
+	exp := s.exposure
+	s.cancel()
+	s.cancel = nil
+	s.exposure = nil
+	s.state = StateIdle
+	s.mu.Unlock()
+
+	s.emit(EventExposureAborted, map[string]any{
+		"duration":   exp.duration,
+		"image_type": exp.imageType,
+	})
+	return nil
+}
+
+// LastFrame returns the most recently completed frame.
+func (s *Simulator) LastFrame() (*Frame, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.last == nil {
+		return nil, errNoImage
+	}
+	return s.last, nil
+}
+
+// runExposure waits out the exposure in simulated time, then reads out the
+// sensor.
+func (s *Simulator) runExposure(ctx context.Context, exp *exposure) {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	for exp.duration > 0 {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		s.mu.Lock()
+		if s.exposure != exp {
+			s.mu.Unlock()
+			return
+		}
+		done := s.sample(exp, s.clock.Now())
+		s.mu.Unlock()
+		if done {
+			break
+		}
+	}
+
+	s.mu.Lock()
+	if s.exposure != exp {
+		s.mu.Unlock()
+		return
+	}
+	s.state = StateReading
+	r := s.renderer(exp)
+	s.mu.Unlock()
+
+	r.loadStars(ctx, s.config.Stars)
+	frame := r.render()
+
+	s.mu.Lock()
+	if s.exposure != exp {
+		// Aborted during readout
+		s.mu.Unlock()
+		return
+	}
+	s.last = frame
+	s.exposure = nil
+	s.cancel()
+	s.cancel = nil
+	s.state = StateIdle
+	s.mu.Unlock()
+
+	matching := s.addCalibration(frame)
+	s.emit(EventExposureComplete, map[string]any{
+		"duration":    exp.duration,
+		"image_type":  exp.imageType,
+		"gain":        exp.gain,
+		"bin":         frame.Info.BinX,
+		"pixel_scale": frame.Info.PixelScale,
+		"width":       frame.Width,
+		"height":      frame.Height,
+		"ra":          frame.Info.RA,
+		"dec":         frame.Info.Dec,
+		"stars":       frame.Info.Stars,
+		"hfr":         frame.Info.HFR,
+		"calibration": matching,
+	})
+}
+
+// sample records where the optics point at simulated time now, and reports
+// whether the exposure is over. Must be called with the write lock held.
+func (s *Simulator) sample(exp *exposure, now time.Time) bool {
+	end := exp.start.Add(time.Duration(exp.duration * float64(time.Second)))
+	if now.After(end) {
+		now = end
+	}
+	dt := now.Sub(exp.sampled).Seconds()
+	exp.sampled = now
+
+	// Dark current follows the sensor temperature over the whole exposure
+	s.advanceCooler(now)
+	if elapsed := now.Sub(exp.start).Seconds(); dt > 0 && elapsed > 0 {
+		exp.sensorTemp += (s.cooler.temperature - exp.sensorTemp) * dt / elapsed
+	}
+
+	if s.config.Mount == nil {
+		return !now.Before(end)
+	}
+	status := s.config.Mount.GetStatus()
+	if exp.mount == nil {
+		exp.mount = &status
+	}
+	p := pointing{
+		ra:  status.ActualRA * 15,
+		dec: status.ActualDec,
+		alt: status.Alt,
+	}
+	p.ra = normalizeRA(p.ra)
+	switch {
+	case status.MountType == "altaz":
+		// The sensor stays fixed to the horizon while the field turns
+		p.rotation = status.FieldRotation
+	case status.PierSide == "west":
+		// Flipping the meridian turns the camera upside down
+		p.rotation = 180
+	}
+
+	if len(exp.path.samples) == 0 || dt > 0 {
+		exp.path.add(p, dt)
+	}
+	return !now.Before(end)
+}
+
+func (s *Simulator) emit(eventType string, data any) {
+	s.mu.RLock()
+	fn := s.onEvent
+	s.mu.RUnlock()
+	if fn != nil {
+		fn(eventType, data)
+	}
+}
+
+// pixelScale returns the image scale in arcsec/pixel at the current
+// binning. Must be called with at least a read lock.
+func (s *Simulator) pixelScale() float64 {
+	return game.CalculateBinnedImageScale(s.telescope, s.camera, s.readout.Binning())
+}
+
+func clampInt(v, lo, hi int) int {
+	if hi < lo {
+		return lo
+	}
+	return max(lo, min(hi, v))
+}
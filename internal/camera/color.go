@@ -0,0 +1,72 @@
+package camera
+
+import (
+	"math"
+
+	"github.com/darkdragonsastro/draco-simulator/internal/debayer"
+)
+
+// Color temperatures of the light sources a frame records, in Kelvin.
+const (
+	// skyTemperature is the color of a light-polluted night sky, reddened
+	// by sodium and LED streetlight and airglow.
+	skyTemperature = 4000.0
+
+	// flatTemperature is the color of a white flat panel.
+	flatTemperature = 6500.0
+)
+
+// channelQE is the quantum efficiency of each color channel for one light
+// source, indexed by debayer.Channel. A mono sensor's are all its QE.
+type channelQE [3]float64
+
+// mean returns the quantum efficiency averaged over a Bayer cell, one red,
+// two green and one blue pixel.
+func (q channelQE) mean() float64 {
+	return (q[debayer.Red] + 2*q[debayer.Green] + q[debayer.Blue]) / 4
+}
+
+// channelQE returns the sensor's response to a blackbody of the given
+// temperature, in Kelvin.
+func (r *renderer) channelQE(temperature float64) channelQE {
+	var q channelQE
+	for c := range q {
+		q[c] = r.sensor.ChannelQE(c, temperature)
+	}
+	return q
+}
+
+// qeAt returns the quantum efficiency of pixel (x, y) for light q: that of
+// the filter over it, or, for mono and binned color frames, whose pixels
+// each sum a whole Bayer cell, the cell's mean.
+func (r *renderer) qeAt(q channelQE, x, y int) float64 {
+	if r.pattern == nil {
+		return q.mean()
+	}
+	return q[r.pattern.At(x, y)]
+}
+
+// starQE returns the sensor's response to a star of color index bv,
+// caching it by color for the frame.
+func (r *renderer) starQE(bv float64) channelQE {
+	if !r.sensor.IsColor() {
+		return r.channelQE(0)
+	}
+	key := int(math.Round(bv * 20))
+	if q, ok := r.starQECache[key]; ok {
+		return q
+	}
+	if r.starQECache == nil {
+		r.starQECache = make(map[int]channelQE)
+	}
+	q := r.channelQE(starTemperature(float64(key) / 20))
+	r.starQECache[key] = q
+	return q
+}
+
+// starTemperature returns the effective temperature, in Kelvin, of a star
+// with B−V color index bv (Ballesteros 2012).
+func starTemperature(bv float64) float64 {
+	bv = math.Max(-0.4, math.Min(2.0, bv))
+	return 4600 * (1/(0.92*bv+1.7) + 1/(0.92*bv+0.62))
+}
@@ -0,0 +1,185 @@
+package camera
+
+import (
+	"math"
+	"time"
+)
+
+// EventCoolerStatus streams the sensor temperature and cooler power once a
+// second while the camera is connected.
+const EventCoolerStatus = "camera.cooler"
+
+const (
+	// coolerInterval is how often the cooler status is streamed.
+	coolerInterval = time.Second
+
+	// defaultSetpoint is the cooler's target until one is set, in Celsius.
+	defaultSetpoint = -10.0
+
+	// defaultRampRate is how fast the cooler moves the sensor toward the
+	// setpoint unless told otherwise, in degrees Celsius per minute. Cooling
+	// gradually spares the sensor thermal shock and frosting.
+	defaultRampRate = 3.0
+
+	// maxRampRate is the fastest a thermoelectric cooler can pull the sensor
+	// down, in degrees Celsius per minute.
+	maxRampRate = 10.0
+
+	// thermalTimeConstant is how many seconds an unpowered sensor takes to
+	// close 63% of the gap to ambient.
+	thermalTimeConstant = 120.0
+
+	// Setpoints outside this range are rejected, in Celsius.
+	minSetpoint = -50.0
+	maxSetpoint = 30.0
+)
+
+// cooler is the thermal state of the sensor and its thermoelectric cooler.
+type cooler struct {
+	on          bool
+	setpoint    float64   // Celsius
+	rampRate    float64   // Celsius per minute
+	temperature float64   // sensor temperature, Celsius
+	power       float64   // percent of full cooling power
+	updated     time.Time // simulated time the state was last advanced
+}
+
+// CoolerRequest switches the cooler on or off. A nil Setpoint or RampRate
+// keeps the current value.
+type CoolerRequest struct {
+	On       bool     `json:"on"`
+	Setpoint *float64 `json:"setpoint,omitempty"`  // Celsius
+	RampRate *float64 `json:"ramp_rate,omitempty"` // Celsius per minute
+}
+
+// CoolerStatus is a snapshot of the cooler, as streamed with
+// EventCoolerStatus.
+type CoolerStatus struct {
+	On          bool    `json:"on"`
+	Setpoint    float64 `json:"setpoint"`    // Celsius
+	RampRate    float64 `json:"ramp_rate"`   // Celsius per minute
+	Temperature float64 `json:"temperature"` // sensor, Celsius
+	Ambient     float64 `json:"ambient"`     // Celsius
+	Power       float64 `json:"power"`       // percent
+	Watts       float64 `json:"watts"`
+	AtSetpoint  bool    `json:"at_setpoint"`
+}
+
+// SetCooler switches the cooler on or off and sets its target temperature
+// and ramp rate.
+func (s *Simulator) SetCooler(req CoolerRequest) error {
+	if req.Setpoint != nil && (*req.Setpoint < minSetpoint || *req.Setpoint > maxSetpoint || math.IsNaN(*req.Setpoint)) {
+		return errInvalidSetpoint
+	}
+	if req.RampRate != nil && (*req.RampRate <= 0 || *req.RampRate > maxRampRate || math.IsNaN(*req.RampRate)) {
+		return errInvalidRampRate
+	}
+
+	s.mu.Lock()
+	if !s.connected {
+		s.mu.Unlock()
+		return errNotConnected
+	}
+	if !s.sensor.HasCooling {
+		s.mu.Unlock()
+		return errNoCooler
+	}
+	s.advanceCooler(s.clock.Now())
+	s.cooler.on = req.On
+	if req.Setpoint != nil {
+		s.cooler.setpoint = *req.Setpoint
+	}
+	if req.RampRate != nil {
+		s.cooler.rampRate = *req.RampRate
+	}
+	status := s.coolerStatus()
+	s.mu.Unlock()
+
+	s.emit(EventCoolerStatus, status)
+	return nil
+}
+
+// CoolerStatus returns the current cooler status.
+func (s *Simulator) CoolerStatus() CoolerStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.advanceCooler(s.clock.Now())
+	return s.coolerStatus()
+}
+
+// coolerStatus snapshots the cooler. Must be called with at least a read
+// lock.
+func (s *Simulator) coolerStatus() CoolerStatus {
+	c := s.cooler
+	return CoolerStatus{
+		On:          c.on,
+		Setpoint:    c.setpoint,
+		RampRate:    c.rampRate,
+		Temperature: round(c.temperature, 2),
+		Ambient:     s.conditions.Temperature,
+		Power:       round(c.power, 1),
+		Watts:       round(c.power/100*s.sensor.CoolingPower, 1),
+		AtSetpoint:  c.on && math.Abs(c.temperature-c.setpoint) < 0.5,
+	}
+}
+
+// advanceCooler brings the sensor temperature up to simulated time now.
+// With the cooler on, the sensor ramps toward the setpoint at the ramp
+// rate but can get no further below ambient than the cooler's CoolingDelta,
+// so on a hot night it stalls above the setpoint at full power. With the
+// cooler off it drifts back to ambient. Must be called with the write lock
+// held.
+func (s *Simulator) advanceCooler(now time.Time) {
+	c := &s.cooler
+	dt := now.Sub(c.updated).Seconds()
+	if dt <= 0 {
+		return
+	}
+	c.updated = now
+	ambient := s.conditions.Temperature
+
+	if !c.on || !s.sensor.HasCooling {
+		c.temperature = ambient + (c.temperature-ambient)*math.Exp(-dt/thermalTimeConstant)
+		c.power = 0
+		return
+	}
+
+	floor := ambient - s.sensor.CoolingDelta
+	target := math.Max(c.setpoint, floor)
+	step := c.rampRate / 60 * dt
+	switch {
+	case c.temperature > target:
+		c.temperature = math.Max(target, c.temperature-step)
+	case c.temperature < floor:
+		// Ambient warmed past what the cooler can hold
+		c.temperature = ambient + (c.temperature-ambient)*math.Exp(-dt/thermalTimeConstant)
+		c.temperature = math.Min(c.temperature, target)
+	default:
+		c.temperature = math.Min(target, c.temperature+step)
+	}
+
+	// Holding a sensor below ambient takes power in proportion to the
+	// difference; CoolingDelta below ambient is all the cooler has
+	c.power = 0
+	if s.sensor.CoolingDelta > 0 {
+		c.power = math.Max(0, math.Min(100, (ambient-c.temperature)/s.sensor.CoolingDelta*100))
+	}
+}
+
+// runCooler streams the cooler status until done is closed.
+func (s *Simulator) runCooler(done <-chan struct{}) {
+	ticker := time.NewTicker(coolerInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+		s.mu.Lock()
+		s.advanceCooler(s.clock.Now())
+		status := s.coolerStatus()
+		s.mu.Unlock()
+		s.emit(EventCoolerStatus, status)
+	}
+}
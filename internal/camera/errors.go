@@ -0,0 +1,35 @@
+package camera
+
+import "errors"
+
+var (
+	errNotConnected = errors.New("camera not connected")
+	errExposing     = errors.New("exposure already in progress")
+	errNotExposing  = errors.New("no exposure in progress")
+	errNoImage      = errors.New("no image available")
+	errNoCooler     = errors.New("camera has no cooler")
+	errNotLight     = errors.New("only light frames can be calibrated")
+	errNoMasters    = errors.New("no calibration masters match the frame")
+
+	errInvalidImageType = errors.New("image type must be light, dark, bias or flat")
+	errInvalidDuration  = errors.New("exposure duration out of range")
+	errInvalidGain      = errors.New("gain out of range")
+	errInvalidOffset    = errors.New("offset out of range")
+	errInvalidDefocus   = errors.New("defocus must not be negative")
+	errInvalidSetpoint  = errors.New("cooler setpoint out of range")
+	errInvalidRampRate  = errors.New("cooler ramp rate out of range")
+	errInvalidBinning   = errors.New("binning not supported by the camera")
+	errInvalidROI       = errors.New("subframe must lie within the binned sensor")
+	errInvalidMode      = errors.New("unknown readout mode")
+)
+
+// IsInvalidRequest reports whether err rejects a command's parameters, as
+// opposed to a command the camera can't carry out in its current state.
+func IsInvalidRequest(err error) bool {
+	switch err {
+	case errInvalidImageType, errInvalidDuration, errInvalidGain, errInvalidOffset, errInvalidDefocus,
+		errInvalidSetpoint, errInvalidRampRate, errInvalidBinning, errInvalidROI, errInvalidMode:
+		return true
+	}
+	return false
+}
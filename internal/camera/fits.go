@@ -0,0 +1,151 @@
+package camera
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/darkdragonsastro/draco-simulator/internal/fits"
+)
+
+// softwareName identifies the simulator in SWCREATE, as capture software
+// identifies itself.
+const softwareName = "Draco Simulator"
+
+// FITS returns the frame as a FITS image with the headers capture software
+// writes. bitpix selects 16-bit integer pixels in ADU or 32-bit float pixels
+// normalized to 0-1, the convention PixInsight and Siril use for floats.
+// Rows are stored top row first, as flagged by ROWORDER.
+func (f *Frame) FITS(bitpix int) *fits.Image {
+	img := &fits.Image{
+		Width:  f.Width,
+		Height: f.Height,
+		Bitpix: bitpix,
+		Pixels: make([]float32, len(f.Pixels)),
+	}
+	scale := float32(1)
+	if bitpix == fits.Float32 {
+		scale = 1.0 / math.MaxUint16
+	}
+	for i, v := range f.Pixels {
+		img.Pixels[i] = float32(v) * scale
+	}
+	img.Header = f.FITSHeader()
+	return img
+}
+
+// FITSHeader returns the headers capture software writes for the frame.
+func (f *Frame) FITSHeader() fits.Header {
+	info := f.Info
+	var header fits.Header
+	h := &header
+	h.Set("IMAGETYP", strings.ToUpper(info.ImageType), "type of exposure")
+	h.Set("EXPOSURE", info.Duration, "[s] exposure duration")
+	h.Set("EXPTIME", info.Duration, "[s] exposure duration")
+	h.Set("DATE-OBS", info.StartTime.UTC().Format("2006-01-02T15:04:05.000"), "UTC start of exposure")
+	h.Set("XBINNING", info.BinX, "X axis binning factor")
+	h.Set("YBINNING", info.BinY, "Y axis binning factor")
+	h.Set("XORGSUBF", info.SubframeX, "subframe X origin, binned pixels")
+	h.Set("YORGSUBF", info.SubframeY, "subframe Y origin, binned pixels")
+	if info.ReadoutMode != "" {
+		h.Set("READOUTM", info.ReadoutMode, "sensor readout mode")
+	}
+	if info.BayerPattern != "" {
+		h.Set("BAYERPAT", info.BayerPattern, "Bayer color pattern")
+		h.Set("XBAYROFF", 0, "X offset of the Bayer pattern")
+		h.Set("YBAYROFF", 0, "Y offset of the Bayer pattern")
+	}
+	h.Set("XPIXSZ", info.PixelSize*float64(info.BinX), "[um] pixel X size, binned")
+	h.Set("YPIXSZ", info.PixelSize*float64(info.BinY), "[um] pixel Y size, binned")
+	h.Set("GAIN", info.Gain, "sensor gain")
+	h.Set("OFFSET", info.Offset, "sensor offset")
+	h.Set("EGAIN", info.ElectronsPerADU/float64(int(1)<<max(0, 16-info.BitDepth)), "[e-/ADU] electrons per 16-bit ADU")
+	h.Set("CCD-TEMP", round(info.SensorTemp, 2), "[C] sensor temperature")
+	if info.CoolerOn {
+		h.Set("SET-TEMP", info.SetTemp, "[C] cooler setpoint")
+		h.Set("COOLPOWR", info.CoolerPower, "[%] cooler power")
+	}
+	if len(info.Calibration) > 0 {
+		var applied strings.Builder
+		for _, t := range info.Calibration {
+			applied.WriteString(strings.ToUpper(t[:1]))
+		}
+		h.Set("CALSTAT", applied.String(), "calibration applied: Bias, Dark, Flat")
+		h.Set("PEDESTAL", info.Pedestal, "[ADU] added to calibrated pixels")
+	}
+	h.Set("INSTRUME", info.Camera, "camera")
+	h.Set("TELESCOP", info.Telescope, "telescope")
+	h.Set("FOCALLEN", info.FocalLength, "[mm] focal length")
+	h.Set("APTDIA", info.Aperture, "[mm] aperture diameter")
+	h.Set("FILTER", info.Filter, "filter")
+	h.Set("SWCREATE", softwareName, "capture software")
+	h.Set("ROWORDER", "TOP-DOWN", "order of the rows in the image array")
+
+	if info.ImageType == ImageLight {
+		if info.Object != "" {
+			h.Set("OBJECT", info.Object, "name of the target")
+		}
+		h.Set("OBJCTRA", sexagesimal(info.MountRA/15, false), "[H M S] RA of the mount")
+		h.Set("OBJCTDEC", sexagesimal(info.MountDec, true), "[D M S] Dec of the mount")
+		h.Set("RA", round(info.MountRA, 6), "[deg] RA of the mount")
+		h.Set("DEC", round(info.MountDec, 6), "[deg] Dec of the mount")
+		h.Set("EQUINOX", 2000.0, "equinox of the coordinates")
+		h.Set("RADESYS", "FK5", "reference frame of the coordinates")
+		h.Set("CENTALT", round(info.Altitude, 4), "[deg] altitude of the mount")
+		h.Set("CENTAZ", round(info.Azimuth, 4), "[deg] azimuth of the mount")
+		if info.Altitude > 0 {
+			h.Set("AIRMASS", round(airmass(info.Altitude), 4), "airmass at the start of the exposure")
+		}
+		if info.PierSide != "" {
+			h.Set("PIERSIDE", strings.ToUpper(info.PierSide), "side of the pier the telescope is on")
+		}
+	}
+
+	h.Set("SITELAT", round(info.Site.Latitude, 6), "[deg] observatory latitude")
+	h.Set("SITELONG", round(info.Site.Longitude, 6), "[deg] observatory longitude, east positive")
+	h.Set("SITEELEV", info.Site.Elevation, "[m] observatory elevation")
+
+	if info.ImageType == ImageLight && info.PixelScale > 0 {
+		// The WCS follows the mount's pointing, so it is off by the
+		// pointing error until the frame is plate solved, and the mount
+		// points the centre of the whole sensor, off the centre of a
+		// subframe
+		width, height := f.Width, f.Height
+		if info.SensorWidth > 0 && info.SensorHeight > 0 {
+			width, height = info.SensorWidth, info.SensorHeight
+		}
+		wcs := fits.NewWCS(info.MountRA, info.MountDec, width, height, info.PixelScale, info.Rotation)
+		wcs.CRPIX1 -= float64(info.SubframeX)
+		wcs.CRPIX2 -= float64(info.SubframeY)
+		wcs.Apply(h)
+	}
+	return header
+}
+
+// sexagesimal formats v as "HH MM SS.ss" or, for declinations, "+DD MM SS.s".
+func sexagesimal(v float64, signed bool) string {
+	sign := "+"
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+	precision := 100.0
+	if signed {
+		precision = 10
+	}
+	total := math.Round(v*3600*precision) / precision
+	d := math.Floor(total / 3600)
+	m := math.Floor((total - d*3600) / 60)
+	sec := total - d*3600 - m*60
+	if !signed {
+		return fmt.Sprintf("%02.0f %02.0f %05.2f", math.Mod(d, 24), m, sec)
+	}
+	return fmt.Sprintf("%s%02.0f %02.0f %04.1f", sign, d, m, sec)
+}
+
+// round rounds v to the given number of decimal places, keeping headers
+// free of float noise.
+func round(v float64, places int) float64 {
+	p := math.Pow(10, float64(places))
+	return math.Round(v*p) / p
+}
@@ -0,0 +1,161 @@
+package camera
+
+import (
+	"image"
+	"math"
+	"time"
+
+	"github.com/darkdragonsastro/draco-simulator/internal/artifacts"
+	"github.com/darkdragonsastro/draco-simulator/internal/debayer"
+	"github.com/darkdragonsastro/draco-simulator/internal/stretch"
+)
+
+// Frame is a raw image read out of the sensor.
+type Frame struct {
+	Width  int
+	Height int
+
+	// Pixels holds 16-bit values row by row, starting at the top-left.
+	// Sensors shallower than 16 bits are scaled up to the full range.
+	Pixels []uint16
+
+	Info FrameInfo
+}
+
+// FrameInfo describes how a frame was taken.
+type FrameInfo struct {
+	ImageType string    `json:"image_type"`
+	Object    string    `json:"object,omitempty"`
+	Filter    string    `json:"filter"`
+	Duration  float64   `json:"duration"`   // seconds
+	StartTime time.Time `json:"start_time"` // simulated time the shutter opened
+
+	Gain       int     `json:"gain"`
+	Offset     int     `json:"offset"`
+	SensorTemp float64 `json:"sensor_temp"` // Celsius, averaged over the exposure
+	BinX       int     `json:"bin_x"`
+	BinY       int     `json:"bin_y"`
+
+	// The readout: mode, and where the frame lies on the whole sensor, in
+	// binned pixels
+	ReadoutMode  string `json:"readout_mode"`
+	SubframeX    int    `json:"subframe_x"`
+	SubframeY    int    `json:"subframe_y"`
+	SensorWidth  int    `json:"sensor_width"`
+	SensorHeight int    `json:"sensor_height"`
+
+	// BayerPattern is the color filter mosaic of a raw one-shot color
+	// frame, starting at its top-left pixel; empty for mono frames and
+	// color frames binned to mono
+	BayerPattern string `json:"bayer_pattern,omitempty"`
+
+	// The cooler when the shutter opened; SetTemp is meaningful only with
+	// the cooler on
+	CoolerOn    bool    `json:"cooler_on"`
+	SetTemp     float64 `json:"set_temp"`     // Celsius
+	CoolerPower float64 `json:"cooler_power"` // percent
+
+	RA       float64 `json:"ra"`       // degrees, J2000 centre of the frame
+	Dec      float64 `json:"dec"`      // degrees, J2000 centre of the frame
+	Rotation float64 `json:"rotation"` // degrees, position angle of the frame's up direction, east of north
+	Altitude float64 `json:"altitude"` // degrees
+
+	// Where the mount believed it pointed; differs from RA/Dec by the
+	// pointing error, as a plate solve would reveal
+	MountRA  float64 `json:"mount_ra"`  // degrees, J2000
+	MountDec float64 `json:"mount_dec"` // degrees, J2000
+	Azimuth  float64 `json:"azimuth"`   // degrees
+	PierSide string  `json:"pier_side,omitempty"`
+
+	Site Site `json:"site"`
+
+	PixelScale      float64 `json:"pixel_scale"` // arcsec/pixel
+	PixelSize       float64 `json:"pixel_size"`  // microns
+	BitDepth        int     `json:"bit_depth"`
+	ElectronsPerADU float64 `json:"electrons_per_adu"` // per ADU of the BitDepth-bit output
+	FocalLength     float64 `json:"focal_length"`      // mm
+	Aperture        float64 `json:"aperture"`          // mm
+
+	Camera    string `json:"camera"`
+	Telescope string `json:"telescope"`
+
+	// The masters a calibrated frame was corrected with, by image type, and
+	// the pedestal in ADU its pixels were lifted by; empty for raw frames
+	Calibration []string `json:"calibration,omitempty"`
+	Pedestal    int      `json:"pedestal,omitempty"`
+
+	FWHM          float64 `json:"fwhm"`           // pixels, of the rendered PSF
+	HFR           float64 `json:"hfr"`            // pixels, of the rendered PSF
+	Stars         int     `json:"stars"`          // catalog stars landing on the sensor
+	SkyBackground float64 `json:"sky_background"` // electrons/pixel at the centre
+
+	// Artifacts lists the trails, cosmic rays and gradient the exposure
+	// asked for, with brightness and charge in electrons
+	Artifacts *artifacts.Report `json:"artifacts,omitempty"`
+}
+
+// Preview returns an 8-bit rendering of the frame no wider than maxWidth,
+// with a midtone stretch that brings the background up to a visible grey.
+// Raw color frames are debayered with method, bilinear if empty, and each
+// channel stretched on its own, which also neutralizes the background.
+func (f *Frame) Preview(maxWidth int, method debayer.Method) image.Image {
+	if method == "" {
+		method = debayer.Bilinear
+	}
+	factor := 1
+	if maxWidth > 0 {
+		for f.Width/factor > maxWidth {
+			factor++
+		}
+	}
+	w, h := f.Width/factor, f.Height/factor
+
+	raw := make([]float32, len(f.Pixels))
+	for i, v := range f.Pixels {
+		raw[i] = float32(v) / math.MaxUint16
+	}
+
+	if p, err := debayer.ParsePattern(f.Info.BayerPattern); err == nil {
+		if color, err := debayer.Debayer(raw, f.Width, f.Height, p, method); err == nil {
+			img := image.NewRGBA(image.Rect(0, 0, w, h))
+			for c, plane := range color.Planes {
+				binned := downsample(plane, f.Width, w, h, factor)
+				shadows, midtone := stretch.Auto(binned)
+				for i, v := range binned {
+					img.Pix[4*i+c] = uint8(math.Round(255 * stretch.Apply(v, shadows, midtone)))
+				}
+			}
+			for i := 0; i < w*h; i++ {
+				img.Pix[4*i+3] = 255
+			}
+			return img
+		}
+	}
+
+	binned := downsample(raw, f.Width, w, h, factor)
+	shadows, midtone := stretch.Auto(binned)
+	img := image.NewGray(image.Rect(0, 0, w, h))
+	for i, v := range binned {
+		img.Pix[i] = uint8(math.Round(255 * stretch.Apply(v, shadows, midtone)))
+	}
+	return img
+}
+
+// downsample averages factor×factor blocks of values, stride pixels wide,
+// into a w×h image.
+func downsample(values []float32, stride, w, h, factor int) []float64 {
+	binned := make([]float64, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var sum float64
+			for dy := 0; dy < factor; dy++ {
+				row := values[(y*factor+dy)*stride:]
+				for dx := 0; dx < factor; dx++ {
+					sum += float64(row[x*factor+dx])
+				}
+			}
+			binned[y*w+x] = sum / float64(factor*factor)
+		}
+	}
+	return binned
+}
@@ -0,0 +1,90 @@
+package camera
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	"github.com/darkdragonsastro/draco-simulator/internal/xisf"
+)
+
+// XISF returns the frame as an XISF image in sampleFormat (xisf.UInt16 or
+// xisf.Float32), with the standard Observation and Instrument properties
+// and the frame's FITS headers as FITS keywords.
+func (f *Frame) XISF(sampleFormat string) *xisf.Image {
+	img := &xisf.Image{
+		Width:        f.Width,
+		Height:       f.Height,
+		SampleFormat: sampleFormat,
+		ImageType:    xisfImageType(f.Info.ImageType),
+		Pixels:       make([]float32, len(f.Pixels)),
+	}
+	scale := float32(1)
+	if sampleFormat == xisf.Float32 {
+		scale = 1.0 / math.MaxUint16
+	}
+	for i, v := range f.Pixels {
+		img.Pixels[i] = float32(v) * scale
+	}
+
+	info := f.Info
+	props := []xisf.Property{
+		xisf.TimeProperty("Observation:Time:Start", info.StartTime),
+		xisf.Float32Property("Instrument:ExposureTime", info.Duration),
+		xisf.StringProperty("Instrument:Camera:Name", info.Camera),
+		xisf.Float32Property("Instrument:Camera:Gain", round(info.ElectronsPerADU/float64(int(1)<<max(0, 16-info.BitDepth)), 6)),
+		xisf.IntProperty("Instrument:Camera:XBinning", info.BinX),
+		xisf.IntProperty("Instrument:Camera:YBinning", info.BinY),
+		xisf.Float32Property("Instrument:Sensor:Temperature", round(info.SensorTemp, 2)),
+		xisf.Float32Property("Instrument:Sensor:XPixelSize", info.PixelSize*float64(info.BinX)),
+		xisf.Float32Property("Instrument:Sensor:YPixelSize", info.PixelSize*float64(info.BinY)),
+		xisf.StringProperty("Instrument:Filter:Name", info.Filter),
+		xisf.StringProperty("Instrument:Telescope:Name", info.Telescope),
+		xisf.Float32Property("Instrument:Telescope:Aperture", info.Aperture/1000),
+		xisf.Float32Property("Instrument:Telescope:FocalLength", info.FocalLength/1000),
+		xisf.FloatProperty("Observation:Location:Latitude", info.Site.Latitude),
+		xisf.FloatProperty("Observation:Location:Longitude", info.Site.Longitude),
+		xisf.FloatProperty("Observation:Location:Elevation", info.Site.Elevation),
+	}
+	if info.CoolerOn {
+		props = append(props, xisf.Float32Property("Instrument:Sensor:TargetTemperature", info.SetTemp))
+	}
+	if info.Duration > 0 {
+		end := info.StartTime.Add(time.Duration(info.Duration * float64(time.Second)))
+		props = append(props, xisf.TimeProperty("Observation:Time:End", end))
+	}
+	if info.ImageType == ImageLight {
+		if info.Object != "" {
+			props = append(props, xisf.StringProperty("Observation:Object:Name", info.Object))
+		}
+		props = append(props,
+			xisf.FloatProperty("Observation:Object:RA", round(info.MountRA, 6)),
+			xisf.FloatProperty("Observation:Object:Dec", round(info.MountDec, 6)),
+			xisf.FloatProperty("Observation:Equinox", 2000),
+		)
+	}
+	img.Properties = props
+	if info.BayerPattern != "" {
+		img.ColorFilterArray = &xisf.ColorFilterArray{Pattern: info.BayerPattern, Width: 2, Height: 2}
+	}
+
+	// Carry the FITS headers so FITS-minded tools find what they expect
+	header := f.FITSHeader()
+	for _, c := range header.Cards {
+		value, _ := c.ValueString()
+		img.FITSKeywords = append(img.FITSKeywords, xisf.FITSKeyword{Name: c.Key, Value: value, Comment: c.Comment})
+	}
+
+	img.Metadata = []xisf.Property{
+		xisf.StringProperty("XISF:CreatorApplication", softwareName),
+	}
+	return img
+}
+
+// xisfImageType maps an image type to XISF's imageType attribute.
+func xisfImageType(imageType string) string {
+	if imageType == "" {
+		return ""
+	}
+	return strings.ToUpper(imageType[:1]) + imageType[1:]
+}
@@ -3,6 +3,8 @@ package catalog
 import (
 	"math"
 	"time"
+
+	"github.com/darkdragonsastro/draco-simulator/internal/astrometry"
 )
 
 // SolarSystemBody represents a solar system object
@@ -28,6 +30,10 @@ type SolarSystemPosition struct {
 	RA  float64 `json:"ra"`  // degrees
 	Dec float64 `json:"dec"` // degrees
 
+	// Apparent place of date (JNow), for mounts that expect it
+	ApparentRA  float64 `json:"apparent_ra"`  // degrees
+	ApparentDec float64 `json:"apparent_dec"` // degrees
+
 	// Distance from Earth
 	Distance float64 `json:"distance"` // AU for planets, km for Moon
 
@@ -59,7 +65,7 @@ func NewEphemeris(observer *Observer) *Ephemeris {
 func (e *Ephemeris) GetSunPosition(t time.Time) SolarSystemPosition {
 	ra, dec := approximateSunPosition(t)
 
-	return SolarSystemPosition{
+	return withApparentPlace(SolarSystemPosition{
 		Body:            BodySun,
 		RA:              ra,
 		Dec:             dec,
@@ -68,7 +74,7 @@ func (e *Ephemeris) GetSunPosition(t time.Time) SolarSystemPosition {
 		Illumination:    100,
 		AngularDiameter: 1920, // ~32 arcminutes = 1920 arcseconds
 		Magnitude:       -26.74,
-	}
+	}, t)
 }
 
 // GetMoonPosition calculates the Moon's position at the given time
@@ -142,7 +148,7 @@ func (e *Ephemeris) GetMoonPosition(t time.Time) SolarSystemPosition {
 	// Approximate based on illumination
 	magnitude := -12.7 + 2.5*math.Log10(1.0/(illumination/100.0+0.01))
 
-	return SolarSystemPosition{
+	return withApparentPlace(SolarSystemPosition{
 		Body:            BodyMoon,
 		RA:              ra,
 		Dec:             dec,
@@ -151,7 +157,7 @@ func (e *Ephemeris) GetMoonPosition(t time.Time) SolarSystemPosition {
 		Illumination:    illumination,
 		AngularDiameter: angularDiam,
 		Magnitude:       magnitude,
-	}
+	}, t)
 }
 
 // GetPlanetPosition calculates a planet's approximate position
@@ -301,14 +307,20 @@ func (e *Ephemeris) GetPlanetPosition(body SolarSystemBody, t time.Time) SolarSy
 	}
 	angularDiam := baseSize / dist
 
-	return SolarSystemPosition{
+	return withApparentPlace(SolarSystemPosition{
 		Body:            body,
 		RA:              ra,
 		Dec:             dec,
 		Distance:        dist,
 		AngularDiameter: angularDiam,
 		Magnitude:       mag,
-	}
+	}, t)
+}
+
+// withApparentPlace fills in the apparent place of date for a position.
+func withApparentPlace(pos SolarSystemPosition, t time.Time) SolarSystemPosition {
+	pos.ApparentRA, pos.ApparentDec = astrometry.J2000ToApparent(pos.RA, pos.Dec, t)
+	return pos
 }
 
 // GetAllVisiblePlanets returns all planets currently above the horizon
@@ -0,0 +1,164 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TargetKind identifies what kind of object a name resolved to
+type TargetKind string
+
+const (
+	TargetKindStar   TargetKind = "star"
+	TargetKindDSO    TargetKind = "dso"
+	TargetKindPlanet TargetKind = "planet"
+	TargetKindMoon   TargetKind = "moon"
+	TargetKindSun    TargetKind = "sun"
+)
+
+// Target is an object resolved from a name
+type Target struct {
+	// Name is the name the object was found under, e.g. "Vega" or "M31"
+	Name string `json:"name"`
+
+	// Kind is the kind of object
+	Kind TargetKind `json:"kind"`
+
+	// RA is the right ascension in degrees (J2000)
+	RA float64 `json:"ra"`
+
+	// Dec is the declination in degrees (J2000)
+	Dec float64 `json:"dec"`
+
+	// VMag is the visual magnitude
+	VMag float64 `json:"vmag"`
+
+	// Star, DSO or Body holds the catalog entry that matched
+	Star *Star                `json:"star,omitempty"`
+	DSO  *DeepSkyObject       `json:"dso,omitempty"`
+	Body *SolarSystemPosition `json:"body,omitempty"`
+}
+
+// hipPattern matches Hipparcos designations such as "HIP 91262"
+var hipPattern = regexp.MustCompile(`(?i)^HIP\s*(\d+)$`)
+
+// namedStarCatalog is implemented by star catalogs that know common names
+type namedStarCatalog interface {
+	GetByName(ctx context.Context, name string) (*Star, error)
+}
+
+// Resolver looks up objects by name across the solar system, the star
+// catalog and the deep sky catalog
+type Resolver struct {
+	stars    StarCatalog
+	dsos     []DSOCatalog
+	observer *Observer
+}
+
+// NewResolver creates a resolver over a star catalog, which may be nil, and
+// any number of deep sky catalogs, searched in order (e.g. Messier, NGC, IC).
+// Solar system positions are computed for the observer.
+func NewResolver(stars StarCatalog, observer *Observer, dsos ...DSOCatalog) *Resolver {
+	return &Resolver{stars: stars, dsos: dsos, observer: observer}
+}
+
+// Resolve finds an object by name at time t. Names are tried as a solar
+// system body ("Jupiter", "Moon"), a Hipparcos number ("HIP 91262"), a star
+// name ("Vega"), then a deep sky ID or common name ("M31", "NGC 7000",
+// "Andromeda Galaxy"). Returns ErrObjectNotFound if nothing matches.
+func (r *Resolver) Resolve(ctx context.Context, name string, t time.Time) (*Target, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, ErrInvalidQuery
+	}
+
+	if target := r.resolveBody(name, t); target != nil {
+		return target, nil
+	}
+
+	if r.stars != nil {
+		if m := hipPattern.FindStringSubmatch(name); m != nil {
+			hip, _ := strconv.Atoi(m[1])
+			star, err := r.stars.GetStar(ctx, hip)
+			if err != nil {
+				return nil, err
+			}
+			return starTarget(name, star), nil
+		}
+
+		if named, ok := r.stars.(namedStarCatalog); ok {
+			star, err := named.GetByName(ctx, name)
+			if err == nil {
+				return starTarget(star.Name, star), nil
+			}
+			if !errors.Is(err, ErrObjectNotFound) && !errors.Is(err, ErrCatalogNotLoaded) {
+				return nil, err
+			}
+		}
+	}
+
+	for _, cat := range r.dsos {
+		if cat == nil {
+			continue
+		}
+		obj, err := cat.GetObject(ctx, name)
+		if err == nil {
+			return &Target{
+				Name: obj.ID,
+				Kind: TargetKindDSO,
+				RA:   obj.RA,
+				Dec:  obj.Dec,
+				VMag: obj.VMag,
+				DSO:  obj,
+			}, nil
+		}
+		if !errors.Is(err, ErrObjectNotFound) && !errors.Is(err, ErrCatalogNotLoaded) {
+			return nil, err
+		}
+	}
+
+	return nil, ErrObjectNotFound
+}
+
+// resolveBody returns the Sun, Moon or a planet by name, or nil.
+func (r *Resolver) resolveBody(name string, t time.Time) *Target {
+	body := SolarSystemBody(strings.ToLower(name))
+	eph := NewEphemeris(r.observer)
+
+	var pos SolarSystemPosition
+	var kind TargetKind
+	switch body {
+	case BodySun:
+		pos, kind = eph.GetSunPosition(t), TargetKindSun
+	case BodyMoon:
+		pos, kind = eph.GetMoonPosition(t), TargetKindMoon
+	case BodyMercury, BodyVenus, BodyMars, BodyJupiter, BodySaturn, BodyUranus, BodyNeptune:
+		pos, kind = eph.GetPlanetPosition(body, t), TargetKindPlanet
+	default:
+		return nil
+	}
+
+	return &Target{
+		Name: strings.ToUpper(name[:1]) + strings.ToLower(name[1:]),
+		Kind: kind,
+		RA:   pos.RA,
+		Dec:  pos.Dec,
+		VMag: pos.Magnitude,
+		Body: &pos,
+	}
+}
+
+func starTarget(name string, star *Star) *Target {
+	return &Target{
+		Name: name,
+		Kind: TargetKindStar,
+		RA:   star.RA,
+		Dec:  star.Dec,
+		VMag: star.VMag,
+		Star: star,
+	}
+}
@@ -3,6 +3,8 @@ package catalog
 import (
 	"math"
 	"time"
+
+	"github.com/darkdragonsastro/draco-simulator/internal/astrometry"
 )
 
 // Observer represents an observing location
@@ -19,6 +21,11 @@ type Observer struct {
 	// Timezone is the local timezone
 	Timezone *time.Location `json:"-"`
 
+	// Temperature (Celsius) and Pressure (hPa) drive atmospheric refraction.
+	// A zero pressure means the standard atmosphere at this elevation.
+	Temperature float64 `json:"temperature,omitempty"`
+	Pressure    float64 `json:"pressure,omitempty"`
+
 	// Name is a friendly name for the location
 	Name string `json:"name,omitempty"`
 }
@@ -33,6 +40,14 @@ func NewObserver(lat, lon, elevation float64) *Observer {
 	}
 }
 
+// Atmosphere returns the conditions used for refraction at this location.
+func (o *Observer) Atmosphere() astrometry.Atmosphere {
+	if o.Pressure <= 0 {
+		return astrometry.StandardAtmosphere(o.Elevation)
+	}
+	return astrometry.Atmosphere{Temperature: o.Temperature, Pressure: o.Pressure}
+}
+
 // HorizontalCoordinates represents altitude and azimuth
 type HorizontalCoordinates struct {
 	// Altitude in degrees above horizon (-90 to +90)
@@ -147,74 +162,16 @@ func JulianDate(t time.Time) float64 {
 	return math.Floor(365.25*(y+4716)) + math.Floor(30.6001*(m+1)) + d + b - 1524.5
 }
 
-// LocalSiderealTime calculates the local sidereal time in hours
+// LocalSiderealTime calculates the local apparent sidereal time in hours
 func LocalSiderealTime(t time.Time, longitude float64) float64 {
-	jd := JulianDate(t)
-	jd0 := math.Floor(jd-0.5) + 0.5 // JD at 0h UT
-
-	// Days since J2000.0
-	_ = jd - J2000 // d used in full algorithm
-	d0 := jd0 - J2000
-
-	// Greenwich Mean Sidereal Time at 0h UT (in hours)
-	gmst0 := 6.697374558 + 0.06570982441908*d0
-
-	// Hours since 0h UT
-	h := (jd - jd0) * 24.0
-
-	// GMST at time t
-	gmst := gmst0 + 1.00273790935*h
-
-	// Local Sidereal Time
-	lst := gmst + longitude/15.0
-
-	// Normalize to 0-24 range
-	for lst < 0 {
-		lst += 24
-	}
-	for lst >= 24 {
-		lst -= 24
-	}
-
-	return lst
+	return astrometry.LocalSiderealTime(t, longitude)
 }
 
-// EquatorialToHorizontal converts RA/Dec to Altitude/Azimuth
+// EquatorialToHorizontal converts J2000 RA/Dec (degrees) to the observed
+// Altitude/Azimuth, correcting to the apparent place of date and for
+// atmospheric refraction
 func EquatorialToHorizontal(ra, dec float64, observer *Observer, t time.Time) HorizontalCoordinates {
-	// Local sidereal time in hours
-	lst := LocalSiderealTime(t, observer.Longitude)
-
-	// Hour angle in degrees
-	ha := (lst - ra/15.0) * 15.0
-
-	// Convert to radians
-	haRad := ha * deg2rad
-	decRad := dec * deg2rad
-	latRad := observer.Latitude * deg2rad
-
-	// Calculate altitude
-	sinAlt := math.Sin(decRad)*math.Sin(latRad) +
-		math.Cos(decRad)*math.Cos(latRad)*math.Cos(haRad)
-	altitude := math.Asin(sinAlt) * rad2deg
-
-	// Calculate azimuth
-	cosAz := (math.Sin(decRad) - math.Sin(latRad)*sinAlt) /
-		(math.Cos(latRad) * math.Cos(altitude*deg2rad))
-
-	// Clamp cosAz to valid range
-	if cosAz > 1 {
-		cosAz = 1
-	}
-	if cosAz < -1 {
-		cosAz = -1
-	}
-
-	azimuth := math.Acos(cosAz) * rad2deg
-
-	// Correct azimuth quadrant
-	if math.Sin(haRad) > 0 {
-		azimuth = 360 - azimuth
-	}
+	altitude, azimuth := astrometry.Observed(ra, dec, t, observer.Latitude, observer.Longitude, observer.Atmosphere())
 
 	return HorizontalCoordinates{
 		Altitude: altitude,
@@ -230,9 +187,10 @@ func CalculateVisibility(ra, dec float64, observer *Observer, t time.Time, minAl
 	info.Coords = EquatorialToHorizontal(ra, dec, observer, t)
 	info.IsVisible = info.Coords.Altitude > minAltitude
 
-	// Hour angle
+	// Hour angle of the apparent place
 	lst := LocalSiderealTime(t, observer.Longitude)
-	info.HourAngle = lst - ra/15.0
+	appRA, _ := astrometry.J2000ToApparent(ra, dec, t)
+	info.HourAngle = lst - appRA/15.0
 	if info.HourAngle > 12 {
 		info.HourAngle -= 24
 	}
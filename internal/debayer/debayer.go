@@ -0,0 +1,332 @@
+// Package debayer reconstructs color images from the raw mosaic of a
+// one-shot color sensor, where each pixel sits behind a red, green or blue
+// filter laid out in a repeating 2×2 Bayer cell.
+package debayer
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// Channel is a color channel.
+type Channel int
+
+// Color channels, in the order Image planes hold them.
+const (
+	Red Channel = iota
+	Green
+	Blue
+)
+
+// Pattern is a Bayer mosaic: the filters of the top-left 2×2 cell, read row
+// by row.
+type Pattern struct {
+	cell [2][2]Channel // [y][x]
+}
+
+// Bayer patterns.
+var (
+	RGGB = Pattern{[2][2]Channel{{Red, Green}, {Green, Blue}}}
+	BGGR = Pattern{[2][2]Channel{{Blue, Green}, {Green, Red}}}
+	GRBG = Pattern{[2][2]Channel{{Green, Red}, {Blue, Green}}}
+	GBRG = Pattern{[2][2]Channel{{Green, Blue}, {Red, Green}}}
+)
+
+// ParsePattern returns the pattern named by s, such as "RGGB".
+func ParsePattern(s string) (Pattern, error) {
+	for _, p := range []Pattern{RGGB, BGGR, GRBG, GBRG} {
+		if strings.EqualFold(s, p.String()) {
+			return p, nil
+		}
+	}
+	return Pattern{}, fmt.Errorf("%w: %q", errUnknownPattern, s)
+}
+
+// String returns the pattern's name.
+func (p Pattern) String() string {
+	const letters = "RGB"
+	return string([]byte{
+		letters[p.cell[0][0]], letters[p.cell[0][1]],
+		letters[p.cell[1][0]], letters[p.cell[1][1]],
+	})
+}
+
+// At returns the filter over pixel (x, y).
+func (p Pattern) At(x, y int) Channel {
+	return p.cell[y&1][x&1]
+}
+
+// Shift returns the pattern as seen from a subframe whose origin is at
+// (dx, dy) on the sensor.
+func (p Pattern) Shift(dx, dy int) Pattern {
+	var q Pattern
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 2; x++ {
+			q.cell[y][x] = p.At(x+dx, y+dy)
+		}
+	}
+	return q
+}
+
+// Image is a color image held as one plane per channel, each row by row
+// from the top.
+type Image struct {
+	Width  int
+	Height int
+	Planes [3][]float32 // indexed by Channel
+}
+
+// Method is a demosaicing algorithm.
+type Method string
+
+// Demosaicing methods.
+const (
+	// Bilinear averages each missing color from the nearest pixels that
+	// carry it. It is fast but softens edges and fringes around stars.
+	Bilinear Method = "bilinear"
+
+	// VNG (variable number of gradients) interpolates only along the
+	// directions in which the image changes least, keeping edges sharp.
+	VNG Method = "vng"
+)
+
+// ParseMethod returns the method named by s.
+func ParseMethod(s string) (Method, error) {
+	switch m := Method(strings.ToLower(s)); m {
+	case Bilinear, VNG:
+		return m, nil
+	}
+	return "", fmt.Errorf("%w: %q", errUnknownMethod, s)
+}
+
+// Debayer demosaics raw, a width×height mosaic laid out in pattern p.
+func Debayer(raw []float32, width, height int, p Pattern, method Method) (*Image, error) {
+	if width <= 0 || height <= 0 || len(raw) != width*height {
+		return nil, errBadDimensions
+	}
+	img := &Image{Width: width, Height: height}
+	for c := range img.Planes {
+		img.Planes[c] = make([]float32, width*height)
+	}
+
+	switch method {
+	case Bilinear:
+		parallelRows(height, func(y int) {
+			for x := 0; x < width; x++ {
+				bilinear(raw, width, height, p, x, y, img)
+			}
+		})
+	case VNG:
+		parallelRows(height, func(y int) {
+			for x := 0; x < width; x++ {
+				if x < 2 || y < 2 || x >= width-2 || y >= height-2 {
+					// Too close to the edge for the 5×5 gradients
+					bilinear(raw, width, height, p, x, y, img)
+					continue
+				}
+				vng(raw, width, p, x, y, img)
+			}
+		})
+	default:
+		return nil, fmt.Errorf("%w: %q", errUnknownMethod, method)
+	}
+	return img, nil
+}
+
+// bilinear fills pixel (x, y) of img with the mean of each channel over the
+// pixel's 3×3 neighbourhood; the pixel's own channel keeps its value.
+func bilinear(raw []float32, width, height int, p Pattern, x, y int, img *Image) {
+	var sum [3]float32
+	var n [3]int
+	for dy := -1; dy <= 1; dy++ {
+		yy := y + dy
+		if yy < 0 || yy >= height {
+			continue
+		}
+		for dx := -1; dx <= 1; dx++ {
+			xx := x + dx
+			if xx < 0 || xx >= width {
+				continue
+			}
+			c := p.At(xx, yy)
+			sum[c] += raw[yy*width+xx]
+			n[c]++
+		}
+	}
+	i := y*width + x
+	own := p.At(x, y)
+	for c := range img.Planes {
+		switch {
+		case Channel(c) == own:
+			img.Planes[c][i] = raw[i]
+		case n[c] > 0:
+			img.Planes[c][i] = sum[c] / float32(n[c])
+		}
+	}
+}
+
+// VNG thresholds: directions whose gradient is at most
+// vngK1·min + vngK2·(max − min) are used.
+const (
+	vngK1 = 1.5
+	vngK2 = 0.5
+)
+
+// vngDirections are the eight compass directions VNG weighs, as unit steps.
+var vngDirections = [8][2]int{
+	{0, -1}, {1, -1}, {1, 0}, {1, 1}, {0, 1}, {-1, 1}, {-1, 0}, {-1, -1},
+}
+
+// vngStencil is the pixels VNG reads for one direction, as offsets from
+// the pixel being filled.
+type vngStencil struct {
+	// pairs are the pixel pairs whose differences make up the direction's
+	// gradient, with their weights
+	pairs   [][2][2]int
+	weights []float32
+
+	// near are the pixels lying in the direction; far reach further out
+	// for channels the near ones lack
+	near, far [][2]int
+}
+
+// vngStencils holds the stencil of each of vngDirections.
+var vngStencils = func() [8]vngStencil {
+	var stencils [8]vngStencil
+	for i, d := range vngDirections {
+		dx, dy := d[0], d[1]
+		px, py := -dy, dx // perpendicular
+		st := &stencils[i]
+		st.pairs = [][2][2]int{
+			{{dx, dy}, {-dx, -dy}},
+			{{2 * dx, 2 * dy}, {0, 0}},
+			{{px + dx, py + dy}, {px - dx, py - dy}},
+			{{-px + dx, -py + dy}, {-px - dx, -py - dy}},
+		}
+		st.weights = []float32{1, 1, 0.5, 0.5}
+		if dx != 0 && dy != 0 {
+			// Diagonal pairs are twice as far apart
+			for j := range st.weights {
+				st.weights[j] /= 2
+			}
+			st.near = [][2]int{{0, 0}, {dx, dy}, {2 * dx, 2 * dy}}
+			st.far = [][2]int{{dx, 0}, {0, dy}}
+		} else {
+			st.near = [][2]int{{0, 0}, {dx, dy}, {2 * dx, 2 * dy}, {dx + px, dy + py}, {dx - px, dy - py}}
+			st.far = [][2]int{{px, py}, {-px, -py}, {2*dx + px, 2*dy + py}, {2*dx - px, 2*dy - py}}
+		}
+	}
+	return stencils
+}()
+
+// vng fills pixel (x, y) of img, which must be at least two pixels from the
+// edge. It measures the gradient in each of eight directions over the 5×5
+// neighbourhood, averages each channel over the directions with small
+// gradients, and adds the resulting color differences to the pixel's own
+// value.
+func vng(raw []float32, width int, p Pattern, x, y int, img *Image) {
+	i := y*width + x
+	at := func(o [2]int) float32 {
+		return raw[i+o[1]*width+o[0]]
+	}
+
+	var grad [8]float32
+	lo, hi := float32(0), float32(0)
+	for d := range vngStencils {
+		st := &vngStencils[d]
+		var g float32
+		for j, pair := range st.pairs {
+			diff := at(pair[0]) - at(pair[1])
+			if diff < 0 {
+				diff = -diff
+			}
+			g += st.weights[j] * diff
+		}
+		grad[d] = g
+		if d == 0 || g < lo {
+			lo = g
+		}
+		if d == 0 || g > hi {
+			hi = g
+		}
+	}
+	threshold := vngK1*lo + vngK2*(hi-lo)
+
+	var total [3]float32
+	var used int
+	for d := range vngStencils {
+		if grad[d] > threshold {
+			continue
+		}
+		avg := vngAverage(raw, width, p, x, y, &vngStencils[d])
+		for c := range total {
+			total[c] += avg[c]
+		}
+		used++
+	}
+
+	own := p.At(x, y)
+	center := raw[i]
+	for c := range img.Planes {
+		if Channel(c) == own {
+			img.Planes[c][i] = center
+			continue
+		}
+		v := center + (total[c]-total[own])/float32(used)
+		img.Planes[c][i] = max(0, v)
+	}
+}
+
+// vngAverage returns the mean of each channel over the stencil's pixels
+// around (x, y): the near ones first, reaching further out only for
+// channels the near ones lack.
+func vngAverage(raw []float32, width int, p Pattern, x, y int, st *vngStencil) [3]float32 {
+	i := y*width + x
+	var sum [3]float32
+	var n [3]int
+	for _, o := range st.near {
+		c := p.At(x+o[0], y+o[1])
+		sum[c] += raw[i+o[1]*width+o[0]]
+		n[c]++
+	}
+	var reach [3]bool
+	for c := range n {
+		reach[c] = n[c] == 0
+	}
+	for _, o := range st.far {
+		c := p.At(x+o[0], y+o[1])
+		if reach[c] {
+			sum[c] += raw[i+o[1]*width+o[0]]
+			n[c]++
+		}
+	}
+
+	var avg [3]float32
+	for c := range avg {
+		if n[c] > 0 {
+			avg[c] = sum[c] / float32(n[c])
+		}
+	}
+	return avg
+}
+
+// parallelRows calls fn for every row, spread across the CPUs.
+func parallelRows(height int, fn func(y int)) {
+	workers := min(runtime.NumCPU(), height)
+	rows := (height + workers - 1) / workers
+
+	var wg sync.WaitGroup
+	for lo := 0; lo < height; lo += rows {
+		hi := min(height, lo+rows)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for y := lo; y < hi; y++ {
+				fn(y)
+			}
+		}()
+	}
+	wg.Wait()
+}
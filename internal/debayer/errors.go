@@ -0,0 +1,9 @@
+package debayer
+
+import "errors"
+
+var (
+	errUnknownPattern = errors.New("Bayer pattern must be RGGB, BGGR, GRBG or GBRG")
+	errUnknownMethod  = errors.New("debayer method must be bilinear or vng")
+	errBadDimensions  = errors.New("image dimensions do not match pixel count")
+)
@@ -0,0 +1,13 @@
+package fits
+
+import "errors"
+
+var (
+	errNotFITS        = errors.New("not a FITS file")
+	errUnsupported    = errors.New("unsupported FITS layout")
+	errTooLarge       = errors.New("image dimensions too large")
+	errBadDimensions  = errors.New("image dimensions do not match pixel count")
+	errInvalidBitpix  = errors.New("BITPIX must be 16 or -32")
+	errReservedHeader = errors.New("structural keywords are written automatically")
+	errNoWCS          = errors.New("header has no TAN WCS")
+)
@@ -0,0 +1,470 @@
+// Package fits reads and writes single-image FITS files, the format capture
+// software such as NINA, SGP and KStars/Ekos hands to PixInsight and Siril.
+package fits
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	blockSize = 2880
+	cardSize  = 80
+
+	// maxString is how many characters fit between the quotes of a string
+	// value written from column 11 of a header record.
+	maxString = 68
+
+	// readChunk is how many pixels Read makes room for up front; the rest
+	// grow as the data arrives.
+	readChunk = 1 << 20
+)
+
+// Limits on the images Read accepts, well beyond any sensor's but short of
+// exhausting memory on a hostile header.
+const (
+	MaxDimension = 1 << 16 // pixels along either axis
+	MaxPixels    = 1 << 28
+)
+
+// Supported BITPIX values.
+const (
+	Int16   = 16  // unsigned 16-bit pixels, stored signed with BZERO 32768
+	Float32 = -32 // IEEE single precision pixels
+)
+
+// Card is one header record.
+type Card struct {
+	Key     string
+	Value   any // string, bool, int, float64, or nil for commentary cards
+	Comment string
+}
+
+// Header is an ordered list of header cards, excluding the structural
+// keywords (SIMPLE, BITPIX, NAXIS*, BZERO, BSCALE, END) which are derived
+// from the image.
+type Header struct {
+	Cards []Card
+}
+
+// Set adds a card, replacing an existing card with the same keyword.
+func (h *Header) Set(key string, value any, comment string) {
+	key = strings.ToUpper(key)
+	for i := range h.Cards {
+		if h.Cards[i].Key == key && key != "COMMENT" && key != "HISTORY" {
+			h.Cards[i].Value = value
+			h.Cards[i].Comment = comment
+			return
+		}
+	}
+	h.Cards = append(h.Cards, Card{Key: key, Value: value, Comment: comment})
+}
+
+// AddComment adds a COMMENT card.
+func (h *Header) AddComment(text string) {
+	h.Cards = append(h.Cards, Card{Key: "COMMENT", Comment: text})
+}
+
+// Get returns the value of keyword key.
+func (h *Header) Get(key string) (any, bool) {
+	key = strings.ToUpper(key)
+	for _, c := range h.Cards {
+		if c.Key == key && c.Value != nil {
+			return c.Value, true
+		}
+	}
+	return nil, false
+}
+
+// String returns a string keyword's value.
+func (h *Header) String(key string) (string, bool) {
+	v, ok := h.Get(key)
+	s, isString := v.(string)
+	return s, ok && isString
+}
+
+// Float returns a numeric keyword's value.
+func (h *Header) Float(key string) (float64, bool) {
+	v, ok := h.Get(key)
+	if !ok {
+		return 0, false
+	}
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// Int returns an integer keyword's value.
+func (h *Header) Int(key string) (int, bool) {
+	v, ok := h.Get(key)
+	if !ok {
+		return 0, false
+	}
+	switch n := v.(type) {
+	case int:
+		return n, true
+	case float64:
+		if n == math.Trunc(n) {
+			return int(n), true
+		}
+	}
+	return 0, false
+}
+
+// Image is a two-dimensional image with its header. Pixels hold physical
+// values row by row in file order; BZERO and BSCALE are already applied.
+type Image struct {
+	Width  int
+	Height int
+	Bitpix int // Int16 or Float32
+	Pixels []float32
+	Header Header
+}
+
+// Write encodes img as a FITS file.
+func Write(w io.Writer, img *Image) error {
+	if img.Bitpix != Int16 && img.Bitpix != Float32 {
+		return errInvalidBitpix
+	}
+	if img.Width <= 0 || img.Height <= 0 || len(img.Pixels) != img.Width*img.Height {
+		return errBadDimensions
+	}
+
+	cards := []Card{
+		{Key: "SIMPLE", Value: true, Comment: "file conforms to FITS standard"},
+		{Key: "BITPIX", Value: img.Bitpix, Comment: bitpixComment(img.Bitpix)},
+		{Key: "NAXIS", Value: 2, Comment: "number of data axes"},
+		{Key: "NAXIS1", Value: img.Width, Comment: "length of data axis 1"},
+		{Key: "NAXIS2", Value: img.Height, Comment: "length of data axis 2"},
+		{Key: "EXTEND", Value: true, Comment: "FITS dataset may contain extensions"},
+	}
+	if img.Bitpix == Int16 {
+		cards = append(cards,
+			Card{Key: "BZERO", Value: 32768, Comment: "offset data range to that of unsigned short"},
+			Card{Key: "BSCALE", Value: 1, Comment: "default scaling factor"},
+		)
+	}
+	for _, c := range img.Header.Cards {
+		if structural(c.Key) {
+			return fmt.Errorf("%w: %s", errReservedHeader, c.Key)
+		}
+	}
+	cards = append(cards, img.Header.Cards...)
+
+	bw := bufio.NewWriter(w)
+	var written int
+	for _, c := range cards {
+		line, err := formatCard(c)
+		if err != nil {
+			return err
+		}
+		bw.WriteString(line)
+		written += cardSize
+	}
+	bw.WriteString(pad("END", cardSize))
+	written += cardSize
+	bw.WriteString(strings.Repeat(" ", padding(written)))
+
+	var buf [4]byte
+	for _, v := range img.Pixels {
+		if img.Bitpix == Int16 {
+			u := math.Round(math.Max(0, math.Min(math.MaxUint16, float64(v))))
+			binary.BigEndian.PutUint16(buf[:2], uint16(int32(u)-32768))
+			bw.Write(buf[:2])
+		} else {
+			binary.BigEndian.PutUint32(buf[:], math.Float32bits(v))
+			bw.Write(buf[:])
+		}
+	}
+	data := len(img.Pixels) * abs(img.Bitpix) / 8
+	bw.Write(make([]byte, padding(data)))
+	return bw.Flush()
+}
+
+// Read decodes the primary image of a FITS file.
+func Read(r io.Reader) (*Image, error) {
+	br := bufio.NewReader(r)
+
+	var header Header
+	var bitpix, naxis, width, height int
+	bzero, bscale := 0.0, 1.0
+	var read int
+
+	block := make([]byte, blockSize)
+	for ended := false; !ended; {
+		if _, err := io.ReadFull(br, block); err != nil {
+			if read == 0 {
+				return nil, errNotFITS
+			}
+			return nil, err
+		}
+		for off := 0; off < blockSize; off += cardSize {
+			line := string(block[off : off+cardSize])
+			if read == 0 && off == 0 && !strings.HasPrefix(line, "SIMPLE  =") {
+				return nil, errNotFITS
+			}
+			card, err := parseCard(line)
+			if err != nil {
+				return nil, err
+			}
+			switch card.Key {
+			case "END":
+				ended = true
+			case "SIMPLE", "EXTEND":
+			case "BITPIX":
+				bitpix, _ = card.Value.(int)
+			case "NAXIS":
+				naxis, _ = card.Value.(int)
+			case "NAXIS1":
+				width, _ = card.Value.(int)
+			case "NAXIS2":
+				height, _ = card.Value.(int)
+			case "BZERO":
+				bzero, _ = toFloat(card.Value)
+			case "BSCALE":
+				bscale, _ = toFloat(card.Value)
+			case "":
+			default:
+				header.Cards = append(header.Cards, card)
+			}
+			if ended {
+				break
+			}
+		}
+		read += blockSize
+	}
+
+	if naxis != 2 || width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("%w: NAXIS=%d", errUnsupported, naxis)
+	}
+	if width > MaxDimension || height > MaxDimension || width > MaxPixels/height {
+		return nil, fmt.Errorf("%w: %d×%d", errTooLarge, width, height)
+	}
+	var size int
+	switch bitpix {
+	case 8, 16, 32, -32, -64:
+		size = abs(bitpix) / 8
+	default:
+		return nil, fmt.Errorf("%w: BITPIX=%d", errUnsupported, bitpix)
+	}
+
+	// The data is read a row at a time, so a header promising more than the
+	// file holds costs no more memory than the file does
+	img := &Image{Width: width, Height: height, Bitpix: bitpix, Header: header}
+	img.Pixels = make([]float32, 0, min(width*height, readChunk))
+	row := make([]byte, width*size)
+	for y := 0; y < height; y++ {
+		if _, err := io.ReadFull(br, row); err != nil {
+			return nil, err
+		}
+		for x := 0; x < width; x++ {
+			b := row[x*size:]
+			var v float64
+			switch bitpix {
+			case 8:
+				v = float64(b[0])
+			case 16:
+				v = float64(int16(binary.BigEndian.Uint16(b)))
+			case 32:
+				v = float64(int32(binary.BigEndian.Uint32(b)))
+			case -32:
+				v = float64(math.Float32frombits(binary.BigEndian.Uint32(b)))
+			case -64:
+				v = math.Float64frombits(binary.BigEndian.Uint64(b))
+			}
+			img.Pixels = append(img.Pixels, float32(bzero+bscale*v))
+		}
+	}
+	return img, nil
+}
+
+// structural reports whether key is written by Write itself.
+func structural(key string) bool {
+	switch key {
+	case "SIMPLE", "BITPIX", "NAXIS", "NAXIS1", "NAXIS2", "EXTEND", "BZERO", "BSCALE", "END":
+		return true
+	}
+	return false
+}
+
+func bitpixComment(bitpix int) string {
+	if bitpix == Float32 {
+		return "array data type: 32-bit float"
+	}
+	return "array data type: 16-bit integer"
+}
+
+// formatCard renders c as an 80-character header record: strings are quoted
+// from column 11, other values right-justified to column 30.
+func formatCard(c Card) (string, error) {
+	key := strings.ToUpper(c.Key)
+	if len(key) > 8 {
+		return "", fmt.Errorf("keyword %q longer than 8 characters", c.Key)
+	}
+	if c.Value == nil {
+		return pad(fmt.Sprintf("%-8s%s", key, c.Comment), cardSize), nil
+	}
+
+	value, err := c.ValueString()
+	if err != nil {
+		return "", err
+	}
+	if _, isString := c.Value.(string); !isString {
+		value = fmt.Sprintf("%20s", value)
+	}
+
+	line := fmt.Sprintf("%-8s= %s", key, value)
+	if c.Comment != "" {
+		line += " / " + c.Comment
+	}
+	return pad(line, cardSize), nil
+}
+
+// ValueString returns the card's value as it appears in a header record:
+// strings quoted, logicals as T or F. Strings are cut to fit the record,
+// and characters other than printable ASCII replaced with '?'.
+func (c Card) ValueString() (string, error) {
+	switch v := c.Value.(type) {
+	case string:
+		return fmt.Sprintf("'%-8s'", quote(v)), nil
+	case bool:
+		if v {
+			return "T", nil
+		}
+		return "F", nil
+	case int:
+		return strconv.Itoa(v), nil
+	case float64:
+		return formatFloat(v), nil
+	case nil:
+		return "", nil
+	}
+	return "", fmt.Errorf("keyword %s: unsupported value type %T", c.Key, c.Value)
+}
+
+// quote returns s as it goes between a string value's quotes: printable
+// ASCII, with quotes doubled, and no more than maxString characters.
+func quote(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		q := string(r)
+		switch {
+		case r == '\'':
+			q = "''"
+		case r < ' ' || r > '~':
+			q = "?"
+		}
+		if b.Len()+len(q) > maxString {
+			break
+		}
+		b.WriteString(q)
+	}
+	return b.String()
+}
+
+// formatFloat renders f in the shortest form that round-trips, always with
+// a decimal point or exponent so readers see a real number.
+func formatFloat(f float64) string {
+	if f == 0 {
+		f = 0 // no negative zero
+	}
+	s := strconv.FormatFloat(f, 'G', -1, 64)
+	if !strings.ContainsAny(s, ".E") {
+		s += "."
+	}
+	return s
+}
+
+// parseCard decodes one header record.
+func parseCard(line string) (Card, error) {
+	key := strings.TrimSpace(line[:8])
+	if len(line) < 10 || line[8:10] != "= " {
+		return Card{Key: key, Comment: strings.TrimRight(line[8:], " ")}, nil
+	}
+
+	rest := strings.TrimLeft(line[10:], " ")
+	card := Card{Key: key}
+	if strings.HasPrefix(rest, "'") {
+		// Quoted string; '' is an escaped quote
+		var sb strings.Builder
+		i := 1
+		for ; i < len(rest); i++ {
+			if rest[i] == '\'' {
+				if i+1 < len(rest) && rest[i+1] == '\'' {
+					sb.WriteByte('\'')
+					i++
+					continue
+				}
+				break
+			}
+			sb.WriteByte(rest[i])
+		}
+		card.Value = strings.TrimRight(sb.String(), " ")
+		rest = rest[min(i+1, len(rest)):]
+		if j := strings.Index(rest, "/"); j >= 0 {
+			card.Comment = strings.TrimSpace(rest[j+1:])
+		}
+		return card, nil
+	}
+
+	value := rest
+	if j := strings.Index(rest, "/"); j >= 0 {
+		value, card.Comment = rest[:j], strings.TrimSpace(rest[j+1:])
+	}
+	value = strings.TrimSpace(value)
+	switch {
+	case value == "T":
+		card.Value = true
+	case value == "F":
+		card.Value = false
+	case value == "":
+	default:
+		if n, err := strconv.Atoi(value); err == nil {
+			card.Value = n
+		} else if f, err := strconv.ParseFloat(strings.Replace(value, "D", "E", 1), 64); err == nil {
+			card.Value = f
+		} else {
+			return Card{}, fmt.Errorf("keyword %s: invalid value %q", key, value)
+		}
+	}
+	return card, nil
+}
+
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+// pad right-pads s with spaces to n characters, truncating longer input.
+func pad(s string, n int) string {
+	if len(s) >= n {
+		return s[:n]
+	}
+	return s + strings.Repeat(" ", n-len(s))
+}
+
+// padding returns how many bytes complete the last 2880-byte block.
+func padding(n int) int {
+	return (blockSize - n%blockSize) % blockSize
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,118 @@
+package fits
+
+import "math"
+
+const deg2rad = math.Pi / 180
+
+// WCS is a gnomonic (TAN) world coordinate system mapping FITS pixel
+// coordinates, 1-based with integers at pixel centres, to J2000 RA/Dec.
+type WCS struct {
+	CRVAL1, CRVAL2 float64       // degrees, RA/Dec of the reference pixel
+	CRPIX1, CRPIX2 float64       // reference pixel
+	CD             [2][2]float64 // degrees per pixel, rotation and scale
+}
+
+// NewWCS returns the WCS of a width×height image centred on ra/dec
+// (degrees) at scale arcsec/pixel, stored top row first. At zero rotation
+// north is up and east left; rotation turns the sky clockwise on the
+// sensor, in degrees.
+func NewWCS(ra, dec float64, width, height int, scale, rotation float64) WCS {
+	s := scale / 3600
+	sin, cos := math.Sincos(rotation * deg2rad)
+	return WCS{
+		CRVAL1: ra,
+		CRVAL2: dec,
+		CRPIX1: float64(width)/2 + 0.5,
+		CRPIX2: float64(height)/2 + 0.5,
+		CD: [2][2]float64{
+			{-s * cos, -s * sin},
+			{s * sin, -s * cos},
+		},
+	}
+}
+
+// Apply writes the WCS keywords into h.
+func (w WCS) Apply(h *Header) {
+	h.Set("CTYPE1", "RA---TAN", "gnomonic projection")
+	h.Set("CTYPE2", "DEC--TAN", "gnomonic projection")
+	h.Set("CUNIT1", "deg", "unit of CRVAL1 and CD1_*")
+	h.Set("CUNIT2", "deg", "unit of CRVAL2 and CD2_*")
+	h.Set("CRVAL1", w.CRVAL1, "[deg] RA of reference pixel")
+	h.Set("CRVAL2", w.CRVAL2, "[deg] Dec of reference pixel")
+	h.Set("CRPIX1", w.CRPIX1, "X of reference pixel")
+	h.Set("CRPIX2", w.CRPIX2, "Y of reference pixel")
+	h.Set("CD1_1", w.CD[0][0], "[deg/px] rotation and scale matrix")
+	h.Set("CD1_2", w.CD[0][1], "[deg/px] rotation and scale matrix")
+	h.Set("CD2_1", w.CD[1][0], "[deg/px] rotation and scale matrix")
+	h.Set("CD2_2", w.CD[1][1], "[deg/px] rotation and scale matrix")
+}
+
+// ReadWCS reads a TAN WCS from h.
+func ReadWCS(h *Header) (WCS, error) {
+	if t, _ := h.String("CTYPE1"); t != "RA---TAN" {
+		return WCS{}, errNoWCS
+	}
+	var w WCS
+	keys := []struct {
+		key string
+		dst *float64
+	}{
+		{"CRVAL1", &w.CRVAL1}, {"CRVAL2", &w.CRVAL2},
+		{"CRPIX1", &w.CRPIX1}, {"CRPIX2", &w.CRPIX2},
+		{"CD1_1", &w.CD[0][0]}, {"CD1_2", &w.CD[0][1]},
+		{"CD2_1", &w.CD[1][0]}, {"CD2_2", &w.CD[1][1]},
+	}
+	for _, k := range keys {
+		v, ok := h.Float(k.key)
+		if !ok {
+			return WCS{}, errNoWCS
+		}
+		*k.dst = v
+	}
+	return w, nil
+}
+
+// Scale returns the pixel scale in arcsec/pixel.
+func (w WCS) Scale() float64 {
+	return math.Sqrt(math.Abs(w.CD[0][0]*w.CD[1][1]-w.CD[0][1]*w.CD[1][0])) * 3600
+}
+
+// PixelToSky returns the RA/Dec in degrees of FITS pixel (x, y).
+func (w WCS) PixelToSky(x, y float64) (ra, dec float64) {
+	dx, dy := x-w.CRPIX1, y-w.CRPIX2
+	xi := (w.CD[0][0]*dx + w.CD[0][1]*dy) * deg2rad
+	eta := (w.CD[1][0]*dx + w.CD[1][1]*dy) * deg2rad
+
+	ra0, dec0 := w.CRVAL1*deg2rad, w.CRVAL2*deg2rad
+	denom := math.Cos(dec0) - eta*math.Sin(dec0)
+	ra = ra0 + math.Atan2(xi, denom)
+	dec = math.Atan2(math.Sin(dec0)+eta*math.Cos(dec0), math.Hypot(xi, denom))
+
+	ra = math.Mod(ra/deg2rad, 360)
+	if ra < 0 {
+		ra += 360
+	}
+	return ra, dec / deg2rad
+}
+
+// SkyToPixel returns the FITS pixel coordinates of RA/Dec in degrees. ok is
+// false for points on the far hemisphere.
+func (w WCS) SkyToPixel(ra, dec float64) (x, y float64, ok bool) {
+	ra0, dec0 := w.CRVAL1*deg2rad, w.CRVAL2*deg2rad
+	ra1, dec1 := ra*deg2rad, dec*deg2rad
+	dRA := ra1 - ra0
+	cosc := math.Sin(dec0)*math.Sin(dec1) + math.Cos(dec0)*math.Cos(dec1)*math.Cos(dRA)
+	if cosc <= 0 {
+		return 0, 0, false
+	}
+	xi := math.Cos(dec1) * math.Sin(dRA) / cosc / deg2rad
+	eta := (math.Cos(dec0)*math.Sin(dec1) - math.Sin(dec0)*math.Cos(dec1)*math.Cos(dRA)) / cosc / deg2rad
+
+	det := w.CD[0][0]*w.CD[1][1] - w.CD[0][1]*w.CD[1][0]
+	if det == 0 {
+		return 0, 0, false
+	}
+	dx := (w.CD[1][1]*xi - w.CD[0][1]*eta) / det
+	dy := (-w.CD[1][0]*xi + w.CD[0][0]*eta) / det
+	return w.CRPIX1 + dx, w.CRPIX2 + dy, true
+}
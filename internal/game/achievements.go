@@ -209,6 +209,24 @@ var AllAchievements = []Achievement{
 		CreditsReward: 400,
 		Category:      string(CategoryImaging),
 	},
+	{
+		ID:            "calibration_set",
+		Name:          "By the Book",
+		Description:   "Take 10 bias, 10 dark and 10 flat frames in one session",
+		Rarity:        RarityUncommon,
+		XPReward:      250,
+		CreditsReward: 500,
+		Category:      string(CategoryImaging),
+	},
+	{
+		ID:            "calibration_library",
+		Name:          "Calibration Library",
+		Description:   "Capture 500 calibration frames",
+		Rarity:        RarityRare,
+		XPReward:      600,
+		CreditsReward: 1500,
+		Category:      string(CategoryImaging),
+	},
 
 	// Target Achievements
 	{
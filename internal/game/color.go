@@ -0,0 +1,107 @@
+package game
+
+import "math"
+
+// Bayer patterns of one-shot color sensors, naming the filters of the
+// top-left 2×2 cell row by row.
+const (
+	BayerRGGB = "RGGB"
+	BayerBGGR = "BGGR"
+	BayerGRBG = "GRBG"
+	BayerGBRG = "GBRG"
+)
+
+// Color channels of a one-shot color sensor, indexing ChannelResponse.
+const (
+	ChannelRed = iota
+	ChannelGreen
+	ChannelBlue
+)
+
+const (
+	// solarTemperature is the color temperature, in Kelvin, of the
+	// sunlike star the effective QE of a color sensor is quoted for.
+	solarTemperature = 5778.0
+
+	// The visible band over which channel responses are integrated, in nm.
+	bandStart = 400.0
+	bandEnd   = 700.0
+	bandStep  = 5.0
+)
+
+// SpectralResponse is the quantum efficiency of one filtered channel as a
+// Gaussian passband.
+type SpectralResponse struct {
+	Peak  float64 `json:"peak"`  // wavelength of peak response, nm
+	Width float64 `json:"width"` // full width at half maximum, nm
+	QE    float64 `json:"qe"`    // quantum efficiency at the peak (0-1)
+}
+
+// At returns the channel's quantum efficiency at wavelength nm.
+func (r SpectralResponse) At(nm float64) float64 {
+	if r.Width <= 0 {
+		return 0
+	}
+	sigma := r.Width / (2 * math.Sqrt(2*math.Ln2))
+	d := (nm - r.Peak) / sigma
+	return r.QE * math.Exp(-d*d/2)
+}
+
+// IsColor reports whether the camera is a one-shot color camera.
+func (c VirtualCameraConfig) IsColor() bool {
+	return c.BayerPattern != "" && len(c.ChannelResponse) == 3
+}
+
+// ChannelQE returns the fraction of photons from a blackbody of the given
+// temperature, in Kelvin, that a pixel of the channel records. A mono
+// camera records them all at its QE; a color pixel loses what its filter
+// blocks.
+func (c VirtualCameraConfig) ChannelQE(channel int, temperature float64) float64 {
+	if !c.IsColor() || channel < 0 || channel >= len(c.ChannelResponse) {
+		return c.QE
+	}
+	resp := c.ChannelResponse[channel]
+	var recorded, total float64
+	for nm := bandStart; nm <= bandEnd; nm += bandStep {
+		photons := blackbodyPhotons(nm, temperature)
+		recorded += photons * resp.At(nm)
+		total += photons
+	}
+	if total <= 0 {
+		return 0
+	}
+	return recorded / total
+}
+
+// EffectiveQE returns the quantum efficiency averaged over the sensor's
+// pixels for sunlike light. For a color camera it weighs the channels as
+// the Bayer cell does, one red, two green and one blue.
+func (c VirtualCameraConfig) EffectiveQE() float64 {
+	if !c.IsColor() {
+		return c.QE
+	}
+	return (c.ChannelQE(ChannelRed, solarTemperature) +
+		2*c.ChannelQE(ChannelGreen, solarTemperature) +
+		c.ChannelQE(ChannelBlue, solarTemperature)) / 4
+}
+
+// blackbodyPhotons returns the relative photon flux of a blackbody at
+// temperature Kelvin per unit wavelength at nm.
+func blackbodyPhotons(nm, temperature float64) float64 {
+	if temperature <= 0 {
+		return 0
+	}
+	// Photon radiance goes as λ⁻⁴ / (exp(hc/λkT) − 1); hc/k = 1.4388e7 nm·K
+	return math.Pow(nm, -4) / math.Expm1(1.4388e7/(nm*temperature))
+}
+
+// colorChannelResponse returns the channel passbands of a one-shot color
+// camera with peak quantum efficiency qe: broad overlapping red, green and
+// blue dyes, green the most sensitive as on consumer CMOS sensors.
+func colorChannelResponse(qe float64) []SpectralResponse {
+	return []SpectralResponse{
+		ChannelRed:   {Peak: 610, Width: 120, QE: qe * 0.85},
+		ChannelGreen: {Peak: 535, Width: 110, QE: qe},
+		ChannelBlue:  {Peak: 460, Width: 100, QE: qe * 0.9},
+	}
+}
@@ -52,6 +52,7 @@ type EquipmentStats struct {
 	HasCooling       bool    `json:"has_cooling,omitempty"`
 	CoolingDelta     float64 `json:"cooling_delta,omitempty"` // degrees below ambient
 	QE               float64 `json:"qe,omitempty"`            // quantum efficiency %
+	BayerPattern     string  `json:"bayer_pattern,omitempty"` // color filter mosaic, empty for mono
 
 	// Mount stats
 	TrackingAccuracy float64 `json:"tracking_accuracy,omitempty"` // arcsec/sec periodic error
@@ -106,7 +107,7 @@ var AllEquipment = []Equipment{
 		Description: "Entry-level monochrome camera for learning the basics. High read noise but affordable.",
 		Type:        EquipmentTypeCamera,
 		Tier:        TierStarter,
-		Price:       0, // Included in starter kit
+		Price:       500,
 		Stats: EquipmentStats{
 			SensorWidth:      3096,
 			SensorHeight:     2080,
@@ -124,7 +125,7 @@ var AllEquipment = []Equipment{
 		Description: "Entry-level one-shot color camera. Easy to use but limited sensitivity.",
 		Type:        EquipmentTypeCamera,
 		Tier:        TierStarter,
-		Price:       500,
+		Price:       0, // Included in starter kit
 		Stats: EquipmentStats{
 			SensorWidth:      3096,
 			SensorHeight:     2080,
@@ -134,6 +135,7 @@ var AllEquipment = []Equipment{
 			BitDepth:         12,
 			HasCooling:       false,
 			QE:               45,
+			BayerPattern:     "RGGB",
 		},
 	},
 
@@ -176,6 +178,7 @@ var AllEquipment = []Equipment{
 			HasCooling:       true,
 			CoolingDelta:     35,
 			QE:               60,
+			BayerPattern:     "RGGB",
 		},
 	},
 
@@ -597,7 +600,7 @@ func GetAvailableEquipment(playerTier PlayerTier, ownedIDs []string) []Equipment
 // GetStarterKit returns the default equipment for new players
 func GetStarterKit() []string {
 	return []string{
-		"camera_starter_mono",
+		"camera_starter_color",
 		"mount_starter_altaz",
 		"focuser_starter",
 		"scope_starter_refractor",
@@ -609,7 +612,7 @@ var StarterLoadout = EquipmentLoadout{
 	ID:          "starter_loadout",
 	Name:        "Starter Setup",
 	Description: "Basic equipment to begin your astrophotography journey",
-	Camera:      "camera_starter_mono",
+	Camera:      "camera_starter_color",
 	Mount:       "mount_starter_altaz",
 	Focuser:     "focuser_starter",
 	Telescope:   "scope_starter_refractor",
@@ -649,3 +652,23 @@ func CalculateFieldOfView(telescope, camera *Equipment) (width, height float64)
 
 	return width, height
 }
+
+// CalculateBinnedImageScale returns the image scale in arcsec/pixel with the
+// camera binned by bin
+func CalculateBinnedImageScale(telescope, camera *Equipment, bin int) float64 {
+	return CalculateImageScale(telescope, camera) * float64(max(1, bin))
+}
+
+// CalculateReadoutFieldOfView returns the FOV in arcmin (width, height) of
+// the part of the sensor the readout covers
+func CalculateReadoutFieldOfView(telescope, camera *Equipment, readout Readout) (width, height float64) {
+	if telescope == nil || camera == nil || readout.ROI == nil {
+		return CalculateFieldOfView(telescope, camera)
+	}
+
+	scale := CalculateBinnedImageScale(telescope, camera, readout.Binning())
+	width = float64(readout.ROI.Width) * scale / 60.0
+	height = float64(readout.ROI.Height) * scale / 60.0
+
+	return width, height
+}
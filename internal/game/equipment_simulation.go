@@ -1,5 +1,7 @@
 package game
 
+import "math"
+
 // VirtualCameraConfig holds configuration for the virtual camera based on equipment
 type VirtualCameraConfig struct {
 	// Sensor dimensions
@@ -24,6 +26,14 @@ type VirtualCameraConfig struct {
 	// Additional characteristics
 	GainRange      [2]float64 `json:"gain_range"`     // min, max gain
 	OffsetRange    [2]int     `json:"offset_range"`   // min, max offset
+
+	// Readout
+	MaxBin         int           `json:"max_bin"`       // largest square binning factor
+	ReadoutModes   []ReadoutMode `json:"readout_modes"` // first is the default
+
+	// Color
+	BayerPattern    string             `json:"bayer_pattern,omitempty"`    // empty for mono
+	ChannelResponse []SpectralResponse `json:"channel_response,omitempty"` // red, green, blue
 }
 
 // VirtualMountConfig holds configuration for the virtual mount based on equipment
@@ -36,6 +46,12 @@ type VirtualMountConfig struct {
 	DriftRate      float64 `json:"drift_rate"`       // arcsec/hour polar misalignment
 	TrackingJitter float64 `json:"tracking_jitter"`  // arcsec RMS random error
 
+	// Gear imperfections seen by guiding
+	DecBacklash   float64 `json:"dec_backlash"`   // arcsec of Dec gear play taken up on reversal
+	RAStiction    float64 `json:"ra_stiction"`    // arcsec lost at the start of an RA pulse from rest
+	DecStiction   float64 `json:"dec_stiction"`   // arcsec lost at the start of a Dec pulse from rest
+	BalanceEffect float64 `json:"balance_effect"` // fraction of RA correction gained or lost to imbalance
+
 	// Slewing
 	MaxSlewSpeed   float64 `json:"max_slew_speed"`   // degrees/sec
 	SlewAccel      float64 `json:"slew_accel"`       // degrees/sec^2
@@ -133,6 +149,11 @@ func equipmentToCameraConfig(equip *Equipment) VirtualCameraConfig {
 		QE:               equip.Stats.QE / 100.0, // Convert percentage to fraction
 		HasCooling:       equip.Stats.HasCooling,
 		CoolingDelta:     equip.Stats.CoolingDelta,
+		ReadoutModes:     cameraReadoutModes(equip),
+		BayerPattern:     equip.Stats.BayerPattern,
+	}
+	if config.BayerPattern != "" {
+		config.ChannelResponse = colorChannelResponse(config.QE)
 	}
 
 	// Set defaults based on tier
@@ -142,24 +163,28 @@ func equipmentToCameraConfig(equip *Equipment) VirtualCameraConfig {
 		config.GainRange = [2]float64{0, 100}
 		config.OffsetRange = [2]int{0, 50}
 		config.CoolingPower = 0
+		config.MaxBin = 2
 
 	case TierMidRange:
 		config.DarkCurrent = 0.1
 		config.GainRange = [2]float64{0, 200}
 		config.OffsetRange = [2]int{0, 100}
 		config.CoolingPower = 30
+		config.MaxBin = 4
 
 	case TierProfessional:
 		config.DarkCurrent = 0.01
 		config.GainRange = [2]float64{0, 300}
 		config.OffsetRange = [2]int{0, 200}
 		config.CoolingPower = 50
+		config.MaxBin = 4
 
 	case TierPremium:
 		config.DarkCurrent = 0.001
 		config.GainRange = [2]float64{0, 500}
 		config.OffsetRange = [2]int{0, 300}
 		config.CoolingPower = 80
+		config.MaxBin = 4
 	}
 
 	return config
@@ -183,6 +208,10 @@ func equipmentToMountConfig(equip *Equipment) VirtualMountConfig {
 	case TierStarter:
 		config.DriftRate = 30.0       // Poor polar alignment tolerance
 		config.TrackingJitter = 2.0   // High random error
+		config.DecBacklash = 20.0     // Sloppy Dec worm
+		config.RAStiction = 1.0
+		config.DecStiction = 1.5
+		config.BalanceEffect = 0.3
 		config.SlewAccel = 0.5
 		config.AltitudeMin = 10.0
 		config.AltitudeMax = 85.0
@@ -191,6 +220,10 @@ func equipmentToMountConfig(equip *Equipment) VirtualMountConfig {
 	case TierMidRange:
 		config.DriftRate = 10.0
 		config.TrackingJitter = 0.5
+		config.DecBacklash = 8.0
+		config.RAStiction = 0.4
+		config.DecStiction = 0.6
+		config.BalanceEffect = 0.15
 		config.SlewAccel = 1.0
 		config.AltitudeMin = 5.0
 		config.AltitudeMax = 88.0
@@ -199,6 +232,10 @@ func equipmentToMountConfig(equip *Equipment) VirtualMountConfig {
 	case TierProfessional:
 		config.DriftRate = 3.0
 		config.TrackingJitter = 0.2
+		config.DecBacklash = 2.0
+		config.RAStiction = 0.1
+		config.DecStiction = 0.2
+		config.BalanceEffect = 0.05
 		config.SlewAccel = 2.0
 		config.AltitudeMin = 2.0
 		config.AltitudeMax = 89.0
@@ -207,6 +244,10 @@ func equipmentToMountConfig(equip *Equipment) VirtualMountConfig {
 	case TierPremium:
 		config.DriftRate = 1.0
 		config.TrackingJitter = 0.05
+		config.DecBacklash = 0.2      // Harmonic or belt drive
+		config.RAStiction = 0.02
+		config.DecStiction = 0.05
+		config.BalanceEffect = 0.02
 		config.SlewAccel = 3.0
 		config.AltitudeMin = 0.0
 		config.AltitudeMax = 90.0
@@ -359,7 +400,9 @@ func CalculateExpectedHFR(config *VirtualLoadoutConfig) float64 {
 	return hfr
 }
 
-// CalculateExpectedSNR estimates achievable SNR for given conditions
+// CalculateExpectedSNR estimates achievable SNR for given conditions. The
+// camera's pixel size, read noise and dark current are taken as they are,
+// so pass a config binned with WithReadout to compare binning modes.
 func CalculateExpectedSNR(config *VirtualLoadoutConfig, exposureTime float64, targetMag float64) float64 {
 	if config == nil || exposureTime <= 0 {
 		return 0
@@ -367,18 +410,40 @@ func CalculateExpectedSNR(config *VirtualLoadoutConfig, exposureTime float64, ta
 
 	// Simplified SNR calculation
 	// Signal = exposure_time * QE * aperture^2 * 10^(-0.4 * magnitude)
-	signal := exposureTime * config.Camera.QE *
+	// A color sensor's filters pass each pixel only part of the band, so it
+	// records the signal and sky at its effective QE
+	qe := config.Camera.EffectiveQE()
+	filter := 1.0
+	if config.Camera.QE > 0 {
+		filter = qe / config.Camera.QE
+	}
+	signal := exposureTime * qe *
 		(config.Telescope.Aperture * config.Telescope.Aperture / 10000) *
 		pow(10, -0.4*targetMag) * 1000000 // arbitrary scaling
 
-	// Noise sources
+	// The star covers the pixels within an aperture a FWHM across (assume
+	// 2" seeing), each adding its own read noise, dark current and sky;
+	// binned pixels cover the star with fewer of them
+	pixels := 1.0
+	skyRate := 10.0 // Simplified sky background, electrons/sec/pixel at 1"/pixel
+	if config.Camera.PixelSize > 0 && config.Telescope.FocalLength > 0 {
+		scale := 206.265 * config.Camera.PixelSize / config.Telescope.FocalLength
+		fwhm := 2.0 / scale
+		pixels = math.Pi * (fwhm / 2) * (fwhm / 2)
+		if pixels < 1 {
+			pixels = 1
+		}
+		skyRate *= scale * scale
+	}
+
+	// Noise sources, per pixel
 	readNoise := config.Camera.ReadNoise
 	darkNoise := sqrt(config.Camera.DarkCurrent * exposureTime)
 	shotNoise := sqrt(signal)
-	skyNoise := sqrt(exposureTime * 10) // Simplified sky background
+	skyNoise := sqrt(exposureTime * skyRate * filter)
 
-	totalNoise := sqrt(readNoise*readNoise + darkNoise*darkNoise +
-		shotNoise*shotNoise + skyNoise*skyNoise)
+	totalNoise := sqrt(shotNoise*shotNoise +
+		pixels*(readNoise*readNoise+darkNoise*darkNoise+skyNoise*skyNoise))
 
 	if totalNoise <= 0 {
 		return 0
@@ -387,6 +452,33 @@ func CalculateExpectedSNR(config *VirtualLoadoutConfig, exposureTime float64, ta
 	return signal / totalNoise
 }
 
+// maxEstimatedExposure bounds CalculateExposureForSNR, in seconds.
+const maxEstimatedExposure = 3600.0
+
+// CalculateExposureForSNR estimates the exposure, in seconds, that reaches
+// targetSNR on a star of magnitude targetMag, or 0 when no exposure up to
+// an hour does.
+func CalculateExposureForSNR(config *VirtualLoadoutConfig, targetSNR float64, targetMag float64) float64 {
+	if config == nil || targetSNR <= 0 {
+		return 0
+	}
+	if CalculateExpectedSNR(config, maxEstimatedExposure, targetMag) < targetSNR {
+		return 0
+	}
+
+	// SNR grows with exposure, so bisect
+	lo, hi := 0.0, maxEstimatedExposure
+	for i := 0; i < 50 && hi-lo > 0.01; i++ {
+		mid := (lo + hi) / 2
+		if CalculateExpectedSNR(config, mid, targetMag) < targetSNR {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return hi
+}
+
 // sqrt is a simple square root approximation
 func sqrt(x float64) float64 {
 	if x <= 0 {
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"math"
 	"sync"
 
 	"github.com/darkdragonsastro/draco-simulator/internal/database"
@@ -308,7 +309,9 @@ func GetLoadoutStats(loadout EquipmentLoadout) LoadoutStats {
 	mount := GetEquipment(loadout.Mount)
 	focuser := GetEquipment(loadout.Focuser)
 
+	var sensor VirtualCameraConfig
 	if camera != nil {
+		sensor = equipmentToCameraConfig(camera)
 		stats.SensorWidth = camera.Stats.SensorWidth
 		stats.SensorHeight = camera.Stats.SensorHeight
 		stats.PixelSize = camera.Stats.PixelSize
@@ -317,6 +320,10 @@ func GetLoadoutStats(loadout EquipmentLoadout) LoadoutStats {
 		stats.HasCooling = camera.Stats.HasCooling
 		stats.CoolingDelta = camera.Stats.CoolingDelta
 		stats.QE = camera.Stats.QE
+		stats.MaxBin = sensor.MaxBin
+		stats.ReadoutModes = sensor.ReadoutModes
+		stats.BayerPattern = sensor.BayerPattern
+		stats.EffectiveQE = math.Round(sensor.EffectiveQE()*1000) / 10
 	}
 
 	if telescope != nil {
@@ -341,6 +348,17 @@ func GetLoadoutStats(loadout EquipmentLoadout) LoadoutStats {
 	if camera != nil && telescope != nil {
 		stats.ImageScale = CalculateImageScale(telescope, camera)
 		stats.FOVWidth, stats.FOVHeight = CalculateFieldOfView(telescope, camera)
+
+		// Sampling at each binning the camera offers
+		for bin := 1; bin <= max(1, stats.MaxBin); bin++ {
+			width, height := sensor.BinnedSize(bin)
+			stats.Binning = append(stats.Binning, BinningStats{
+				Bin:        bin,
+				Width:      width,
+				Height:     height,
+				ImageScale: CalculateBinnedImageScale(telescope, camera, bin),
+			})
+		}
 	}
 
 	return stats
@@ -358,6 +376,12 @@ type LoadoutStats struct {
 	CoolingDelta float64 `json:"cooling_delta"`
 	QE           float64 `json:"qe"`
 
+	BayerPattern string  `json:"bayer_pattern,omitempty"` // empty for mono
+	EffectiveQE  float64 `json:"effective_qe"`            // % averaged over the Bayer cell for sunlike light
+
+	MaxBin       int           `json:"max_bin"`
+	ReadoutModes []ReadoutMode `json:"readout_modes"`
+
 	// Telescope
 	Aperture    float64 `json:"aperture"`
 	FocalLength float64 `json:"focal_length"`
@@ -377,6 +401,16 @@ type LoadoutStats struct {
 	ImageScale float64 `json:"image_scale"` // arcsec/pixel
 	FOVWidth   float64 `json:"fov_width"`   // arcmin
 	FOVHeight  float64 `json:"fov_height"`  // arcmin
+
+	Binning []BinningStats `json:"binning"` // sampling at each binning factor
+}
+
+// BinningStats describes the sensor binned by Bin on a loadout's telescope
+type BinningStats struct {
+	Bin        int     `json:"bin"`
+	Width      int     `json:"width"`       // pixels
+	Height     int     `json:"height"`      // pixels
+	ImageScale float64 `json:"image_scale"` // arcsec/pixel
 }
 
 // GetEquipmentUpgrades returns equipment upgrades available from current equipment
@@ -0,0 +1,113 @@
+package game
+
+import "math"
+
+// ReadoutMode is one of a camera's selectable sensor readout modes. Modes
+// trade read noise against full well, as the high-gain and low-noise modes
+// of modern CMOS cameras do.
+type ReadoutMode struct {
+	Name             string  `json:"name"`
+	Description      string  `json:"description"`
+	ReadNoise        float64 `json:"read_noise"`         // electrons RMS
+	FullWellCapacity int     `json:"full_well_capacity"` // electrons
+}
+
+// ROI is a subframe of the sensor in binned pixels, from the top-left corner.
+type ROI struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// Readout selects how the sensor is read out: binning, subframe and mode.
+// The zero value reads the full sensor unbinned in the default mode.
+type Readout struct {
+	Bin  int    `json:"bin"`            // square binning factor, 1-4; 0 means 1
+	ROI  *ROI   `json:"roi,omitempty"`  // subframe, nil for the full frame
+	Mode string `json:"mode,omitempty"` // readout mode name, empty for the default
+}
+
+// Binning returns the binning factor, defaulting to 1.
+func (r Readout) Binning() int {
+	return max(1, r.Bin)
+}
+
+// ReadoutMode returns the named readout mode, or the default mode for an
+// empty name. Cameras without modes read out in a single mode with their
+// nominal noise and full well.
+func (c VirtualCameraConfig) ReadoutMode(name string) (ReadoutMode, bool) {
+	if len(c.ReadoutModes) == 0 {
+		mode := ReadoutMode{Name: "Standard", ReadNoise: c.ReadNoise, FullWellCapacity: c.FullWellCapacity}
+		return mode, name == "" || name == mode.Name
+	}
+	if name == "" {
+		return c.ReadoutModes[0], true
+	}
+	for _, m := range c.ReadoutModes {
+		if m.Name == name {
+			return m, true
+		}
+	}
+	return ReadoutMode{}, false
+}
+
+// BinnedSize returns the size of the full sensor in pixels binned by bin.
+func (c VirtualCameraConfig) BinnedSize(bin int) (width, height int) {
+	bin = max(1, bin)
+	return c.SensorWidth / bin, c.SensorHeight / bin
+}
+
+// WithReadout returns the camera as it behaves under the readout: the
+// sensor shrinks to the subframe, and binned pixels are larger, collect the
+// dark current and full well of the pixels they combine, and, binned in
+// software as CMOS sensors are, add their read noise in quadrature. An
+// unknown mode falls back to the default.
+func (c VirtualCameraConfig) WithReadout(r Readout) VirtualCameraConfig {
+	bin := r.Binning()
+	mode, ok := c.ReadoutMode(r.Mode)
+	if !ok {
+		mode, _ = c.ReadoutMode("")
+	}
+
+	out := c
+	out.SensorWidth, out.SensorHeight = c.BinnedSize(bin)
+	if r.ROI != nil {
+		out.SensorWidth, out.SensorHeight = r.ROI.Width, r.ROI.Height
+	}
+	n := float64(bin * bin)
+	out.PixelSize = c.PixelSize * float64(bin)
+	out.ReadNoise = mode.ReadNoise * math.Sqrt(n)
+	out.FullWellCapacity = mode.FullWellCapacity * bin * bin
+	out.DarkCurrent = c.DarkCurrent * n
+	return out
+}
+
+// cameraReadoutModes returns the readout modes a camera offers by tier,
+// derived from its nominal read noise and full well.
+func cameraReadoutModes(equip *Equipment) []ReadoutMode {
+	standard := ReadoutMode{
+		Name:             "Standard",
+		Description:      "Balanced read noise and dynamic range",
+		ReadNoise:        equip.Stats.ReadNoise,
+		FullWellCapacity: equip.Stats.FullWellCapacity,
+	}
+	if equip.Tier == TierStarter {
+		return []ReadoutMode{standard}
+	}
+	return []ReadoutMode{
+		standard,
+		{
+			Name:             "High Gain",
+			Description:      "Lowest read noise for short and narrowband exposures, at a third of the full well",
+			ReadNoise:        equip.Stats.ReadNoise * 0.6,
+			FullWellCapacity: equip.Stats.FullWellCapacity / 3,
+		},
+		{
+			Name:             "Low Noise",
+			Description:      "Slower readout that lowers read noise while keeping the full well",
+			ReadNoise:        equip.Stats.ReadNoise * 0.8,
+			FullWellCapacity: equip.Stats.FullWellCapacity,
+		},
+	}
+}
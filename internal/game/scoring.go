@@ -23,6 +23,9 @@ type ImageScore struct {
 	XPEarned   int                       `json:"xp_earned"`  // XP awarded for this image
 	BonusXP    int                       `json:"bonus_xp"`   // Bonus XP for exceptional quality
 	Grade      ImageGrade                `json:"grade"`      // Letter grade
+
+	// Metrics are the measurements scored, when measured from the pixels
+	Metrics *ImageMetrics `json:"metrics,omitempty"`
 }
 
 // ImageGrade represents letter grades for image quality
@@ -62,6 +65,8 @@ type ImageMetrics struct {
 	Gain         int     `json:"gain"`          // Camera gain setting
 	MeanADU      float64 `json:"mean_adu"`      // Mean background ADU
 	MaxADU       float64 `json:"max_adu"`       // Maximum ADU (saturation check)
+	// Fraction of detected stars with saturated cores, when measured
+	SaturatedFraction float64 `json:"saturated_fraction"`
 
 	// Noise metrics
 	BackgroundStdDev float64 `json:"background_stddev"` // Background noise level
@@ -69,7 +74,8 @@ type ImageMetrics struct {
 
 	// Context
 	BitDepth   int     `json:"bit_depth"`   // Camera bit depth (8, 12, 14, 16)
-	PixelScale float64 `json:"pixel_scale"` // Arcsec per pixel
+	PixelScale float64 `json:"pixel_scale"` // Arcsec per (binned) pixel
+	Binning    int     `json:"binning"`     // Binning factor the frame was read out at
 }
 
 // ScoringConfig holds thresholds and weights for scoring
@@ -80,6 +86,15 @@ type ScoringConfig struct {
 	HFRAcceptable float64 // HFR for 60 score
 	HFRPoor       float64 // HFR for 40 score
 
+	// ReferencePixelScale is the arcsec/pixel the HFR thresholds are set
+	// for; HFRs measured at other scales, binned frames included, are
+	// converted to it so coarser sampling isn't mistaken for better focus
+	ReferencePixelScale float64
+
+	// Sampling thresholds (FWHM in pixels)
+	UndersampledFWHM float64 // Below this stars are blocky
+	OversampledFWHM  float64 // Above this binning would lose no detail
+
 	// Tracking thresholds (elongation ratio)
 	ElongationExcellent  float64 // Elongation for 100 score
 	ElongationGood       float64 // Elongation for 80 score
@@ -116,6 +131,12 @@ func DefaultScoringConfig() ScoringConfig {
 		HFRAcceptable: 3.5,
 		HFRPoor:       5.0,
 
+		ReferencePixelScale: 1.5,
+
+		// Sampling thresholds (FWHM in pixels)
+		UndersampledFWHM: 1.5,
+		OversampledFWHM:  5.0,
+
 		// Tracking thresholds
 		ElongationExcellent:  1.05,
 		ElongationGood:       1.15,
@@ -216,6 +237,12 @@ func (s *ImageScorer) scoreFocus(metrics ImageMetrics, score *ImageScore) float6
 		return 50.0 // Neutral score when no data
 	}
 
+	// Judge focus by the star size on the sky, not in pixels
+	if metrics.PixelScale > 0 && s.config.ReferencePixelScale > 0 {
+		hfr *= metrics.PixelScale / s.config.ReferencePixelScale
+	}
+	s.sampleFeedback(metrics, score)
+
 	var focusScore float64
 
 	switch {
@@ -264,6 +291,37 @@ func (s *ImageScorer) scoreFocus(metrics ImageMetrics, score *ImageScore) float6
 	return clamp(focusScore, 0, 100)
 }
 
+// sampleFeedback advises on binning when the stars are under- or
+// oversampled for the pixel scale
+func (s *ImageScorer) sampleFeedback(metrics ImageMetrics, score *ImageScore) {
+	if metrics.FWHM <= 0 || metrics.PixelScale <= 0 {
+		return
+	}
+	sampling := metrics.FWHM / metrics.PixelScale // FWHM in pixels
+	binning := max(1, metrics.Binning)
+
+	switch {
+	case sampling < s.config.UndersampledFWHM:
+		suggestion := "Use a longer focal length or smaller pixels to resolve finer detail"
+		if binning > 1 {
+			suggestion = "Bin less: stars span too few pixels to resolve their shape"
+		}
+		score.Feedback = append(score.Feedback, ScoringFeedback{
+			Category:   ScoreCategoryFocus,
+			Message:    "Stars are undersampled",
+			Severity:   "info",
+			Suggestion: suggestion,
+		})
+	case sampling > s.config.OversampledFWHM && binning < 4:
+		score.Feedback = append(score.Feedback, ScoringFeedback{
+			Category:   ScoreCategoryFocus,
+			Message:    "Stars are oversampled",
+			Severity:   "info",
+			Suggestion: "Bin 2x2 or more: you lose no detail and gain signal per pixel",
+		})
+	}
+}
+
 // scoreTracking evaluates tracking/guiding quality
 func (s *ImageScorer) scoreTracking(metrics ImageMetrics, score *ImageScore) float64 {
 	elongation := metrics.Elongation
@@ -374,8 +432,24 @@ func (s *ImageScorer) scoreExposure(metrics ImageMetrics, score *ImageScore) flo
 		})
 	}
 
-	// Check for saturation
-	if metrics.MaxADU >= maxADU*0.98 {
+	// Check for saturation. A few saturated bright stars are normal; with
+	// no star measurements, any clipped pixel counts against the exposure
+	switch {
+	case metrics.SaturatedFraction > s.config.SaturationLimit:
+		exposureScore -= 15
+		score.Feedback = append(score.Feedback, ScoringFeedback{
+			Category:   ScoreCategoryExposure,
+			Message:    "Many stars are saturated",
+			Severity:   "warning",
+			Suggestion: "Reduce exposure or gain to keep star colour and profile",
+		})
+	case metrics.SaturatedFraction > 0:
+		score.Feedback = append(score.Feedback, ScoringFeedback{
+			Category: ScoreCategoryExposure,
+			Message:  "Only the brightest stars are saturated",
+			Severity: "info",
+		})
+	case metrics.MaxADU >= maxADU*0.98:
 		exposureScore -= 15
 		score.Feedback = append(score.Feedback, ScoringFeedback{
 			Category:   ScoreCategoryExposure,
@@ -469,6 +543,21 @@ func clamp(value, min, max float64) float64 {
 	return value
 }
 
+// stackFrameXP is the XP each frame integrated into a stack earns, up to
+// maxStackXP in all
+const (
+	stackFrameXP = 2
+	maxStackXP   = 100
+)
+
+// ScoreStack scores the stacked image of a session, judged like a single
+// frame, with XP for each of the frames integrated into it
+func (s *ImageScorer) ScoreStack(metrics ImageMetrics, frames int) ImageScore {
+	score := s.ScoreImage(metrics)
+	score.XPEarned += min(frames*stackFrameXP, maxStackXP)
+	return score
+}
+
 // CalculateSessionScore aggregates scores for a session
 func CalculateSessionScore(scores []ImageScore) float64 {
 	if len(scores) == 0 {
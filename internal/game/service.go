@@ -16,11 +16,13 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/darkdragonsastro/draco-simulator/internal/database"
 	"github.com/darkdragonsastro/draco-simulator/internal/eventbus"
+	"github.com/darkdragonsastro/draco-simulator/internal/simclock"
 )
 
 // PlayerTier represents the player's progression tier
@@ -38,8 +40,9 @@ type Service struct {
 	mu sync.RWMutex
 
 	// Dependencies
-	bus eventbus.EventBus
-	db  database.Database
+	bus   eventbus.EventBus
+	db    database.Database
+	clock *simclock.Clock
 
 	// State
 	subscriptions []eventbus.SubscriptionID
@@ -85,13 +88,14 @@ type PlayerState struct {
 	Credits int        `json:"credits"`
 
 	// Statistics
-	TotalExposureTime float64   `json:"total_exposure_time"` // seconds
-	TotalImages       int       `json:"total_images"`
-	TotalSessions     int       `json:"total_sessions"`
-	BestHFR           float64   `json:"best_hfr"`      // lowest HFR achieved
-	BestGuideRMS      float64   `json:"best_guide_rms"` // lowest guide RMS
-	ObjectsImaged     []string  `json:"objects_imaged"` // DSO IDs
-	FirstLightDate    time.Time `json:"first_light_date"`
+	TotalExposureTime      float64   `json:"total_exposure_time"` // seconds
+	TotalImages            int       `json:"total_images"`
+	TotalCalibrationFrames int       `json:"total_calibration_frames"` // bias, dark and flat
+	TotalSessions          int       `json:"total_sessions"`
+	BestHFR                float64   `json:"best_hfr"`       // lowest HFR achieved
+	BestGuideRMS           float64   `json:"best_guide_rms"` // lowest guide RMS
+	ObjectsImaged          []string  `json:"objects_imaged"` // DSO IDs
+	FirstLightDate         time.Time `json:"first_light_date"`
 
 	// Achievements
 	UnlockedAchievements []string `json:"unlocked_achievements"`
@@ -99,17 +103,24 @@ type PlayerState struct {
 	// Current session
 	SessionStartTime time.Time `json:"session_start_time"`
 	SessionXPEarned  int       `json:"session_xp_earned"`
+	// Calibration frames taken this session, by image type
+	SessionCalibrationFrames map[string]int `json:"session_calibration_frames,omitempty"`
 
 	// Equipment
 	OwnedEquipment []string `json:"owned_equipment"`
 	CurrentLoadout string   `json:"current_loadout"`
 }
 
-// NewService creates a new game service
-func NewService(bus eventbus.EventBus, db database.Database) *Service {
+// NewService creates a new game service. Session times follow the given
+// simulation clock; nil follows real time.
+func NewService(bus eventbus.EventBus, db database.Database, clock *simclock.Clock) *Service {
+	if clock == nil {
+		clock = simclock.New()
+	}
 	return &Service{
 		bus:           bus,
 		db:            db,
+		clock:         clock,
 		subscriptions: make([]eventbus.SubscriptionID, 0),
 	}
 }
@@ -143,8 +154,9 @@ func (s *Service) Start(ctx context.Context) error {
 	}
 
 	// Start session
-	s.playerState.SessionStartTime = time.Now()
+	s.playerState.SessionStartTime = s.clock.Now()
 	s.playerState.SessionXPEarned = 0
+	s.playerState.SessionCalibrationFrames = make(map[string]int)
 	s.playerState.TotalSessions++
 	s.running = true
 
@@ -216,8 +228,13 @@ func (s *Service) handleExposureComplete(e eventbus.Event) {
 	imageType, _ := data["image_type"].(string)
 
 	// Award XP for taking exposures
-	if imageType == "light" || imageType == "Light" {
-		xp := int(duration * 0.1) // 0.1 XP per second of exposure
+	switch strings.ToLower(imageType) {
+	case "bias", "dark", "flat":
+		s.recordCalibrationFrame(strings.ToLower(imageType))
+	case "light":
+		// Lights the camera can calibrate earn more
+		calibration, _ := data["calibration"].(map[string]int)
+		xp := int(duration * 0.1 * calibrationMultiplier(calibration)) // 0.1 XP per second of exposure
 		if xp < 1 {
 			xp = 1
 		}
@@ -229,12 +246,60 @@ func (s *Service) handleExposureComplete(e eventbus.Event) {
 
 		// Check for first light
 		if s.playerState.FirstLightDate.IsZero() {
-			s.playerState.FirstLightDate = time.Now()
+			s.playerState.FirstLightDate = s.clock.Now()
 			s.unlockAchievement("first_light")
 		}
 	}
 }
 
+// Calibration rewards
+const (
+	calibrationFrameXP = 2 // per bias, dark or flat frame
+
+	// calibrationSetFrames of each type in one session unlock calibration_set
+	calibrationSetFrames = 10
+
+	// calibrationLibraryFrames in all unlock calibration_library
+	calibrationLibraryFrames = 500
+
+	// minMasterFrames is the fewest frames a master needs to earn the light
+	// frames it calibrates a bonus
+	minMasterFrames = 5
+)
+
+// recordCalibrationFrame awards a bias, dark or flat frame and checks the
+// calibration achievements. Must be called with the lock held.
+func (s *Service) recordCalibrationFrame(imageType string) {
+	s.awardXP(calibrationFrameXP, "calibration_frame")
+	s.playerState.TotalCalibrationFrames++
+	if s.playerState.SessionCalibrationFrames == nil {
+		s.playerState.SessionCalibrationFrames = make(map[string]int)
+	}
+	s.playerState.SessionCalibrationFrames[imageType]++
+
+	session := s.playerState.SessionCalibrationFrames
+	if min(session["bias"], session["dark"], session["flat"]) >= calibrationSetFrames {
+		s.unlockAchievement("calibration_set")
+	}
+	if s.playerState.TotalCalibrationFrames >= calibrationLibraryFrames {
+		s.unlockAchievement("calibration_library")
+	}
+}
+
+// calibrationMultiplier returns the XP multiplier for a light frame the
+// camera's calibration masters can correct, given their frame counts by
+// image type: 1.25 with a dark or a flat of at least minMasterFrames
+// frames, 1.5 with both.
+func calibrationMultiplier(masters map[string]int) float64 {
+	multiplier := 1.0
+	for _, t := range []string{"dark", "flat"} {
+		if masters[t] >= minMasterFrames {
+			multiplier += 0.25
+		}
+	}
+	return multiplier
+}
+
 func (s *Service) handleSequenceComplete(e eventbus.Event) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -547,6 +612,18 @@ func (s *Service) GetPlayerState() PlayerState {
 	return *s.playerState
 }
 
+// SessionDuration returns the simulated time elapsed in the current session,
+// or 0 when no session is running.
+func (s *Service) SessionDuration() time.Duration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.playerState == nil || !s.running {
+		return 0
+	}
+	return s.clock.Since(s.playerState.SessionStartTime)
+}
+
 // GetProgress returns progression information
 func (s *Service) GetProgress() ProgressInfo {
 	s.mu.RLock()
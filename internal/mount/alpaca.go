@@ -0,0 +1,375 @@
+package mount
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ASCOM enumerations used by the Telescope interface.
+const (
+	alpacaEquJ2000 = 2 // EquatorialCoordinateType.equJ2000
+
+	alpacaPierEast = 0 // PierSide.pierEast
+	alpacaPierWest = 1 // PierSide.pierWest
+)
+
+// alpacaTrackingRates maps tracking modes to ASCOM DriveRates.
+var alpacaTrackingRates = map[string]int{
+	"sidereal": 0,
+	"lunar":    1,
+	"solar":    2,
+}
+
+// alpacaGuideDirections maps guide directions to ASCOM GuideDirections.
+var alpacaGuideDirections = map[string]int{
+	"north": 0,
+	"south": 1,
+	"east":  2,
+	"west":  3,
+}
+
+// alpacaClientID identifies this application to Alpaca servers.
+const alpacaClientID = 4242
+
+// AlpacaDriver drives an ASCOM Alpaca Telescope device over HTTP.
+type AlpacaDriver struct {
+	baseURL string
+	number  int
+	client  *http.Client
+	txID    atomic.Uint32
+
+	mu         sync.RWMutex
+	status     MountStatus
+	jnow       bool // the mount works in the apparent place of date
+	onStatus   func(MountStatus)
+	pollCancel context.CancelFunc
+}
+
+// alpacaResponse is the envelope of every Alpaca device API response.
+type alpacaResponse struct {
+	Value        json.RawMessage `json:"Value"`
+	ErrorNumber  int             `json:"ErrorNumber"`
+	ErrorMessage string          `json:"ErrorMessage"`
+}
+
+// NewAlpacaDriver returns a driver for telescope number on the Alpaca server
+// at baseURL, e.g. "http://localhost:11111".
+func NewAlpacaDriver(baseURL string, number int) *AlpacaDriver {
+	return &AlpacaDriver{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		number:  number,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		jnow:    true,
+	}
+}
+
+// Connect connects the telescope and starts streaming its status.
+func (d *AlpacaDriver) Connect(ctx context.Context) error {
+	if err := d.put(ctx, "connected", url.Values{"Connected": {"true"}}); err != nil {
+		return err
+	}
+
+	var system int
+	if err := d.get(ctx, "equatorialsystem", &system); err == nil {
+		d.mu.Lock()
+		d.jnow = system != alpacaEquJ2000
+		d.mu.Unlock()
+	}
+
+	pollCtx, cancel := context.WithCancel(context.Background())
+	d.mu.Lock()
+	if d.pollCancel != nil {
+		d.pollCancel()
+	}
+	d.pollCancel = cancel
+	d.mu.Unlock()
+
+	d.refresh(ctx)
+	go d.poll(pollCtx)
+	return nil
+}
+
+// Disconnect stops streaming and disconnects the telescope.
+func (d *AlpacaDriver) Disconnect() error {
+	d.mu.Lock()
+	if d.pollCancel != nil {
+		d.pollCancel()
+		d.pollCancel = nil
+	}
+	d.status.Connected = false
+	d.mu.Unlock()
+
+	err := d.put(context.Background(), "connected", url.Values{"Connected": {"false"}})
+	d.publish()
+	return err
+}
+
+// GetStatus returns the most recently polled status.
+func (d *AlpacaDriver) GetStatus() MountStatus {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.status
+}
+
+// SlewTo starts an asynchronous slew to J2000 coordinates.
+func (d *AlpacaDriver) SlewTo(ctx context.Context, ra, dec float64) error {
+	ra, dec = d.toMount(ra, dec)
+	err := d.put(ctx, "slewtocoordinatesasync", url.Values{
+		"RightAscension": {formatFloat(ra)},
+		"Declination":    {formatFloat(dec)},
+	})
+	d.mu.Lock()
+	if err == nil {
+		d.status.TargetRA, d.status.TargetDec = d.fromMount(ra, dec)
+	}
+	d.mu.Unlock()
+	return d.afterCommand(err)
+}
+
+// StopSlew aborts any slew in progress.
+func (d *AlpacaDriver) StopSlew() error {
+	return d.afterCommand(d.put(context.Background(), "abortslew", nil))
+}
+
+// Sync tells the mount it is pointing at the given J2000 coordinates.
+func (d *AlpacaDriver) Sync(ra, dec float64) error {
+	ra, dec = d.toMount(ra, dec)
+	return d.afterCommand(d.put(context.Background(), "synctocoordinates", url.Values{
+		"RightAscension": {formatFloat(ra)},
+		"Declination":    {formatFloat(dec)},
+	}))
+}
+
+// SetTracking switches tracking off or to a sidereal, lunar or solar rate.
+func (d *AlpacaDriver) SetTracking(mode string) error {
+	ctx := context.Background()
+	if mode == "off" {
+		return d.afterCommand(d.put(ctx, "tracking", url.Values{"Tracking": {"false"}}))
+	}
+
+	rate, ok := alpacaTrackingRates[mode]
+	if !ok {
+		return errUnsupportedTracking
+	}
+	if err := d.put(ctx, "trackingrate", url.Values{"TrackingRate": {strconv.Itoa(rate)}}); err != nil {
+		return err
+	}
+	return d.afterCommand(d.put(ctx, "tracking", url.Values{"Tracking": {"true"}}))
+}
+
+// Park parks the mount.
+func (d *AlpacaDriver) Park() error {
+	return d.afterCommand(d.put(context.Background(), "park", nil))
+}
+
+// Unpark unparks the mount.
+func (d *AlpacaDriver) Unpark() error {
+	return d.afterCommand(d.put(context.Background(), "unpark", nil))
+}
+
+// PulseGuide sends a guide pulse; the mount runs it asynchronously.
+func (d *AlpacaDriver) PulseGuide(direction string, duration time.Duration) error {
+	dir, ok := alpacaGuideDirections[direction]
+	if !ok {
+		return errInvalidDirection
+	}
+	if duration <= 0 || duration > maxPulseDuration {
+		return errInvalidPulseDuration
+	}
+	return d.afterCommand(d.put(context.Background(), "pulseguide", url.Values{
+		"Direction": {strconv.Itoa(dir)},
+		"Duration":  {strconv.FormatInt(duration.Milliseconds(), 10)},
+	}))
+}
+
+// SetStatusHandler sets the callback that receives polled status updates.
+func (d *AlpacaDriver) SetStatusHandler(fn func(MountStatus)) {
+	d.mu.Lock()
+	d.onStatus = fn
+	d.mu.Unlock()
+}
+
+// poll refreshes the status until cancelled.
+func (d *AlpacaDriver) poll(ctx context.Context) {
+	ticker := time.NewTicker(statusPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.refresh(ctx)
+		}
+	}
+}
+
+// refresh reads the telescope's state and publishes it. Properties the
+// driver doesn't implement keep their zero value.
+func (d *AlpacaDriver) refresh(ctx context.Context) {
+	var ra, dec, alt, az, lst float64
+	var slewing, tracking, parked, guiding, connected bool
+	var side, rate int
+
+	if err := d.get(ctx, "connected", &connected); err != nil {
+		return
+	}
+	d.get(ctx, "rightascension", &ra)
+	d.get(ctx, "declination", &dec)
+	d.get(ctx, "altitude", &alt)
+	d.get(ctx, "azimuth", &az)
+	d.get(ctx, "siderealtime", &lst)
+	d.get(ctx, "slewing", &slewing)
+	d.get(ctx, "tracking", &tracking)
+	d.get(ctx, "atpark", &parked)
+	d.get(ctx, "ispulseguiding", &guiding)
+	side = -1
+	d.get(ctx, "sideofpier", &side)
+	d.get(ctx, "trackingrate", &rate)
+
+	d.mu.Lock()
+	st := &d.status
+	st.RA, st.Dec = d.fromMount(ra, dec)
+	st.ApparentRA, st.ApparentDec = d.apparent(ra, dec)
+	st.Alt, st.Az = alt, az
+	st.LST = lst
+	st.HourAngle = haOf(lst, st.ApparentRA)
+	st.IsSlewing = slewing
+	st.IsTracking = tracking
+	st.IsParked = parked
+	st.IsPulseGuiding = guiding
+	st.Connected = connected
+	st.TrackingMode = "off"
+	if tracking {
+		st.TrackingMode = "sidereal"
+		for mode, r := range alpacaTrackingRates {
+			if r == rate {
+				st.TrackingMode = mode
+			}
+		}
+	}
+	switch side {
+	case alpacaPierEast:
+		st.PierSide = pierEast
+	case alpacaPierWest:
+		st.PierSide = pierWest
+	default:
+		st.PierSide = ""
+	}
+	st.ActualRA, st.ActualDec = st.RA, st.Dec
+	d.mu.Unlock()
+
+	d.publish()
+}
+
+// publish sends the current status to the status handler.
+func (d *AlpacaDriver) publish() {
+	d.mu.RLock()
+	fn, status := d.onStatus, d.status
+	d.mu.RUnlock()
+	if fn != nil {
+		fn(status)
+	}
+}
+
+// afterCommand refreshes the status once a command succeeded so clients see
+// its effect without waiting for the next poll.
+func (d *AlpacaDriver) afterCommand(err error) error {
+	if err == nil {
+		go d.refresh(context.Background())
+	}
+	return err
+}
+
+// toMount converts J2000 coordinates to the mount's equatorial system.
+func (d *AlpacaDriver) toMount(ra, dec float64) (float64, float64) {
+	d.mu.RLock()
+	jnow := d.jnow
+	d.mu.RUnlock()
+	return toMountFrame(ra, dec, jnow, time.Now())
+}
+
+// fromMount converts the mount's coordinates to J2000. Must be called with
+// at least a read lock.
+func (d *AlpacaDriver) fromMount(ra, dec float64) (float64, float64) {
+	return fromMountFrame(ra, dec, d.jnow, time.Now())
+}
+
+// apparent returns the apparent place of the mount's coordinates. Must be
+// called with at least a read lock.
+func (d *AlpacaDriver) apparent(ra, dec float64) (float64, float64) {
+	if d.jnow {
+		return ra, dec
+	}
+	return toMountFrame(ra, dec, true, time.Now())
+}
+
+// get reads a telescope property into v.
+func (d *AlpacaDriver) get(ctx context.Context, name string, v any) error {
+	q := url.Values{}
+	q.Set("ClientID", strconv.Itoa(alpacaClientID))
+	q.Set("ClientTransactionID", strconv.FormatUint(uint64(d.txID.Add(1)), 10))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, d.endpoint(name)+"?"+q.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	return d.do(req, name, v)
+}
+
+// put invokes a telescope method or sets a property.
+func (d *AlpacaDriver) put(ctx context.Context, name string, form url.Values) error {
+	if form == nil {
+		form = url.Values{}
+	}
+	form.Set("ClientID", strconv.Itoa(alpacaClientID))
+	form.Set("ClientTransactionID", strconv.FormatUint(uint64(d.txID.Add(1)), 10))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, d.endpoint(name), strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return d.do(req, name, nil)
+}
+
+// do sends an Alpaca request and decodes the Value of the response into v.
+func (d *AlpacaDriver) do(req *http.Request, name string, v any) error {
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("alpaca %s: %w", name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("alpaca %s: server returned %d", name, resp.StatusCode)
+	}
+
+	var r alpacaResponse
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return fmt.Errorf("alpaca %s: %w", name, err)
+	}
+	if r.ErrorNumber != 0 {
+		return fmt.Errorf("alpaca %s: %s (0x%X)", name, r.ErrorMessage, r.ErrorNumber)
+	}
+	if v != nil && len(r.Value) > 0 {
+		return json.Unmarshal(r.Value, v)
+	}
+	return nil
+}
+
+func (d *AlpacaDriver) endpoint(name string) string {
+	return fmt.Sprintf("%s/api/v1/telescope/%d/%s", d.baseURL, d.number, name)
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
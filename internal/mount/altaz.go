@@ -0,0 +1,122 @@
+package mount
+
+import (
+	"math"
+
+	"github.com/darkdragonsastro/draco-simulator/internal/astrometry"
+)
+
+// Mount types, matching game.VirtualMountConfig.MountType.
+const (
+	MountTypeGEM   = "gem"
+	MountTypeEQ    = "eq"
+	MountTypeAltAz = "altaz"
+)
+
+// LimitZenithKeyhole is reported when an alt-az mount is asked to track too
+// close to the zenith, where the azimuth axis would have to spin arbitrarily fast.
+const LimitZenithKeyhole = "zenith_keyhole"
+
+// defaultZenithKeyhole is the altitude (degrees) above which an alt-az mount
+// refuses to track.
+const defaultZenithKeyhole = 87.0
+
+// earthRotation is the sidereal rotation rate in degrees per minute.
+const earthRotation = 15.041 / 60.0
+
+// isAltAz reports whether the mount is an alt-azimuth mount. Must be called
+// with at least a read lock.
+func (s *Simulator) isAltAz() bool {
+	return s.config.Mechanics.MountType == MountTypeAltAz
+}
+
+// mountType returns the configured mount type, defaulting to a GEM. Must be
+// called with at least a read lock.
+func (s *Simulator) mountType() string {
+	if s.config.Mechanics.MountType == "" {
+		return MountTypeGEM
+	}
+	return s.config.Mechanics.MountType
+}
+
+// sideFor returns the pier side a slew to the given hour angle ends on.
+// Alt-az mounts have no pier side. Must be called with at least a read lock.
+func (s *Simulator) sideFor(ha float64) string {
+	if s.isAltAz() {
+		return ""
+	}
+	return pierSideForHA(ha)
+}
+
+// mountAxes converts an apparent hour angle and declination to the mount's mechanical
+// axis angles: RA/Dec axes for equatorial mounts, azimuth/altitude for alt-az.
+// Must be called with at least a read lock.
+func (s *Simulator) mountAxes(ha, dec float64, side string) (primary, secondary float64) {
+	if s.isAltAz() {
+		alt, az := astrometry.Horizontal(ha, dec, s.config.Latitude)
+		return az, alt
+	}
+	return axesFor(ha, dec, side)
+}
+
+// fromMountAxes converts mechanical axis angles back to hour angle,
+// declination and pier side. Must be called with at least a read lock.
+func (s *Simulator) fromMountAxes(primary, secondary float64) (ha, dec float64, side string) {
+	if s.isAltAz() {
+		ha, dec = horizontalToEquatorial(secondary, primary, s.config.Latitude)
+		return ha, dec, ""
+	}
+	return haDecFor(primary, secondary)
+}
+
+// checkKeyhole refuses alt-az tracking near the zenith. Must be called with
+// at least a read lock.
+func (s *Simulator) checkKeyhole(ra, dec float64) error {
+	if !s.isAltAz() {
+		return nil
+	}
+	alt, az := s.horizontal(ra, dec)
+	if alt > s.limits.ZenithKeyhole {
+		return &LimitError{Limit: LimitZenithKeyhole, Alt: alt, Az: az, Bound: s.limits.ZenithKeyhole}
+	}
+	return nil
+}
+
+// fieldRotationRate returns how fast the field rotates in the image of an
+// alt-az mount, in degrees per minute. Equatorial mounts don't rotate the
+// field. Must be called with at least a read lock.
+func (s *Simulator) fieldRotationRate(alt, az float64) float64 {
+	if !s.isAltAz() {
+		return 0
+	}
+	cosAlt := math.Max(math.Cos(alt*deg2rad), 1e-3)
+	return earthRotation * math.Cos(s.config.Latitude*deg2rad) * math.Cos(az*deg2rad) / cosAlt
+}
+
+// altAzRates returns the axis rates, in arcsec/sec, an alt-az mount needs to
+// follow a sidereal target at the given position.
+func altAzRates(alt, az, lat float64) (altRate, azRate float64) {
+	omega := 15.041 // arcsec/sec
+	phi := lat * deg2rad
+	a := az * deg2rad
+	altRate = omega * math.Cos(phi) * math.Sin(a)
+	azRate = omega * (math.Sin(phi) - math.Cos(phi)*math.Cos(a)*math.Tan(math.Min(alt, 89.9)*deg2rad))
+	return altRate, azRate
+}
+
+// horizontalToEquatorial converts altitude/azimuth (degrees, azimuth from
+// north through east) to hour angle (hours) and declination (degrees).
+func horizontalToEquatorial(alt, az, lat float64) (ha, dec float64) {
+	altRad := alt * deg2rad
+	azRad := az * deg2rad
+	latRad := lat * deg2rad
+
+	sinDec := math.Sin(altRad)*math.Sin(latRad) + math.Cos(altRad)*math.Cos(latRad)*math.Cos(azRad)
+	sinDec = math.Max(-1, math.Min(1, sinDec))
+	dec = math.Asin(sinDec) * rad2deg
+
+	y := -math.Sin(azRad) * math.Cos(altRad)
+	x := math.Sin(altRad)*math.Cos(latRad) - math.Cos(altRad)*math.Sin(latRad)*math.Cos(azRad)
+	ha = math.Atan2(y, x) * rad2deg / 15.0
+	return wrapHA(ha), dec
+}
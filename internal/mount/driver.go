@@ -0,0 +1,101 @@
+package mount
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/darkdragonsastro/draco-simulator/internal/astrometry"
+	"github.com/darkdragonsastro/draco-simulator/internal/device"
+)
+
+// Driver controls a mount, simulated or real. Coordinates are J2000 (RA in
+// hours, Dec in degrees); drivers for mounts that work in JNow convert at the
+// boundary. Tracking modes and guide directions use the simulator's names.
+type Driver interface {
+	Connect(ctx context.Context) error
+	Disconnect() error
+	GetStatus() MountStatus
+
+	SlewTo(ctx context.Context, ra, dec float64) error
+	StopSlew() error
+	Sync(ra, dec float64) error
+	SetTracking(mode string) error
+	Park() error
+	Unpark() error
+	PulseGuide(direction string, duration time.Duration) error
+
+	// SetStatusHandler sets the callback that receives status updates as the
+	// mount moves; this is the driver's status stream.
+	SetStatusHandler(fn func(MountStatus))
+}
+
+var _ Driver = (*Simulator)(nil)
+
+// statusPollInterval is how often real-mount drivers refresh their status.
+const statusPollInterval = time.Second
+
+// NewDriver returns the driver for the profile's first enabled mount: the
+// simulator for virtual mounts, configured from the device's settings, or an
+// Alpaca or INDI client for real ones.
+func NewDriver(profile *device.EquipmentProfile, sim *Simulator) (Driver, error) {
+	dev := MountDevice(profile)
+	if dev == nil {
+		return nil, errNoMountDevice
+	}
+
+	switch dev.ConnectionType {
+	case device.ConnectionTypeVirtual:
+		sim.applyDeviceProfile(dev)
+		return sim, nil
+	case device.ConnectionTypeAlpaca:
+		baseURL, _ := dev.ConnectionConfig["base_url"].(string)
+		number, _ := configFloat(dev.ConnectionConfig, "device_number")
+		if baseURL == "" {
+			return nil, errAlpacaConfig
+		}
+		return NewAlpacaDriver(baseURL, int(number)), nil
+	case device.ConnectionTypeINDI:
+		addr, _ := dev.ConnectionConfig["server_address"].(string)
+		name, _ := dev.ConnectionConfig["device_name"].(string)
+		if addr == "" || name == "" {
+			return nil, errINDIConfig
+		}
+		return NewINDIDriver(addr, name), nil
+	default:
+		return nil, fmt.Errorf("unknown connection type: %s", dev.ConnectionType)
+	}
+}
+
+// MountDevice returns the profile's first enabled mount, or nil.
+func MountDevice(profile *device.EquipmentProfile) *device.DeviceProfile {
+	if profile == nil {
+		return nil
+	}
+	for i := range profile.Devices {
+		dev := &profile.Devices[i]
+		if dev.DeviceType == device.DeviceTypeMount && dev.Enabled {
+			return dev
+		}
+	}
+	return nil
+}
+
+// toMountFrame converts J2000 coordinates (RA in hours) to those of a mount
+// working in the apparent place of date when jnow is set.
+func toMountFrame(ra, dec float64, jnow bool, at time.Time) (float64, float64) {
+	if !jnow {
+		return ra, dec
+	}
+	appRA, appDec := astrometry.J2000ToApparent(ra*15, dec, at)
+	return appRA / 15, appDec
+}
+
+// fromMountFrame converts a mount's coordinates back to J2000.
+func fromMountFrame(ra, dec float64, jnow bool, at time.Time) (float64, float64) {
+	if !jnow {
+		return ra, dec
+	}
+	meanRA, meanDec := astrometry.ApparentToJ2000(ra*15, dec, at)
+	return wrapRA(meanRA / 15), meanDec
+}
@@ -5,4 +5,27 @@ import "errors"
 var (
 	errNotConnected = errors.New("mount not connected")
 	errParked       = errors.New("mount is parked")
+	errSlewing      = errors.New("mount is slewing")
+
+	errInvalidParkPosition = errors.New("park position out of range")
+
+	errInvalidLimits  = errors.New("invalid mount limits")
+	errFlipNotNeeded  = errors.New("meridian flip not needed on this side of the pier")
+	errNoMeridianFlip = errors.New("alt-az mounts do not meridian flip")
+
+	errInvalidSync = errors.New("invalid sync coordinates")
+	errSyncTooFar  = errors.New("sync position too far from the mount position")
+
+	errInvalidDirection     = errors.New("invalid guide direction")
+	errInvalidPulseDuration = errors.New("pulse duration out of range")
+	errInvalidGuideRate     = errors.New("guide rate out of range")
+	errInvalidBalance       = errors.New("balance must be balanced, east_heavy or west_heavy")
+
+	errInvalidTrackingRate = errors.New("tracking rate out of range")
+	errInvalidTrackBody    = errors.New("unknown body to track")
+	errUnsupportedTracking = errors.New("tracking mode not supported by this mount")
+
+	errNoMountDevice = errors.New("active profile has no enabled mount")
+	errAlpacaConfig  = errors.New("alpaca base URL is required")
+	errINDIConfig    = errors.New("INDI server address and device name are required")
 )
@@ -0,0 +1,170 @@
+package mount
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	// defaultGuideRate is the default guide rate as a fraction of sidereal.
+	defaultGuideRate = 0.5
+
+	minGuideRate     = 0.1
+	maxGuideRate     = 1.0
+	maxPulseDuration = 10 * time.Second
+
+	// pulseTick is how often an active guide pulse moves the axis.
+	pulseTick = 20 * time.Millisecond
+)
+
+// Axis indices for per-axis pulse state.
+const (
+	axisRA = iota
+	axisDec
+)
+
+// PulseGuide moves the mount in a direction ("north", "south", "east",
+// "west") at the configured guide rate for the given duration. The pulse
+// runs asynchronously; a new pulse on the same axis replaces the active one,
+// while RA and Dec pulses may run concurrently.
+func (s *Simulator) PulseGuide(direction string, duration time.Duration) error {
+	s.mu.Lock()
+	if !s.connected {
+		s.mu.Unlock()
+		return errNotConnected
+	}
+	if s.isParked {
+		s.mu.Unlock()
+		return errParked
+	}
+	if s.isSlewing {
+		s.mu.Unlock()
+		return errSlewing
+	}
+	if duration <= 0 || duration > maxPulseDuration {
+		s.mu.Unlock()
+		return errInvalidPulseDuration
+	}
+
+	var axis int
+	var rate float64 // arcsec/sec on the sky, signed
+	switch direction {
+	case "north":
+		axis, rate = axisDec, s.guideRateDec*siderealRate*arcsecPerHourRA
+	case "south":
+		axis, rate = axisDec, -s.guideRateDec*siderealRate*arcsecPerHourRA
+	case "east":
+		axis, rate = axisRA, s.guideRateRA*siderealRate*arcsecPerHourRA
+	case "west":
+		axis, rate = axisRA, -s.guideRateRA*siderealRate*arcsecPerHourRA
+	default:
+		s.mu.Unlock()
+		return errInvalidDirection
+	}
+
+	// A new pulse on a resting axis has to break static friction first
+	var stiction float64
+	if s.pulseCancel[axis] != nil {
+		s.pulseCancel[axis]()
+	} else if axis == axisRA {
+		stiction = s.config.Mechanics.RAStiction
+	} else {
+		stiction = s.config.Mechanics.DecStiction
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.pulseCancel[axis] = cancel
+	s.pulseID[axis]++
+	id := s.pulseID[axis]
+	s.guidePulses++
+	s.mu.Unlock()
+
+	s.broadcast()
+
+	go s.runPulse(ctx, axis, id, rate, duration, stiction)
+	return nil
+}
+
+// IsPulseGuiding reports whether a guide pulse is active on either axis.
+func (s *Simulator) IsPulseGuiding() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.isPulseGuiding()
+}
+
+// SetGuideRates sets the RA and Dec guide rates as fractions of sidereal.
+func (s *Simulator) SetGuideRates(ra, dec float64) error {
+	if ra < minGuideRate || ra > maxGuideRate || dec < minGuideRate || dec > maxGuideRate {
+		return errInvalidGuideRate
+	}
+
+	s.mu.Lock()
+	s.guideRateRA = ra
+	s.guideRateDec = dec
+	s.mu.Unlock()
+	s.broadcast()
+	return nil
+}
+
+// runPulse applies a guide pulse to one axis in small increments, through
+// the axis' stiction, backlash and balance.
+func (s *Simulator) runPulse(ctx context.Context, axis int, id uint64, rate float64, duration time.Duration, stiction float64) {
+	ticker := time.NewTicker(pulseTick)
+	defer ticker.Stop()
+
+	var elapsed time.Duration
+	for elapsed < duration {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			dt := pulseTick
+			if elapsed+dt > duration {
+				dt = duration - elapsed
+			}
+			elapsed += dt
+
+			s.mu.Lock()
+			s.applyGuideMotion(axis, s.guideResponse(axis, rate*dt.Seconds(), &stiction))
+			s.mu.Unlock()
+		}
+	}
+
+	s.mu.Lock()
+	if s.pulseID[axis] == id {
+		s.pulseCancel[axis] = nil
+	}
+	s.mu.Unlock()
+	s.broadcast()
+}
+
+// applyGuideMotion moves one axis by the given amount in arcseconds. Must be
+// called with the write lock held.
+func (s *Simulator) applyGuideMotion(axis int, arcsec float64) {
+	s.settle()
+	s.guideMotion[axis] += arcsec
+	if axis == axisRA {
+		s.ra = wrapRA(s.ra + arcsec/arcsecPerHourRA)
+		s.trackErr.correct(arcsec, 0)
+	} else {
+		s.dec = clampDec(s.dec + arcsec/3600.0)
+		s.trackErr.correct(0, arcsec)
+	}
+}
+
+// stopPulses cancels any active guide pulses.
+func (s *Simulator) stopPulses() {
+	s.mu.Lock()
+	for axis, cancel := range s.pulseCancel {
+		if cancel != nil {
+			cancel()
+			s.pulseCancel[axis] = nil
+		}
+	}
+	s.mu.Unlock()
+}
+
+// isPulseGuiding reports whether a pulse is active. Must be called with at
+// least a read lock.
+func (s *Simulator) isPulseGuiding() bool {
+	return s.pulseCancel[axisRA] != nil || s.pulseCancel[axisDec] != nil
+}
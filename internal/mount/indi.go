@@ -0,0 +1,469 @@
+package mount
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/darkdragonsastro/draco-simulator/internal/astrometry"
+)
+
+// indiConnectTimeout bounds how long Connect waits for the device to report
+// that it is connected.
+const indiConnectTimeout = 10 * time.Second
+
+// indiTrackModes maps tracking modes to TELESCOPE_TRACK_MODE switches.
+var indiTrackModes = map[string]string{
+	"sidereal": "TRACK_SIDEREAL",
+	"lunar":    "TRACK_LUNAR",
+	"solar":    "TRACK_SOLAR",
+}
+
+// indiGuidePulses maps guide directions to timed guide properties.
+var indiGuidePulses = map[string][2]string{
+	"north": {"TELESCOPE_TIMED_GUIDE_NS", "TIMED_GUIDE_N"},
+	"south": {"TELESCOPE_TIMED_GUIDE_NS", "TIMED_GUIDE_S"},
+	"east":  {"TELESCOPE_TIMED_GUIDE_WE", "TIMED_GUIDE_E"},
+	"west":  {"TELESCOPE_TIMED_GUIDE_WE", "TIMED_GUIDE_W"},
+}
+
+// INDIDriver drives an INDI telescope device over the INDI XML protocol.
+type INDIDriver struct {
+	addr   string
+	device string
+
+	mu       sync.RWMutex
+	conn     net.Conn
+	props    map[string]*indiVector // by property name
+	status   MountStatus
+	onStatus func(MountStatus)
+}
+
+// indiVector is a def*Vector or set*Vector message.
+type indiVector struct {
+	XMLName  xml.Name
+	Device   string        `xml:"device,attr"`
+	Name     string        `xml:"name,attr"`
+	State    string        `xml:"state,attr"`
+	Elements []indiElement `xml:",any"`
+}
+
+// indiElement is one member of a vector, e.g. oneNumber or defSwitch.
+type indiElement struct {
+	XMLName xml.Name
+	Name    string `xml:"name,attr"`
+	Value   string `xml:",chardata"`
+}
+
+// NewINDIDriver returns a driver for the named telescope on the INDI server
+// at addr, e.g. "localhost:7624".
+func NewINDIDriver(addr, device string) *INDIDriver {
+	return &INDIDriver{
+		addr:   addr,
+		device: device,
+		props:  make(map[string]*indiVector),
+	}
+}
+
+// Connect opens the server connection, asks the device to connect and waits
+// until it reports that it has.
+func (d *INDIDriver) Connect(ctx context.Context) error {
+	dialer := net.Dialer{Timeout: indiConnectTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", d.addr)
+	if err != nil {
+		return fmt.Errorf("cannot connect to INDI server: %w", err)
+	}
+
+	d.mu.Lock()
+	if d.conn != nil {
+		d.conn.Close()
+	}
+	d.conn = conn
+	d.props = make(map[string]*indiVector)
+	d.mu.Unlock()
+
+	go d.read(conn)
+
+	if err := d.send(fmt.Sprintf("<getProperties version=\"1.7\" device=\"%s\"/>", indiEscape(d.device))); err != nil {
+		return err
+	}
+	if err := d.newSwitch("CONNECTION", "CONNECT"); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, indiConnectTimeout)
+	defer cancel()
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		if d.switchOn("CONNECTION", "CONNECT") {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("INDI device %s did not connect: %w", d.device, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// Disconnect asks the device to disconnect and closes the server connection.
+func (d *INDIDriver) Disconnect() error {
+	err := d.newSwitch("CONNECTION", "DISCONNECT")
+
+	d.mu.Lock()
+	if d.conn != nil {
+		d.conn.Close()
+		d.conn = nil
+	}
+	d.status.Connected = false
+	d.mu.Unlock()
+
+	d.publish()
+	return err
+}
+
+// GetStatus returns the status built from the latest property updates.
+func (d *INDIDriver) GetStatus() MountStatus {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.status
+}
+
+// SlewTo slews to J2000 coordinates and tracks on arrival.
+func (d *INDIDriver) SlewTo(_ context.Context, ra, dec float64) error {
+	if err := d.newSwitch("ON_COORD_SET", "TRACK"); err != nil {
+		return err
+	}
+	if err := d.gotoCoords(ra, dec); err != nil {
+		return err
+	}
+	d.mu.Lock()
+	d.status.TargetRA, d.status.TargetDec = ra, dec
+	d.mu.Unlock()
+	return nil
+}
+
+// StopSlew aborts any motion.
+func (d *INDIDriver) StopSlew() error {
+	return d.newSwitch("TELESCOPE_ABORT_MOTION", "ABORT")
+}
+
+// Sync tells the mount it is pointing at the given J2000 coordinates.
+func (d *INDIDriver) Sync(ra, dec float64) error {
+	if err := d.newSwitch("ON_COORD_SET", "SYNC"); err != nil {
+		return err
+	}
+	return d.gotoCoords(ra, dec)
+}
+
+// SetTracking switches tracking off or to a sidereal, lunar or solar rate.
+func (d *INDIDriver) SetTracking(mode string) error {
+	if mode == "off" {
+		return d.newSwitch("TELESCOPE_TRACK_STATE", "TRACK_OFF")
+	}
+
+	sw, ok := indiTrackModes[mode]
+	if !ok {
+		return errUnsupportedTracking
+	}
+	if err := d.newSwitch("TELESCOPE_TRACK_MODE", sw); err != nil {
+		return err
+	}
+	return d.newSwitch("TELESCOPE_TRACK_STATE", "TRACK_ON")
+}
+
+// Park parks the mount.
+func (d *INDIDriver) Park() error {
+	return d.newSwitch("TELESCOPE_PARK", "PARK")
+}
+
+// Unpark unparks the mount.
+func (d *INDIDriver) Unpark() error {
+	return d.newSwitch("TELESCOPE_PARK", "UNPARK")
+}
+
+// PulseGuide sends a timed guide pulse.
+func (d *INDIDriver) PulseGuide(direction string, duration time.Duration) error {
+	pulse, ok := indiGuidePulses[direction]
+	if !ok {
+		return errInvalidDirection
+	}
+	if duration <= 0 || duration > maxPulseDuration {
+		return errInvalidPulseDuration
+	}
+	return d.newNumber(pulse[0], [][2]string{{pulse[1], strconv.FormatInt(duration.Milliseconds(), 10)}})
+}
+
+// SetStatusHandler sets the callback that receives status updates as the
+// device reports property changes.
+func (d *INDIDriver) SetStatusHandler(fn func(MountStatus)) {
+	d.mu.Lock()
+	d.onStatus = fn
+	d.mu.Unlock()
+}
+
+// gotoCoords sends coordinates in the frame the device supports, preferring
+// the JNow EQUATORIAL_EOD_COORD.
+func (d *INDIDriver) gotoCoords(ra, dec float64) error {
+	d.mu.RLock()
+	_, j2000Only := d.props["EQUATORIAL_COORD"]
+	_, eod := d.props["EQUATORIAL_EOD_COORD"]
+	d.mu.RUnlock()
+
+	prop, jnow := "EQUATORIAL_EOD_COORD", true
+	if j2000Only && !eod {
+		prop, jnow = "EQUATORIAL_COORD", false
+	}
+	ra, dec = toMountFrame(ra, dec, jnow, time.Now())
+	return d.newNumber(prop, [][2]string{{"RA", formatFloat(ra)}, {"DEC", formatFloat(dec)}})
+}
+
+// read consumes property messages until the connection closes.
+func (d *INDIDriver) read(conn net.Conn) {
+	dec := xml.NewDecoder(conn)
+	dec.Strict = false
+
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			d.mu.Lock()
+			if d.conn == conn {
+				d.conn = nil
+				d.status.Connected = false
+			}
+			d.mu.Unlock()
+			d.publish()
+			return
+		}
+
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		name := start.Name.Local
+		if !strings.HasSuffix(name, "Vector") || !(strings.HasPrefix(name, "def") || strings.HasPrefix(name, "set")) {
+			dec.Skip()
+			continue
+		}
+
+		var v indiVector
+		if err := dec.DecodeElement(&v, &start); err != nil || v.Device != d.device {
+			continue
+		}
+		d.update(&v)
+	}
+}
+
+// update merges a property message into the known properties and rebuilds
+// the status.
+func (d *INDIDriver) update(v *indiVector) {
+	d.mu.Lock()
+	prop, ok := d.props[v.Name]
+	if !ok || strings.HasPrefix(v.XMLName.Local, "def") {
+		prop = &indiVector{Name: v.Name, Device: v.Device}
+		d.props[v.Name] = prop
+	}
+	if v.State != "" {
+		prop.State = v.State
+	}
+	for _, el := range v.Elements {
+		el.Value = strings.TrimSpace(el.Value)
+		replaced := false
+		for i := range prop.Elements {
+			if prop.Elements[i].Name == el.Name {
+				prop.Elements[i].Value = el.Value
+				replaced = true
+			}
+		}
+		if !replaced {
+			prop.Elements = append(prop.Elements, el)
+		}
+	}
+	d.buildStatus()
+	d.mu.Unlock()
+
+	d.publish()
+}
+
+// buildStatus derives the mount status from the known properties. Must be
+// called with the write lock held.
+func (d *INDIDriver) buildStatus() {
+	st := &d.status
+	now := time.Now()
+
+	ra, raOK := d.number("EQUATORIAL_EOD_COORD", "RA")
+	dec, _ := d.number("EQUATORIAL_EOD_COORD", "DEC")
+	jnow := true
+	if !raOK {
+		ra, raOK = d.number("EQUATORIAL_COORD", "RA")
+		dec, _ = d.number("EQUATORIAL_COORD", "DEC")
+		jnow = false
+	}
+	if raOK {
+		st.RA, st.Dec = fromMountFrame(ra, dec, jnow, now)
+		st.ApparentRA, st.ApparentDec = toMountFrame(st.RA, st.Dec, true, now)
+		st.ActualRA, st.ActualDec = st.RA, st.Dec
+	}
+
+	lat, _ := d.number("GEOGRAPHIC_COORD", "LAT")
+	lon, _ := d.number("GEOGRAPHIC_COORD", "LONG")
+	if lon > 180 {
+		lon -= 360 // INDI longitudes run 0-360 east
+	}
+	st.LST = astrometry.LocalSiderealTime(now, lon)
+	st.HourAngle = haOf(st.LST, st.ApparentRA)
+	if alt, ok := d.number("HORIZONTAL_COORD", "ALT"); ok {
+		st.Alt = alt
+		st.Az, _ = d.number("HORIZONTAL_COORD", "AZ")
+	} else {
+		st.Alt, st.Az = astrometry.Horizontal(st.HourAngle, st.ApparentDec, lat)
+	}
+
+	st.Connected = d.conn != nil && d.switchOnLocked("CONNECTION", "CONNECT")
+	st.IsSlewing = d.state("EQUATORIAL_EOD_COORD") == "Busy" || d.state("EQUATORIAL_COORD") == "Busy"
+	st.IsParked = d.switchOnLocked("TELESCOPE_PARK", "PARK")
+	st.IsTracking = d.switchOnLocked("TELESCOPE_TRACK_STATE", "TRACK_ON")
+	st.IsPulseGuiding = d.state("TELESCOPE_TIMED_GUIDE_NS") == "Busy" || d.state("TELESCOPE_TIMED_GUIDE_WE") == "Busy"
+
+	st.TrackingMode = "off"
+	if st.IsTracking {
+		st.TrackingMode = "sidereal"
+		for mode, sw := range indiTrackModes {
+			if d.switchOnLocked("TELESCOPE_TRACK_MODE", sw) {
+				st.TrackingMode = mode
+			}
+		}
+	}
+
+	switch {
+	case d.switchOnLocked("TELESCOPE_PIER_SIDE", "PIER_EAST"):
+		st.PierSide = pierEast
+	case d.switchOnLocked("TELESCOPE_PIER_SIDE", "PIER_WEST"):
+		st.PierSide = pierWest
+	default:
+		st.PierSide = ""
+	}
+}
+
+// publish sends the current status to the status handler.
+func (d *INDIDriver) publish() {
+	d.mu.RLock()
+	fn, status := d.onStatus, d.status
+	d.mu.RUnlock()
+	if fn != nil {
+		fn(status)
+	}
+}
+
+// number returns a numeric element. Must be called with at least a read lock.
+func (d *INDIDriver) number(prop, element string) (float64, bool) {
+	v, ok := d.element(prop, element)
+	if !ok {
+		return 0, false
+	}
+	f, err := parseSexagesimal(v)
+	return f, err == nil
+}
+
+// state returns a property's state. Must be called with at least a read lock.
+func (d *INDIDriver) state(prop string) string {
+	if p, ok := d.props[prop]; ok {
+		return p.State
+	}
+	return ""
+}
+
+// switchOn reports whether a switch element is on.
+func (d *INDIDriver) switchOn(prop, element string) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.switchOnLocked(prop, element)
+}
+
+// switchOnLocked reports whether a switch element is on. Must be called with
+// at least a read lock.
+func (d *INDIDriver) switchOnLocked(prop, element string) bool {
+	v, ok := d.element(prop, element)
+	return ok && v == "On"
+}
+
+// element returns an element's value. Must be called with at least a read lock.
+func (d *INDIDriver) element(prop, element string) (string, bool) {
+	p, ok := d.props[prop]
+	if !ok {
+		return "", false
+	}
+	for _, el := range p.Elements {
+		if el.Name == element {
+			return el.Value, true
+		}
+	}
+	return "", false
+}
+
+// newSwitch turns on one switch of a one-of-many switch vector.
+func (d *INDIDriver) newSwitch(prop, element string) error {
+	return d.send(fmt.Sprintf("<newSwitchVector device=\"%s\" name=\"%s\"><oneSwitch name=\"%s\">On</oneSwitch></newSwitchVector>",
+		indiEscape(d.device), prop, element))
+}
+
+// newNumber sets the elements of a number vector, in order.
+func (d *INDIDriver) newNumber(prop string, values [][2]string) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "<newNumberVector device=\"%s\" name=\"%s\">", indiEscape(d.device), prop)
+	for _, kv := range values {
+		fmt.Fprintf(&b, "<oneNumber name=\"%s\">%s</oneNumber>", kv[0], kv[1])
+	}
+	b.WriteString("</newNumberVector>")
+	return d.send(b.String())
+}
+
+// send writes a message to the server.
+func (d *INDIDriver) send(msg string) error {
+	d.mu.RLock()
+	conn := d.conn
+	d.mu.RUnlock()
+	if conn == nil {
+		return errNotConnected
+	}
+	_, err := conn.Write([]byte(msg + "\n"))
+	return err
+}
+
+// indiEscape escapes a string for use in an XML attribute.
+func indiEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// parseSexagesimal parses INDI numbers, which may be decimal or sexagesimal
+// such as "12:30:00" or "-5 30 00".
+func parseSexagesimal(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	fields := strings.FieldsFunc(s, func(r rune) bool { return r == ':' || r == ' ' || r == ';' })
+	if len(fields) <= 1 {
+		return strconv.ParseFloat(s, 64)
+	}
+
+	negative := strings.HasPrefix(s, "-")
+	var value float64
+	for i, f := range fields {
+		v, err := strconv.ParseFloat(f, 64)
+		if err != nil {
+			return 0, err
+		}
+		value += math.Abs(v) / math.Pow(60, float64(i))
+	}
+	if negative {
+		value = -value
+	}
+	return value, nil
+}
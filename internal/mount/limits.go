@@ -0,0 +1,197 @@
+package mount
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/darkdragonsastro/draco-simulator/internal/game"
+)
+
+// Limit kinds reported by LimitError.
+const (
+	LimitAltitudeMin   = "altitude_min"
+	LimitAltitudeMax   = "altitude_max"
+	LimitHorizon       = "horizon"
+	LimitCounterweight = "counterweight_up"
+)
+
+// EventLimitReached is published when a slew or tracking is stopped by a limit.
+const EventLimitReached = "mount.limit.reached"
+
+// defaultCounterweightUp is how far (degrees) the counterweight may rise above
+// horizontal before motion is refused.
+const defaultCounterweightUp = 10.0
+
+// LimitError reports a position that violates a mount safety limit.
+type LimitError struct {
+	Limit string  `json:"limit"` // one of the Limit* kinds
+	Alt   float64 `json:"alt"`   // degrees, position that was refused
+	Az    float64 `json:"az"`    // degrees
+	Bound float64 `json:"bound"` // the limit value that was exceeded
+}
+
+func (e *LimitError) Error() string {
+	switch e.Limit {
+	case LimitAltitudeMin:
+		return fmt.Sprintf("altitude %.1f° is below the minimum of %.1f°", e.Alt, e.Bound)
+	case LimitAltitudeMax:
+		return fmt.Sprintf("altitude %.1f° is above the maximum of %.1f°", e.Alt, e.Bound)
+	case LimitHorizon:
+		return fmt.Sprintf("altitude %.1f° is below the horizon (%.1f° at azimuth %.1f°)", e.Alt, e.Bound, e.Az)
+	case LimitCounterweight:
+		return fmt.Sprintf("counterweight would rise more than %.1f° above horizontal", e.Bound)
+	case LimitZenithKeyhole:
+		return fmt.Sprintf("altitude %.1f° is inside the zenith keyhole (above %.1f°)", e.Alt, e.Bound)
+	default:
+		return "mount limit exceeded"
+	}
+}
+
+// HorizonPoint is one vertex of a local horizon profile.
+type HorizonPoint struct {
+	Az  float64 `json:"az"`  // degrees
+	Alt float64 `json:"alt"` // degrees
+}
+
+// Limits holds the mount's safety limits.
+type Limits struct {
+	AltitudeMin     float64        `json:"altitude_min"`      // degrees
+	AltitudeMax     float64        `json:"altitude_max"`      // degrees
+	Horizon         []HorizonPoint `json:"horizon,omitempty"` // local obstructions, sorted by azimuth
+	CounterweightUp float64        `json:"counterweight_up"`  // degrees above horizontal
+	ZenithKeyhole   float64        `json:"zenith_keyhole"`    // alt-az only: max tracking altitude
+}
+
+// limitsFromMechanics derives default limits from the equipped mount.
+func limitsFromMechanics(mech game.VirtualMountConfig) Limits {
+	limits := Limits{
+		AltitudeMin:     mech.AltitudeMin,
+		AltitudeMax:     mech.AltitudeMax,
+		CounterweightUp: defaultCounterweightUp,
+		ZenithKeyhole:   defaultZenithKeyhole,
+	}
+	if limits.AltitudeMax <= limits.AltitudeMin {
+		limits.AltitudeMax = 90
+	}
+	return limits
+}
+
+// GetLimits returns the current safety limits.
+func (s *Simulator) GetLimits() Limits {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.limits
+}
+
+// SetLimits replaces the safety limits. The horizon profile is sorted by azimuth.
+func (s *Simulator) SetLimits(limits Limits) error {
+	if limits.AltitudeMin < -90 || limits.AltitudeMax > 90 || limits.AltitudeMin >= limits.AltitudeMax {
+		return errInvalidLimits
+	}
+	if limits.CounterweightUp < 0 {
+		return errInvalidLimits
+	}
+	if limits.ZenithKeyhole == 0 {
+		limits.ZenithKeyhole = defaultZenithKeyhole
+	}
+	if limits.ZenithKeyhole < 0 || limits.ZenithKeyhole > 90 {
+		return errInvalidLimits
+	}
+	for _, p := range limits.Horizon {
+		if p.Az < 0 || p.Az >= 360 || p.Alt < -90 || p.Alt > 90 {
+			return errInvalidLimits
+		}
+	}
+
+	horizon := make([]HorizonPoint, len(limits.Horizon))
+	copy(horizon, limits.Horizon)
+	sort.Slice(horizon, func(i, j int) bool { return horizon[i].Az < horizon[j].Az })
+	limits.Horizon = horizon
+
+	s.mu.Lock()
+	s.limits = limits
+	s.mu.Unlock()
+	return nil
+}
+
+// checkPosition verifies that the mount may point at ra/dec on the given pier
+// side. Must be called with at least a read lock.
+func (s *Simulator) checkPosition(ra, dec float64, side string) error {
+	if err := s.checkAltitude(ra, dec); err != nil {
+		return err
+	}
+	return s.checkCounterweight(ra, dec, side)
+}
+
+// checkAltitude verifies the altitude limits and horizon profile. Must be
+// called with at least a read lock.
+func (s *Simulator) checkAltitude(ra, dec float64) error {
+	alt, az := s.horizontal(ra, dec)
+
+	if alt > s.limits.AltitudeMax {
+		return &LimitError{Limit: LimitAltitudeMax, Alt: alt, Az: az, Bound: s.limits.AltitudeMax}
+	}
+	if alt < s.limits.AltitudeMin {
+		return &LimitError{Limit: LimitAltitudeMin, Alt: alt, Az: az, Bound: s.limits.AltitudeMin}
+	}
+	if h := s.limits.horizonAltitude(az); alt < h {
+		return &LimitError{Limit: LimitHorizon, Alt: alt, Az: az, Bound: h}
+	}
+	return nil
+}
+
+// checkCounterweight verifies the counterweight-up limit. Alt-az mounts have
+// no counterweight. Must be called with at least a read lock.
+func (s *Simulator) checkCounterweight(ra, dec float64, side string) error {
+	if s.isAltAz() {
+		return nil
+	}
+	ha := s.hourAngleOf(ra, dec)
+	if up := counterweightAngle(ha, side); up > s.limits.CounterweightUp {
+		return &LimitError{Limit: LimitCounterweight, Bound: s.limits.CounterweightUp}
+	}
+	return nil
+}
+
+// horizonAltitude interpolates the horizon profile at the given azimuth.
+// Returns -90 when no profile is set.
+func (l Limits) horizonAltitude(az float64) float64 {
+	n := len(l.Horizon)
+	if n == 0 {
+		return -90
+	}
+	if n == 1 {
+		return l.Horizon[0].Alt
+	}
+
+	// Find the segment containing az, wrapping from the last point to the first
+	for i := 0; i < n; i++ {
+		a := l.Horizon[i]
+		b := l.Horizon[(i+1)%n]
+		span := b.Az - a.Az
+		if span <= 0 {
+			span += 360
+		}
+		offset := az - a.Az
+		if offset < 0 {
+			offset += 360
+		}
+		if offset <= span {
+			return lerp(a.Alt, b.Alt, offset/span)
+		}
+	}
+	return l.Horizon[0].Alt
+}
+
+// counterweightAngle returns how far the counterweight shaft is above
+// horizontal, in degrees, for a target at the given hour angle on the given
+// pier side. A GEM on the east side is counterweight-down for HA 0..12h.
+func counterweightAngle(ha float64, side string) float64 {
+	if side == pierEast && ha < 0 {
+		return -ha * 15.0
+	}
+	if side == pierWest && ha > 0 {
+		return ha * 15.0
+	}
+	return 0
+}
@@ -0,0 +1,90 @@
+package mount
+
+import "math"
+
+// Balance describes which side of the RA axis is heavier, which decides
+// whether the RA gear stays meshed while guiding.
+const (
+	BalanceBalanced  = "balanced"   // the gear floats in its backlash
+	BalanceEastHeavy = "east_heavy" // the motor always lifts the load: crisp corrections
+	BalanceWestHeavy = "west_heavy" // the load runs ahead of the motor
+)
+
+// SetBalance sets the RA balance. A slightly east-heavy mount keeps the RA
+// worm loaded against the tracking direction, so guide corrections take
+// effect as commanded; balanced or west-heavy mounts lose or overshoot RA
+// corrections in proportion to the mount's BalanceEffect.
+func (s *Simulator) SetBalance(balance string) error {
+	switch balance {
+	case BalanceBalanced, BalanceEastHeavy, BalanceWestHeavy:
+	default:
+		return errInvalidBalance
+	}
+
+	s.mu.Lock()
+	s.balance = balance
+	s.mu.Unlock()
+	s.broadcast()
+	return nil
+}
+
+// guideResponse returns how far an axis really moves, in arcseconds, when
+// a guide pulse commands it to move arcsec. stuck is the stiction left to
+// overcome in this pulse and is reduced as it is used up. Must be called
+// with the write lock held.
+func (s *Simulator) guideResponse(axis int, arcsec float64, stuck *float64) float64 {
+	// Static friction swallows the start of a pulse from rest
+	if *stuck > 0 {
+		used := math.Min(*stuck, math.Abs(arcsec))
+		*stuck -= used
+		arcsec = math.Copysign(math.Abs(arcsec)-used, arcsec)
+	}
+
+	if axis == axisRA {
+		return arcsec * s.balanceFactor(arcsec)
+	}
+	return s.takeUpDecBacklash(arcsec)
+}
+
+// balanceFactor scales an RA correction for the mount's balance. East
+// corrections (positive) slow the RA motor, which a west-heavy load partly
+// ignores; west corrections speed it up and the load carries them further.
+// Must be called with at least a read lock.
+func (s *Simulator) balanceFactor(arcsec float64) float64 {
+	k := s.config.Mechanics.BalanceEffect
+	switch s.balance {
+	case BalanceWestHeavy:
+		if arcsec > 0 {
+			return 1 - k
+		}
+		return 1 + k
+	case BalanceBalanced:
+		return 1 - k/2
+	default:
+		return 1
+	}
+}
+
+// takeUpDecBacklash moves the Dec motor by arcsec and returns how far the
+// axis follows. The motor first has to cross the gear play whenever it
+// reverses, so the first corrections after a reversal do nothing. Must be
+// called with the write lock held.
+func (s *Simulator) takeUpDecBacklash(arcsec float64) float64 {
+	backlash := s.config.Mechanics.DecBacklash
+	if backlash <= 0 {
+		return arcsec
+	}
+
+	// decPlay is where the motor sits within the play: 0 pressed south,
+	// backlash pressed north
+	play := s.decPlay + arcsec
+	var moved float64
+	switch {
+	case play > backlash:
+		moved, play = play-backlash, backlash
+	case play < 0:
+		moved, play = play, 0
+	}
+	s.decPlay = play
+	return moved
+}
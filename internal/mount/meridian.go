@@ -0,0 +1,140 @@
+package mount
+
+const (
+	pierEast = "east" // counterweights west, scope looking west (HA >= 0)
+	pierWest = "west" // counterweights east, scope looking east (HA < 0)
+
+	// meridianWarning is how many minutes before the limit an approaching
+	// event is published.
+	meridianWarning = 10.0
+)
+
+// Mount event types published through the event handler.
+const (
+	EventMeridianApproaching   = "mount.meridian.approaching"
+	EventMeridianLimit         = "mount.meridian.limit"
+	EventMeridianFlipStarted   = "mount.meridian.flip.started"
+	EventMeridianFlipCompleted = "mount.meridian.flip.completed"
+)
+
+// SetEventHandler sets the callback used to publish mount events such as
+// meridian limits and flips.
+func (s *Simulator) SetEventHandler(fn func(eventType string, data any)) {
+	s.mu.Lock()
+	s.onEvent = fn
+	s.mu.Unlock()
+}
+
+// FlipMeridian re-slews the mount to its current coordinates on the other
+// side of the pier. It is only allowed when the target has crossed the
+// meridian, i.e. when the mount is on the west side with a positive hour angle.
+func (s *Simulator) FlipMeridian() error {
+	s.mu.Lock()
+	if !s.connected {
+		s.mu.Unlock()
+		return errNotConnected
+	}
+	if s.isParked {
+		s.mu.Unlock()
+		return errParked
+	}
+	if s.isSlewing {
+		s.mu.Unlock()
+		return errSlewing
+	}
+	if s.isAltAz() {
+		s.mu.Unlock()
+		return errNoMeridianFlip
+	}
+
+	newSide := pierSideForHA(s.hourAngle())
+	if newSide == s.pierSide {
+		s.mu.Unlock()
+		return errFlipNotNeeded
+	}
+
+	// The RA axis turns through 180 degrees while Dec passes over the pole
+	s.settle()
+	plan := s.planSlew(s.ra, s.dec, newSide)
+	plan.flip = true
+	s.beginSlew(plan)
+	s.mu.Unlock()
+
+	s.emit(EventMeridianFlipStarted, map[string]any{
+		"from":     pierSideOpposite(newSide),
+		"to":       newSide,
+		"duration": plan.duration(),
+	})
+	s.broadcast()
+	return nil
+}
+
+// checkMeridianLimit stops tracking once a west-side mount tracks past the
+// meridian limit. Must be called with the write lock held; returns the event
+// to publish, if any, once the lock is released.
+func (s *Simulator) checkMeridianLimit() (event string, data map[string]any) {
+	if s.pierSide != pierWest || s.isSlewing {
+		return "", nil
+	}
+
+	minutesPast := s.hourAngle() * 60.0
+	remaining := s.config.MeridianLimit - minutesPast
+
+	if remaining <= 0 {
+		s.isTracking = false
+		s.trackingMode = "off"
+		return EventMeridianLimit, map[string]any{
+			"minutes_past_meridian": minutesPast,
+			"limit":                 s.config.MeridianLimit,
+		}
+	}
+
+	if remaining <= meridianWarning && !s.meridianWarned {
+		s.meridianWarned = true
+		return EventMeridianApproaching, map[string]any{
+			"minutes_to_limit": remaining,
+		}
+	}
+
+	return "", nil
+}
+
+// minutesToMeridianLimit returns minutes until the meridian limit is reached,
+// or 0 if the mount is on the east side. Must be called with at least a read lock.
+func (s *Simulator) minutesToMeridianLimit(ha float64) float64 {
+	if s.pierSide != pierWest {
+		return 0
+	}
+	return s.config.MeridianLimit - ha*60.0
+}
+
+// hourAngle returns the current apparent hour angle in hours (-12 to +12).
+// Must be called with at least a read lock.
+func (s *Simulator) hourAngle() float64 {
+	return s.hourAngleOf(s.position())
+}
+
+// emit publishes a mount event. Must be called without holding the lock.
+func (s *Simulator) emit(eventType string, data any) {
+	s.mu.RLock()
+	fn := s.onEvent
+	s.mu.RUnlock()
+	if fn != nil {
+		fn(eventType, data)
+	}
+}
+
+// pierSideForHA returns the normal pier side for a target at the given hour angle.
+func pierSideForHA(ha float64) string {
+	if ha < 0 {
+		return pierWest
+	}
+	return pierEast
+}
+
+func pierSideOpposite(side string) string {
+	if side == pierEast {
+		return pierWest
+	}
+	return pierEast
+}
@@ -0,0 +1,301 @@
+package mount
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// defaultSlewAccel is the axis acceleration used when the equipped mount
+// doesn't specify one, in degrees/sec^2.
+const defaultSlewAccel = 2.0
+
+// Slew event types published through the event handler.
+const (
+	EventSlewStarted   = "mount.slew.started"
+	EventSlewCompleted = "mount.slew.completed"
+)
+
+// axisMove is a trapezoidal move of a single mount axis: constant
+// acceleration up to the maximum speed, a cruise phase, then constant
+// deceleration. Short moves never reach full speed and are triangular.
+type axisMove struct {
+	start, end float64 // degrees
+	accel      float64 // degrees/sec^2
+	vmax       float64 // degrees/sec, peak speed actually reached
+	tAccel     float64 // seconds spent accelerating (and decelerating)
+	duration   float64 // seconds
+}
+
+// newAxisMove plans a move from start to end with the given speed and acceleration.
+func newAxisMove(start, end, vmax, accel float64) axisMove {
+	m := axisMove{start: start, end: end, accel: accel, vmax: vmax}
+
+	dist := math.Abs(end - start)
+	if dist == 0 {
+		return m
+	}
+
+	m.tAccel = vmax / accel
+	distAccel := 0.5 * accel * m.tAccel * m.tAccel
+	if 2*distAccel >= dist {
+		// Triangular profile: decelerate before reaching full speed
+		m.tAccel = math.Sqrt(dist / accel)
+		m.vmax = accel * m.tAccel
+		m.duration = 2 * m.tAccel
+	} else {
+		m.duration = 2*m.tAccel + (dist-2*distAccel)/vmax
+	}
+	return m
+}
+
+// position returns the axis angle t seconds into the move.
+func (m axisMove) position(t float64) float64 {
+	if t >= m.duration {
+		return m.end
+	}
+	if t <= 0 {
+		return m.start
+	}
+
+	dist := math.Abs(m.end - m.start)
+	var x float64
+	switch {
+	case t < m.tAccel:
+		x = 0.5 * m.accel * t * t
+	case t < m.duration-m.tAccel:
+		x = 0.5*m.accel*m.tAccel*m.tAccel + m.vmax*(t-m.tAccel)
+	default:
+		remaining := m.duration - t
+		x = dist - 0.5*m.accel*remaining*remaining
+	}
+
+	if m.end < m.start {
+		return m.start - x
+	}
+	return m.start + x
+}
+
+// slewPlan describes a slew in progress.
+type slewPlan struct {
+	targetRA  float64 // hours
+	targetDec float64 // degrees
+	side      string  // pier side at the end of the slew
+	flip      bool    // true for a meridian flip
+	park      bool    // true when slewing to the park position
+	home      bool    // true when slewing to the home position
+
+	primary   axisMove // RA axis, or azimuth on an alt-az mount
+	secondary axisMove // Dec axis, or altitude on an alt-az mount
+	started   time.Time
+}
+
+// duration returns the total slew time in seconds; the slower axis decides.
+func (p *slewPlan) duration() float64 {
+	return math.Max(p.primary.duration, p.secondary.duration)
+}
+
+// remaining returns the seconds left until the slew completes.
+func (p *slewPlan) remaining() float64 {
+	return math.Max(0, p.duration()-time.Since(p.started).Seconds())
+}
+
+// planSlew plans a slew from the current position to ra/dec on the given pier
+// side. The target hour angle is taken at the predicted arrival time so the
+// mount lands on the target rather than where it was when the slew began.
+// Must be called with at least a read lock.
+func (s *Simulator) planSlew(ra, dec float64, side string) *slewPlan {
+	// The axes follow the apparent place; the sky turns about the true pole
+	lst := s.lst()
+	startRA, startDec := s.apparentPlace(s.ra, s.dec)
+	appRA, appDec := s.apparentPlace(ra, dec)
+	startPrimary, startSecondary := s.mountAxes(haOf(lst, startRA), startDec, s.pierSide)
+	vmax, accel := s.slewSpeed(), s.slewAccel()
+	rate := s.clock.Rate()
+
+	plan := &slewPlan{targetRA: ra, targetDec: dec, side: side, started: time.Now()}
+
+	var eta float64
+	for i := 0; i < 2; i++ {
+		// Sidereal time advances ~1.0027 hours per solar hour of simulated time
+		ha := haOf(lst+eta*rate*siderealPerSolar/3600.0, appRA)
+		endPrimary, endSecondary := s.mountAxes(ha, appDec, side)
+		if s.isAltAz() {
+			// Azimuth takes the short way round
+			endPrimary = startPrimary + wrapDegrees180(endPrimary-startPrimary)
+		}
+		plan.primary = newAxisMove(startPrimary, endPrimary, vmax, accel)
+		plan.secondary = newAxisMove(startSecondary, endSecondary, vmax, accel)
+		eta = plan.duration()
+	}
+	return plan
+}
+
+// beginSlew starts executing a slew plan, replacing any slew in progress.
+// Must be called with the write lock held.
+func (s *Simulator) beginSlew(plan *slewPlan) {
+	if s.slewCancel != nil {
+		s.slewCancel()
+	}
+
+	s.targetRA = plan.targetRA
+	s.targetDec = plan.targetDec
+	s.isSlewing = true
+	s.isFlipping = plan.flip
+	s.isParking = plan.park
+	s.isHoming = plan.home
+	s.atHome = false
+	s.slew = plan
+
+	// Use background context so the goroutine outlives the HTTP request
+	ctx, cancel := context.WithCancel(context.Background())
+	s.slewCancel = cancel
+
+	go s.runSlew(ctx, plan)
+}
+
+// runSlew moves both axes along their motion profiles until the slew completes.
+func (s *Simulator) runSlew(ctx context.Context, plan *slewPlan) {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			t := time.Since(plan.started).Seconds()
+
+			s.mu.Lock()
+			if t >= plan.duration() {
+				s.settle()
+				s.ra = plan.targetRA
+				s.dec = plan.targetDec
+				if plan.park || plan.home {
+					// Fixed axes: the position is wherever they point now
+					ha, appDec, _ := s.fromMountAxes(plan.primary.end, plan.secondary.end)
+					s.ra, s.dec = s.meanPlace(wrapRA(s.lst()-ha), appDec)
+					s.targetRA, s.targetDec = s.ra, s.dec
+				}
+				s.pierSide = plan.side
+				s.meridianWarned = false
+				s.fieldRotation = 0
+				s.isSlewing = false
+				s.isFlipping = false
+				s.isParking = false
+				s.isHoming = false
+				s.isParked = plan.park
+				s.atHome = plan.home
+				s.slewCancel = nil
+				s.slew = nil
+				s.trackErr.reset()
+				park := s.park
+				s.mu.Unlock()
+
+				switch {
+				case plan.park:
+					s.emit(EventParked, map[string]any{"alt": park.Alt, "az": park.Az})
+					s.broadcast()
+					return
+				case plan.home:
+					s.emit(EventHomeFound, map[string]any{"pier_side": plan.side})
+					s.broadcast()
+					return
+				case plan.flip:
+					s.emit(EventMeridianFlipCompleted, map[string]any{"pier_side": plan.side})
+				}
+				s.emit(EventSlewCompleted, map[string]any{
+					"ra":        plan.targetRA,
+					"dec":       plan.targetDec,
+					"pier_side": plan.side,
+					"duration":  plan.duration(),
+				})
+				s.broadcast()
+				return
+			}
+
+			ha, appDec, side := s.fromMountAxes(plan.primary.position(t), plan.secondary.position(t))
+			ra, dec := s.meanPlace(wrapRA(s.lst()-ha), appDec)
+
+			// Stop short if the path crosses an altitude limit; the park and
+			// home positions are mechanical and always reachable
+			if err := s.checkAltitude(ra, dec); err != nil && !plan.park && !plan.home {
+				s.settle()
+				s.isSlewing = false
+				s.isFlipping = false
+				s.slewCancel = nil
+				s.slew = nil
+				s.mu.Unlock()
+				s.emit(EventLimitReached, err)
+				s.broadcast()
+				return
+			}
+
+			s.ra, s.dec, s.pierSide = ra, dec, side
+			s.mu.Unlock()
+
+			s.broadcast()
+		}
+	}
+}
+
+// slewSpeed returns the maximum axis speed in degrees/sec. Must be called
+// with at least a read lock.
+func (s *Simulator) slewSpeed() float64 {
+	if s.config.Mechanics.MaxSlewSpeed > 0 {
+		return s.config.Mechanics.MaxSlewSpeed
+	}
+	return s.config.SlewRate
+}
+
+// slewAccel returns the axis acceleration in degrees/sec^2. Must be called
+// with at least a read lock.
+func (s *Simulator) slewAccel() float64 {
+	if s.config.Mechanics.SlewAccel > 0 {
+		return s.config.Mechanics.SlewAccel
+	}
+	return defaultSlewAccel
+}
+
+// axesFor converts an hour angle, declination and pier side to mechanical
+// axis angles in degrees. The RA axis is 0 with the counterweight straight
+// down and beyond ±90 with the counterweight above horizontal. The Dec axis
+// equals the declination on the east side and passes over the pole (90) to
+// reach the west side.
+func axesFor(ha, dec float64, side string) (raAxis, decAxis float64) {
+	if side == pierWest {
+		raAxis = (ha + 6) * 15
+		decAxis = 180 - dec
+	} else {
+		raAxis = (ha - 6) * 15
+		decAxis = dec
+	}
+	return wrapDegrees180(raAxis), decAxis
+}
+
+// haDecFor converts mechanical axis angles back to hour angle, declination
+// and pier side.
+func haDecFor(raAxis, decAxis float64) (ha, dec float64, side string) {
+	if decAxis > 90 {
+		side = pierWest
+		dec = 180 - decAxis
+		ha = raAxis/15 - 6
+	} else {
+		side = pierEast
+		dec = decAxis
+		ha = raAxis/15 + 6
+	}
+	return wrapHA(ha), dec, side
+}
+
+// wrapDegrees180 wraps an angle into (-180, 180].
+func wrapDegrees180(deg float64) float64 {
+	deg = math.Mod(deg, 360)
+	if deg > 180 {
+		deg -= 360
+	}
+	if deg <= -180 {
+		deg += 360
+	}
+	return deg
+}
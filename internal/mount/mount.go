@@ -5,6 +5,11 @@ import (
 	"math"
 	"sync"
 	"time"
+
+	"github.com/darkdragonsastro/draco-simulator/internal/astrometry"
+	"github.com/darkdragonsastro/draco-simulator/internal/catalog"
+	"github.com/darkdragonsastro/draco-simulator/internal/game"
+	"github.com/darkdragonsastro/draco-simulator/internal/simclock"
 )
 
 // MountStatus represents the current state of the mount.
@@ -19,16 +24,55 @@ type MountStatus struct {
 	IsSlewing  bool `json:"is_slewing"`
 	IsTracking bool `json:"is_tracking"`
 	IsParked   bool `json:"is_parked"`
+	IsFlipping bool `json:"is_flipping"`
+	IsParking  bool `json:"is_parking"` // slewing to the park position
+	IsHoming   bool `json:"is_homing"`  // slewing to the home position
+	AtHome     bool `json:"at_home"`    // at the home position and not moved since
+
+	ParkAlt float64 `json:"park_alt"` // degrees
+	ParkAz  float64 `json:"park_az"`  // degrees
+
+	IsPulseGuiding bool    `json:"is_pulse_guiding"`
+	GuideRateRA    float64 `json:"guide_rate_ra"`  // fraction of sidereal
+	GuideRateDec   float64 `json:"guide_rate_dec"` // fraction of sidereal
+	Balance        string  `json:"balance"`        // "balanced"|"east_heavy"|"west_heavy"
+
+	TrackingMode string `json:"tracking_mode"` // "off"|"sidereal"|"lunar"|"solar"|"body"
+	TrackBody    string `json:"track_body,omitempty"` // body followed in "body" mode
+
+	RightAscensionRate float64 `json:"right_ascension_rate"` // RA offset, seconds of RA per sidereal second
+	DeclinationRate    float64 `json:"declination_rate"`     // Dec offset, arcsec per second
 
-	TrackingMode string `json:"tracking_mode"` // "off"|"sidereal"|"lunar"|"solar"
-	PierSide     string `json:"pier_side"`     // "east"|"west"
+	PierSide     string `json:"pier_side"`     // "east"|"west", empty for alt-az
+	MountType    string `json:"mount_type"`    // "gem"|"eq"|"altaz"
 	SlewRate     float64 `json:"slew_rate"`    // deg/sec
+	SlewAccel    float64 `json:"slew_accel"`   // deg/sec^2
+	SlewETA      float64 `json:"slew_eta"`     // seconds until the active slew completes
 
 	HourAngle float64 `json:"hour_angle"` // hours
 	LST       float64 `json:"lst"`        // hours
 
-	RAAxisDeg  float64 `json:"ra_axis_deg"`  // physical RA axis angle for 3D model
-	DecAxisDeg float64 `json:"dec_axis_deg"` // physical Dec axis angle for 3D model
+	MeridianLimit          float64 `json:"meridian_limit"`            // minutes past meridian
+	MinutesToMeridianLimit float64 `json:"minutes_to_meridian_limit"` // 0 when on the east side
+
+	RAAxisDeg  float64 `json:"ra_axis_deg"`  // mechanical RA axis angle (azimuth on alt-az), follows the slew profile
+	DecAxisDeg float64 `json:"dec_axis_deg"` // mechanical Dec axis angle (altitude on alt-az), follows the slew profile
+
+	FieldRotationRate float64 `json:"field_rotation_rate"` // alt-az only: degrees per minute
+	FieldRotation     float64 `json:"field_rotation"`      // alt-az only: degrees accumulated while tracking
+	AltRate           float64 `json:"alt_rate"`            // alt-az only: altitude axis tracking rate, arcsec/sec
+	AzRate            float64 `json:"az_rate"`             // alt-az only: azimuth axis tracking rate, arcsec/sec
+
+	TrackingErrorRA  float64 `json:"tracking_error_ra"`  // arcsec
+	TrackingErrorDec float64 `json:"tracking_error_dec"` // arcsec
+
+	ApparentRA  float64 `json:"apparent_ra"`  // hours, apparent place of date (JNow)
+	ApparentDec float64 `json:"apparent_dec"` // degrees, apparent place of date (JNow)
+
+	ActualRA       float64 `json:"actual_ra"`       // hours, where the optics really point
+	ActualDec      float64 `json:"actual_dec"`      // degrees
+	PointingError  float64 `json:"pointing_error"`  // arcsec between reported and actual position
+	AlignmentStars int     `json:"alignment_stars"` // sync points in the pointing model
 
 	Connected bool `json:"connected"`
 }
@@ -37,7 +81,31 @@ type MountStatus struct {
 type Config struct {
 	Latitude  float64 // observer latitude in degrees
 	Longitude float64 // observer longitude in degrees
-	SlewRate  float64 // degrees per second (default 8)
+	SlewRate  float64 // degrees per second (default 8), used when Mechanics has no MaxSlewSpeed
+
+	// MeridianLimit is how many minutes past the meridian a west-side mount
+	// may track before tracking is stopped and a flip is required.
+	MeridianLimit float64
+
+	// Mechanics describes the mount's tracking imperfections
+	Mechanics game.VirtualMountConfig
+
+	// PointingErrors are the systematic errors of the simulated mechanics,
+	// which GOTO alignment has to model away
+	PointingErrors PointingModel
+
+	// Clock supplies simulated time; nil follows real time
+	Clock *simclock.Clock
+
+	// Atmosphere drives refraction of the reported altitude; a zero pressure
+	// means the standard atmosphere at sea level
+	Atmosphere astrometry.Atmosphere
+
+	// Park is the park position; the zero value parks at the celestial pole
+	Park ParkPosition
+
+	// Balance is the initial RA balance (default BalanceBalanced)
+	Balance string
 }
 
 // DefaultConfig returns default LA observatory config.
@@ -46,6 +114,11 @@ func DefaultConfig() Config {
 		Latitude:  34.0522,
 		Longitude: -118.2437,
 		SlewRate:  8.0,
+
+		MeridianLimit: 5.0,
+
+		// A typical roughly polar-aligned setup: GOTOs land within ~10'
+		PointingErrors: PointingModel{IH: 240, ID: -180, CH: 90, NP: 30, MA: 300, ME: -200, TF: 20},
 	}
 }
 
@@ -53,19 +126,56 @@ func DefaultConfig() Config {
 type Simulator struct {
 	mu     sync.RWMutex
 	config Config
+	clock  *simclock.Clock
 
 	ra, dec       float64 // current position (hours, degrees)
+	settled       time.Time // simulated time ra was last brought up to date; see position
 	targetRA      float64
 	targetDec     float64
 	isSlewing     bool
 	isTracking    bool
 	isParked      bool
+	isParking     bool
+	isHoming      bool
+	atHome        bool
+	park          ParkPosition
 	trackingMode  string
 	connected     bool
 
+	pierSide       string
+	isFlipping     bool
+	meridianWarned bool
+	fieldRotation  float64 // alt-az: degrees accumulated while tracking
+
+	trackErr *trackingErrorModel
+	limits   Limits
+
+	raRate    float64 // ASCOM RightAscensionRate offset
+	decRate   float64 // ASCOM DeclinationRate offset
+	trackBody catalog.SolarSystemBody
+
+	syncPoints    []SyncPoint
+	pointingModel PointingModel // fitted from syncPoints
+	pointingTerms []string
+	pointingRMS   float64
+
+	guideRateRA  float64 // fraction of sidereal
+	guideRateDec float64
+	balance      string  // BalanceBalanced, BalanceEastHeavy or BalanceWestHeavy
+	decPlay      float64 // arcsec, Dec motor position within the gear backlash
+	pulseCancel  [2]context.CancelFunc // indexed by axisRA/axisDec
+	pulseID      [2]uint64
+
+	telemetry     *TelemetryRecorder
+	telemetryDone chan struct{}
+	guidePulses   int        // pulses started since the last telemetry sample
+	guideMotion   [2]float64 // arcsec guided per axis since the last telemetry sample
+
+	slew       *slewPlan // active slew, nil when idle
 	slewCancel context.CancelFunc
 
 	onStatusChanged func(MountStatus)
+	onEvent         func(eventType string, data any)
 	trackingDone    chan struct{}
 }
 
@@ -74,33 +184,78 @@ func NewSimulator(config Config, onStatusChanged func(MountStatus)) *Simulator {
 	if config.SlewRate <= 0 {
 		config.SlewRate = 8.0
 	}
+	if config.Clock == nil {
+		config.Clock = simclock.New()
+	}
+	if config.Atmosphere.Pressure <= 0 {
+		config.Atmosphere = astrometry.StandardAtmosphere(0)
+	}
+	if config.Park == (ParkPosition{}) {
+		config.Park = polePark(config.Latitude)
+	}
+	if config.Balance == "" {
+		config.Balance = BalanceBalanced
+	}
 	return &Simulator{
 		config:          config,
+		clock:           config.Clock,
 		ra:              0,
 		dec:             90, // parked at pole
 		isParked:        true,
+		settled:         config.Clock.Now(),
+		park:            config.Park,
 		trackingMode:    "off",
+		pierSide:        pierEast,
+		trackErr:        newTrackingErrorModel(config.Mechanics),
+		limits:          limitsFromMechanics(config.Mechanics),
+		guideRateRA:     defaultGuideRate,
+		guideRateDec:    defaultGuideRate,
+		balance:         config.Balance,
+		telemetry:       NewTelemetryRecorder(defaultTelemetryCapacity),
 		onStatusChanged: onStatusChanged,
 	}
 }
 
-// Connect sets the mount as connected.
-func (s *Simulator) Connect() {
+// SetMechanics replaces the mount's tracking characteristics, e.g. when the
+// player equips a different mount. Accumulated tracking error is cleared and
+// the altitude limits follow the new mount; the horizon profile is kept.
+func (s *Simulator) SetMechanics(mech game.VirtualMountConfig) {
+	s.mu.Lock()
+	s.config.Mechanics = mech
+	s.pierSide = s.sideFor(s.hourAngle())
+	s.fieldRotation = 0
+	s.trackErr = newTrackingErrorModel(mech)
+	s.decPlay = math.Min(s.decPlay, mech.DecBacklash)
+	limits := limitsFromMechanics(mech)
+	limits.Horizon = s.limits.Horizon
+	limits.CounterweightUp = s.limits.CounterweightUp
+	s.limits = limits
+	s.mu.Unlock()
+	s.broadcast()
+}
+
+// Connect sets the mount as connected and starts recording telemetry.
+func (s *Simulator) Connect(_ context.Context) error {
 	s.mu.Lock()
 	s.connected = true
 	s.mu.Unlock()
+	s.startTelemetry()
 	s.broadcast()
+	return nil
 }
 
 // Disconnect disconnects the mount, stopping everything.
-func (s *Simulator) Disconnect() {
+func (s *Simulator) Disconnect() error {
 	s.StopSlew()
 	s.stopTracking()
+	s.stopPulses()
+	s.stopTelemetry()
 
 	s.mu.Lock()
 	s.connected = false
 	s.mu.Unlock()
 	s.broadcast()
+	return nil
 }
 
 // GetStatus returns the current mount status.
@@ -121,54 +276,91 @@ func (s *Simulator) SlewTo(_ context.Context, ra, dec float64) error {
 		s.mu.Unlock()
 		return errParked
 	}
-
-	// Cancel any existing slew
-	if s.slewCancel != nil {
-		s.slewCancel()
+	if s.isFlipping || s.isParking || s.isHoming {
+		s.mu.Unlock()
+		return errSlewing
 	}
 
-	s.targetRA = ra
-	s.targetDec = dec
-	s.isSlewing = true
+	s.settle()
 
-	// Use background context so the goroutine outlives the HTTP request
-	slewCtx, cancel := context.WithCancel(context.Background())
-	s.slewCancel = cancel
+	// Refuse targets outside the safety limits
+	side := s.sideFor(s.hourAngleOf(ra, dec))
+	if err := s.checkPosition(ra, dec, side); err != nil {
+		s.mu.Unlock()
+		return err
+	}
+
+	plan := s.planSlew(ra, dec, side)
+	s.beginSlew(plan)
 	s.mu.Unlock()
 
+	s.emit(EventSlewStarted, map[string]any{
+		"ra":        ra,
+		"dec":       dec,
+		"pier_side": side,
+		"eta":       plan.duration(),
+	})
 	s.broadcast()
-
-	go s.runSlew(slewCtx, ra, dec)
 	return nil
 }
 
 // StopSlew cancels any active slew.
-func (s *Simulator) StopSlew() {
+func (s *Simulator) StopSlew() error {
 	s.mu.Lock()
 	if s.slewCancel != nil {
 		s.slewCancel()
 		s.slewCancel = nil
 	}
+	s.settle()
 	s.isSlewing = false
+	s.isFlipping = false
+	s.isParking = false
+	s.isHoming = false
+	s.slew = nil
 	s.mu.Unlock()
 	s.broadcast()
+	return nil
 }
 
-// SetTracking sets the tracking mode.
-func (s *Simulator) SetTracking(mode string) {
+// SetTracking sets the tracking mode. Alt-az mounts refuse to track near
+// the zenith keyhole.
+func (s *Simulator) SetTracking(mode string) error {
 	s.mu.Lock()
-	if !s.connected || s.isParked {
+	if !s.connected {
 		s.mu.Unlock()
-		return
+		return errNotConnected
+	}
+	if s.isParked {
+		s.mu.Unlock()
+		return errParked
+	}
+	if s.isParking {
+		s.mu.Unlock()
+		return errSlewing
+	}
+	if mode == "body" && s.trackBody == "" {
+		s.mu.Unlock()
+		return errInvalidTrackBody
+	}
+	s.settle()
+	if mode != "off" {
+		if err := s.checkKeyhole(s.ra, s.dec); err != nil {
+			s.mu.Unlock()
+			return err
+		}
 	}
 
 	oldMode := s.trackingMode
 	s.trackingMode = mode
+	if mode != "body" {
+		s.trackBody = ""
+	}
 
 	if mode == "off" {
 		s.isTracking = false
 	} else {
 		s.isTracking = true
+		s.atHome = false
 	}
 	s.mu.Unlock()
 
@@ -183,102 +375,74 @@ func (s *Simulator) SetTracking(mode string) {
 	}
 
 	s.broadcast()
+	return nil
 }
 
 // Jog nudges the mount in a direction at the given rate (deg/sec).
-func (s *Simulator) Jog(direction string, rate float64) {
+func (s *Simulator) Jog(direction string, rate float64) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if !s.connected || s.isParked {
-		return
+	if !s.connected {
+		return errNotConnected
+	}
+	if s.isParked {
+		return errParked
 	}
 
 	// Apply a small nudge (equivalent to 0.5 seconds of motion)
 	nudge := rate * 0.5
 
+	s.settle()
+	ra, dec := s.ra, s.dec
 	switch direction {
 	case "north":
-		s.dec = clampDec(s.dec + nudge)
+		dec = clampDec(dec + nudge)
 	case "south":
-		s.dec = clampDec(s.dec - nudge)
+		dec = clampDec(dec - nudge)
 	case "east":
-		s.ra = wrapRA(s.ra + nudge/15.0) // convert deg to hours
+		ra = wrapRA(ra + nudge/15.0) // convert deg to hours
 	case "west":
-		s.ra = wrapRA(s.ra - nudge/15.0)
+		ra = wrapRA(ra - nudge/15.0)
+	}
+
+	if err := s.checkPosition(ra, dec, s.pierSide); err != nil {
+		return err
 	}
+	s.ra, s.dec = ra, dec
+	s.atHome = false
 
 	// broadcast without holding lock
 	status := s.buildStatus()
-	go s.onStatusChanged(status)
-}
-
-// Park moves the mount to park position.
-func (s *Simulator) Park() {
-	s.StopSlew()
-	s.stopTracking()
-
-	s.mu.Lock()
-	s.ra = 0
-	s.dec = 90
-	s.isParked = true
-	s.isTracking = false
-	s.trackingMode = "off"
-	s.mu.Unlock()
-	s.broadcast()
+	if s.onStatusChanged != nil {
+		go s.onStatusChanged(status)
+	}
+	return nil
 }
 
-// Unpark enables the mount for use.
-func (s *Simulator) Unpark() {
+// Unpark enables the mount for use. It stays pointing at the park position,
+// which the sky has moved past while the mount was parked.
+func (s *Simulator) Unpark() error {
 	s.mu.Lock()
 	if !s.connected {
 		s.mu.Unlock()
-		return
+		return errNotConnected
+	}
+	if s.isParking {
+		s.mu.Unlock()
+		return errSlewing
+	}
+	s.settle()
+	if s.isParked {
+		ha, _ := s.parkHADec()
+		s.ra, s.dec = s.parkedPosition()
+		s.targetRA, s.targetDec = s.ra, s.dec
+		s.pierSide = s.sideFor(ha)
 	}
 	s.isParked = false
 	s.mu.Unlock()
 	s.broadcast()
-}
-
-// runSlew interpolates the mount along a great-circle arc to the target.
-func (s *Simulator) runSlew(ctx context.Context, targetRA, targetDec float64) {
-	ticker := time.NewTicker(100 * time.Millisecond)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case <-ticker.C:
-			s.mu.Lock()
-			// Calculate angular distance remaining
-			dist := angularDistance(s.ra*15.0, s.dec, targetRA*15.0, targetDec) // convert RA hours to degrees
-
-			if dist < 0.01 { // close enough
-				s.ra = targetRA
-				s.dec = targetDec
-				s.isSlewing = false
-				s.slewCancel = nil
-				s.mu.Unlock()
-				s.broadcast()
-				return
-			}
-
-			// Move along great circle at slew rate
-			step := s.config.SlewRate * 0.1 // degrees per tick (100ms)
-			frac := step / dist
-			if frac > 1.0 {
-				frac = 1.0
-			}
-
-			// Interpolate RA (in hours) and Dec (in degrees)
-			s.ra = lerpRA(s.ra, targetRA, frac)
-			s.dec = lerp(s.dec, targetDec, frac)
-			s.mu.Unlock()
-
-			s.broadcast()
-		}
-	}
+	return nil
 }
 
 func (s *Simulator) startTracking() {
@@ -287,11 +451,13 @@ func (s *Simulator) startTracking() {
 	s.mu.Lock()
 	done := make(chan struct{})
 	s.trackingDone = done
+	s.trackErr.reset()
 	s.mu.Unlock()
 
 	go func() {
 		ticker := time.NewTicker(1 * time.Second)
 		defer ticker.Stop()
+		last := s.clock.Now()
 		for {
 			select {
 			case <-done:
@@ -302,11 +468,42 @@ func (s *Simulator) startTracking() {
 					s.mu.Unlock()
 					return
 				}
-				// Advance RA at sidereal rate: 15.041"/sec = 15.041/3600 deg/sec
-				// In hours: 15.041/(3600*15) hours/sec = ~0.000278 hours/sec
-				rate := s.trackingRate()
-				s.ra = wrapRA(s.ra + rate)
+				// Advance by simulated time so accelerated and paused clocks
+				// track consistently with the sky
+				now, prev := s.clock.Now(), last
+				s.settle()
+				dt := now.Sub(prev).Seconds()
+				last = now
+				if s.isSlewing || dt <= 0 {
+					// The slew profile owns the axes until it completes
+					s.mu.Unlock()
+					continue
+				}
+				moveRA, moveDec := s.trackingMotion(prev, now)
+				errRA, errDec := s.trackErr.step(dt)
+				s.ra = wrapRA(s.ra + moveRA + errRA/arcsecPerHourRA)
+				s.dec = clampDec(s.dec + moveDec + errDec/3600.0)
+				event, data := s.checkMeridianLimit()
+				limitErr := s.checkPosition(s.ra, s.dec, s.pierSide)
+				if limitErr == nil {
+					limitErr = s.checkKeyhole(s.ra, s.dec)
+				}
+				if s.isAltAz() {
+					alt, az := s.horizontal(s.ra, s.dec)
+					s.fieldRotation += s.fieldRotationRate(alt, az) * dt / 60.0
+				}
+				if limitErr != nil {
+					s.isTracking = false
+					s.trackingMode = "off"
+				}
 				s.mu.Unlock()
+
+				if event != "" {
+					s.emit(event, data)
+				}
+				if limitErr != nil {
+					s.emit(EventLimitReached, limitErr)
+				}
 				s.broadcast()
 			}
 		}
@@ -326,7 +523,7 @@ func (s *Simulator) stopTracking() {
 func (s *Simulator) trackingRate() float64 {
 	switch s.trackingMode {
 	case "sidereal":
-		return 15.041 / (3600.0 * 15.0) // sidereal rate in hours/sec
+		return siderealRate
 	case "lunar":
 		return 14.685 / (3600.0 * 15.0)
 	case "solar":
@@ -336,36 +533,71 @@ func (s *Simulator) trackingRate() float64 {
 	}
 }
 
+// position returns where the mount points now. With the drive stopped the
+// axes hold still while the sky turns past, so an untracked mount keeps its
+// hour angle and its RA follows the sidereal time from where it last
+// settled. A parked mount's position comes from its park position instead,
+// and a slewing mount's from the slew. Must be called with at least a read
+// lock.
+func (s *Simulator) position() (ra, dec float64) {
+	if s.isTracking || s.isParked || s.isSlewing || s.settled.IsZero() {
+		return s.ra, s.dec
+	}
+	dt := s.clock.Now().Sub(s.settled).Seconds()
+	return wrapRA(s.ra + siderealRate*dt), s.dec
+}
+
+// settle brings ra up to date with position. Must be called with the write
+// lock held before anything that reads or moves the axes, or starts or
+// stops them.
+func (s *Simulator) settle() {
+	s.ra, s.dec = s.position()
+	s.settled = s.clock.Now()
+}
+
 // buildStatus creates a MountStatus snapshot. Must be called with at least a read lock.
 func (s *Simulator) buildStatus() MountStatus {
-	lst := computeLST(s.config.Longitude)
-	ha := lst - s.ra
-	if ha < -12 {
-		ha += 24
-	}
-	if ha > 12 {
-		ha -= 24
+	lst := s.lst()
+
+	// A parked mount holds still while the sky turns past it
+	ra, dec := s.position()
+	if s.isParked {
+		ra, dec = s.parkedPosition()
 	}
+	ha := s.hourAngleOf(ra, dec)
+
+	alt, az := s.horizontal(ra, dec)
+	appRA, appDec := s.apparentPlace(ra, dec)
+	errRA, errDec := s.trackErr.total()
+
+	pierSide := s.pierSide
 
-	alt, az := equatorialToHorizontal(s.ra, s.dec, s.config.Latitude, lst)
+	// Mechanical axis angles for the 3D model, offset so 0 points the RA axis
+	// at the meridian and the Dec axis at the pole
+	raAxis, decAxis := axesFor(ha, dec, pierSide)
+	raAxisDeg := raAxis + 90
+	decAxisDeg := 90 - decAxis
 
-	pierSide := "east"
-	if ha < 0 {
-		pierSide = "west"
+	var altRate, azRate float64
+	if s.isAltAz() {
+		raAxisDeg, decAxisDeg = az, alt
+		if s.isTracking {
+			altRate, azRate = altAzRates(alt, az, s.config.Latitude)
+		}
 	}
 
-	// Axis angles for 3D model
-	raAxisDeg := ha * 15.0 // HA in hours to degrees
-	decAxisDeg := 90.0 - s.dec
+	actualRA, actualDec := s.actualPosition(ra, dec)
+	dRA := wrapHA(actualRA-ra) * 15 * math.Cos(dec*deg2rad)
+	pointingError := math.Hypot(dRA, actualDec-dec) * 3600
 
-	if pierSide == "west" {
-		raAxisDeg += 180
-		decAxisDeg = 180 - decAxisDeg
+	var slewETA float64
+	if s.slew != nil {
+		slewETA = s.slew.remaining()
 	}
 
 	return MountStatus{
-		RA:           s.ra,
-		Dec:          s.dec,
+		RA:           ra,
+		Dec:          dec,
 		Alt:          alt,
 		Az:           az,
 		TargetRA:     s.targetRA,
@@ -373,23 +605,70 @@ func (s *Simulator) buildStatus() MountStatus {
 		IsSlewing:    s.isSlewing,
 		IsTracking:   s.isTracking,
 		IsParked:     s.isParked,
+		IsFlipping:   s.isFlipping,
+		IsParking:    s.isParking,
+		IsHoming:     s.isHoming,
+		AtHome:       s.atHome,
+		ParkAlt:      s.park.Alt,
+		ParkAz:       s.park.Az,
 		TrackingMode: s.trackingMode,
 		PierSide:     pierSide,
-		SlewRate:     s.config.SlewRate,
+		MountType:    s.mountType(),
+		TrackBody:    string(s.trackBody),
+
+		RightAscensionRate: s.raRate,
+		DeclinationRate:    s.decRate,
+		SlewRate:     s.slewSpeed(),
+		SlewAccel:    s.slewAccel(),
+		SlewETA:      slewETA,
 		HourAngle:    ha,
 		LST:          lst,
+
+		MeridianLimit:          s.config.MeridianLimit,
+		MinutesToMeridianLimit: s.minutesToMeridianLimit(ha),
 		RAAxisDeg:    raAxisDeg,
 		DecAxisDeg:   decAxisDeg,
 		Connected:    s.connected,
+
+		IsPulseGuiding: s.isPulseGuiding(),
+		GuideRateRA:    s.guideRateRA,
+		GuideRateDec:   s.guideRateDec,
+		Balance:        s.balance,
+
+		TrackingErrorRA:  errRA,
+		TrackingErrorDec: errDec,
+
+		ApparentRA:  appRA,
+		ApparentDec: appDec,
+
+		ActualRA:       actualRA,
+		ActualDec:      actualDec,
+		PointingError:  pointingError,
+		AlignmentStars: len(s.syncPoints),
+
+		FieldRotationRate: s.fieldRotationRate(alt, az),
+		FieldRotation:     s.fieldRotation,
+		AltRate:           altRate,
+		AzRate:            azRate,
 	}
 }
 
 func (s *Simulator) broadcast() {
-	if s.onStatusChanged == nil {
+	s.mu.RLock()
+	onStatusChanged := s.onStatusChanged
+	status := s.buildStatus()
+	s.mu.RUnlock()
+	if onStatusChanged == nil {
 		return
 	}
-	status := s.GetStatus()
-	s.onStatusChanged(status)
+	onStatusChanged(status)
+}
+
+// SetStatusHandler sets the callback that receives every status update.
+func (s *Simulator) SetStatusHandler(fn func(MountStatus)) {
+	s.mu.Lock()
+	s.onStatusChanged = fn
+	s.mu.Unlock()
 }
 
 // --- Astronomy helpers ---
@@ -397,85 +676,76 @@ func (s *Simulator) broadcast() {
 const deg2rad = math.Pi / 180.0
 const rad2deg = 180.0 / math.Pi
 
-// computeLST computes approximate Local Sidereal Time in hours for the given longitude.
-func computeLST(longitude float64) float64 {
-	now := time.Now().UTC()
-	// Julian date
-	y, m, d := now.Year(), int(now.Month()), now.Day()
-	if m <= 2 {
-		y--
-		m += 12
-	}
-	a := y / 100
-	b := 2 - a + a/4
-	jd := float64(int(365.25*float64(y+4716))) + float64(int(30.6001*float64(m+1))) + float64(d) + float64(b) - 1524.5
-	jd += (float64(now.Hour()) + float64(now.Minute())/60.0 + float64(now.Second())/3600.0) / 24.0
-
-	// Greenwich Mean Sidereal Time
-	t := (jd - 2451545.0) / 36525.0
-	gmst := 280.46061837 + 360.98564736629*(jd-2451545.0) + 0.000387933*t*t - t*t*t/38710000.0
-	gmst = math.Mod(gmst, 360.0)
-	if gmst < 0 {
-		gmst += 360.0
-	}
+// lst returns the local apparent sidereal time in hours at the simulation
+// clock's current time. Must be called with at least a read lock.
+func (s *Simulator) lst() float64 {
+	return computeLST(s.clock.Now(), s.config.Longitude)
+}
 
-	lst := gmst + longitude
-	lst = math.Mod(lst, 360.0)
-	if lst < 0 {
-		lst += 360.0
-	}
+// computeLST computes the local apparent sidereal time in hours at the given time for the given longitude.
+func computeLST(at time.Time, longitude float64) float64 {
+	return astrometry.LocalSiderealTime(at, longitude)
+}
 
-	return lst / 15.0 // convert degrees to hours
+// apparentPlace converts a J2000 position (RA in hours) to the apparent place
+// of date at the simulation clock's current time. Must be called with at
+// least a read lock.
+func (s *Simulator) apparentPlace(ra, dec float64) (float64, float64) {
+	appRA, appDec := astrometry.J2000ToApparent(ra*15, dec, s.clock.Now())
+	return appRA / 15, appDec
 }
 
-// equatorialToHorizontal converts RA/Dec to Alt/Az.
-func equatorialToHorizontal(ra, dec, lat, lst float64) (alt, az float64) {
-	ha := (lst - ra) * 15.0 * deg2rad // hour angle in radians
-	decRad := dec * deg2rad
-	latRad := lat * deg2rad
+// meanPlace converts an apparent place of date back to J2000 (RA in hours).
+// Must be called with at least a read lock.
+func (s *Simulator) meanPlace(ra, dec float64) (float64, float64) {
+	meanRA, meanDec := astrometry.ApparentToJ2000(ra*15, dec, s.clock.Now())
+	return wrapRA(meanRA / 15), meanDec
+}
 
-	sinAlt := math.Sin(decRad)*math.Sin(latRad) + math.Cos(decRad)*math.Cos(latRad)*math.Cos(ha)
-	alt = math.Asin(sinAlt) * rad2deg
+// hourAngleOf returns the hour angle of a J2000 position from its apparent
+// place. Must be called with at least a read lock.
+func (s *Simulator) hourAngleOf(ra, dec float64) float64 {
+	appRA, _ := s.apparentPlace(ra, dec)
+	return haOf(s.lst(), appRA)
+}
 
-	cosA := (math.Sin(decRad) - math.Sin(alt*deg2rad)*math.Sin(latRad)) / (math.Cos(alt*deg2rad) * math.Cos(latRad))
-	cosA = math.Max(-1, math.Min(1, cosA)) // clamp for acos
-	az = math.Acos(cosA) * rad2deg
+// horizontal returns the observed altitude and azimuth of a J2000 position,
+// refracted by the configured atmosphere. Must be called with at least a read lock.
+func (s *Simulator) horizontal(ra, dec float64) (alt, az float64) {
+	return astrometry.Observed(ra*15, dec, s.clock.Now(), s.config.Latitude, s.config.Longitude, s.config.Atmosphere)
+}
 
-	if math.Sin(ha) > 0 {
-		az = 360.0 - az
+// SetAtmosphere updates the temperature and pressure used for refraction.
+func (s *Simulator) SetAtmosphere(atm astrometry.Atmosphere) {
+	if atm.Pressure <= 0 {
+		atm = astrometry.StandardAtmosphere(0)
 	}
+	s.mu.Lock()
+	s.config.Atmosphere = atm
+	s.mu.Unlock()
+	s.broadcast()
+}
 
-	return alt, az
+// haOf returns the hour angle (-12 to +12 hours) of ra at the given LST.
+func haOf(lst, ra float64) float64 {
+	return wrapHA(lst - ra)
 }
 
-// angularDistance computes great-circle distance in degrees between two points (all in degrees).
-func angularDistance(ra1, dec1, ra2, dec2 float64) float64 {
-	ra1r, dec1r := ra1*deg2rad, dec1*deg2rad
-	ra2r, dec2r := ra2*deg2rad, dec2*deg2rad
-	dra := ra2r - ra1r
-	ddec := dec2r - dec1r
-	a := math.Sin(ddec/2)*math.Sin(ddec/2) + math.Cos(dec1r)*math.Cos(dec2r)*math.Sin(dra/2)*math.Sin(dra/2)
-	c := 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
-	return c * rad2deg
+// wrapHA wraps an hour angle or hour-angle difference into -12 to +12 hours.
+func wrapHA(ha float64) float64 {
+	if ha < -12 {
+		ha += 24
+	}
+	if ha > 12 {
+		ha -= 24
+	}
+	return ha
 }
 
 func lerp(a, b, t float64) float64 {
 	return a + (b-a)*t
 }
 
-// lerpRA interpolates RA in hours, handling the 0/24 wrap.
-func lerpRA(a, b, t float64) float64 {
-	diff := b - a
-	if diff > 12 {
-		diff -= 24
-	}
-	if diff < -12 {
-		diff += 24
-	}
-	result := a + diff*t
-	return wrapRA(result)
-}
-
 func wrapRA(ra float64) float64 {
 	for ra < 0 {
 		ra += 24
@@ -0,0 +1,99 @@
+package mount
+
+import (
+	"time"
+
+	"github.com/darkdragonsastro/draco-simulator/internal/catalog"
+)
+
+const (
+	// maxRARate is the largest RightAscensionRate offset, seconds of RA per
+	// sidereal second.
+	maxRARate = 10.0
+	// maxDecRate is the largest DeclinationRate offset, arcsec per second.
+	maxDecRate = 150.0
+
+	// siderealPerSolar converts SI seconds to sidereal seconds.
+	siderealPerSolar = 1.00273790935
+)
+
+// trackableBodies are the solar system bodies the mount can follow.
+var trackableBodies = map[catalog.SolarSystemBody]bool{
+	catalog.BodyMoon:    true,
+	catalog.BodyMercury: true,
+	catalog.BodyVenus:   true,
+	catalog.BodyMars:    true,
+	catalog.BodyJupiter: true,
+	catalog.BodySaturn:  true,
+	catalog.BodyUranus:  true,
+	catalog.BodyNeptune: true,
+}
+
+// SetTrackingRates sets ASCOM-style rate offsets applied on top of the
+// tracking mode: raRate in seconds of RA per sidereal second
+// (RightAscensionRate) and decRate in arcsec per second (DeclinationRate).
+// Zero for both restores plain tracking. Offsets are ignored while
+// following a body, whose motion is taken from the ephemeris.
+func (s *Simulator) SetTrackingRates(raRate, decRate float64) error {
+	if raRate < -maxRARate || raRate > maxRARate || decRate < -maxDecRate || decRate > maxDecRate {
+		return errInvalidTrackingRate
+	}
+
+	s.mu.Lock()
+	s.raRate = raRate
+	s.decRate = decRate
+	s.mu.Unlock()
+	s.broadcast()
+	return nil
+}
+
+// TrackBody follows the Moon or a planet, holding its current offset from the
+// body so a centered target stays centered.
+func (s *Simulator) TrackBody(body string) error {
+	b := catalog.SolarSystemBody(body)
+	if !trackableBodies[b] {
+		return errInvalidTrackBody
+	}
+
+	s.mu.Lock()
+	prev := s.trackBody
+	s.trackBody = b
+	s.mu.Unlock()
+
+	if err := s.SetTracking("body"); err != nil {
+		s.mu.Lock()
+		s.trackBody = prev
+		s.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// trackingMotion returns how far the pointed RA (hours) and Dec (degrees)
+// move relative to the stars between two simulated times. Must be called with
+// at least a read lock.
+func (s *Simulator) trackingMotion(from, to time.Time) (dRA, dDec float64) {
+	if s.trackingMode == "body" {
+		eph := catalog.NewEphemeris(catalog.NewObserver(s.config.Latitude, s.config.Longitude, 0))
+		p0 := s.bodyPosition(eph, from)
+		p1 := s.bodyPosition(eph, to)
+		return wrapHA((p1.RA - p0.RA) / 15.0), p1.Dec - p0.Dec
+	}
+
+	dt := to.Sub(from).Seconds()
+
+	// Perfect sidereal tracking holds RA fixed; other rates drift relative
+	// to the stars by the difference from sidereal.
+	dRA = (siderealRate - s.trackingRate()) * dt
+	dRA += s.raRate / 3600.0 * siderealPerSolar * dt
+	dDec = s.decRate / 3600.0 * dt
+	return dRA, dDec
+}
+
+// bodyPosition returns the tracked body's position (RA in degrees).
+func (s *Simulator) bodyPosition(eph *catalog.Ephemeris, t time.Time) catalog.SolarSystemPosition {
+	if s.trackBody == catalog.BodyMoon {
+		return eph.GetMoonPosition(t)
+	}
+	return eph.GetPlanetPosition(s.trackBody, t)
+}
@@ -0,0 +1,248 @@
+package mount
+
+import (
+	"math"
+	"time"
+
+	"github.com/darkdragonsastro/draco-simulator/internal/astrometry"
+	"github.com/darkdragonsastro/draco-simulator/internal/device"
+)
+
+// Park and home event types published through the event handler.
+const (
+	EventParkStarted = "mount.park.started"
+	EventParked      = "mount.parked"
+	EventHomeStarted = "mount.home.started"
+	EventHomeFound   = "mount.home.found"
+)
+
+// homeHA is the hour angle of a GEM's home position: counterweights down,
+// scope at the pole.
+const homeHA = 6.0
+
+// poleDec is how close to the pole an equatorial mount parks or homes; 1"
+// off the pole the position still carries the hour angle of the RA axis.
+const poleDec = 90 - 1.0/3600
+
+// ParkPosition is where the mount parks, in horizontal coordinates.
+type ParkPosition struct {
+	Alt float64 `json:"alt"` // degrees
+	Az  float64 `json:"az"`  // degrees, north through east
+}
+
+// polePark returns the park position pointing at the visible celestial pole.
+func polePark(lat float64) ParkPosition {
+	if lat < 0 {
+		return ParkPosition{Alt: -lat, Az: 180}
+	}
+	return ParkPosition{Alt: lat, Az: 0}
+}
+
+// Park slews to the park position and stops. Tracking stops immediately; the
+// mount reports IsParking until the slew completes, then IsParked.
+func (s *Simulator) Park() error {
+	s.mu.Lock()
+	if !s.connected {
+		s.mu.Unlock()
+		return errNotConnected
+	}
+	if s.isParked || s.isParking {
+		s.mu.Unlock()
+		return nil
+	}
+	s.mu.Unlock()
+
+	s.stopTracking()
+	s.stopPulses()
+
+	s.mu.Lock()
+	s.settle()
+	s.isTracking = false
+	s.trackingMode = "off"
+	s.trackBody = ""
+	s.raRate, s.decRate = 0, 0
+
+	ha, dec := s.parkHADec()
+	plan := s.planFixed(ha, dec)
+	plan.park = true
+	s.beginSlew(plan)
+	park := s.park
+	s.mu.Unlock()
+
+	s.emit(EventParkStarted, map[string]any{
+		"alt": park.Alt,
+		"az":  park.Az,
+		"eta": plan.duration(),
+	})
+	s.broadcast()
+	return nil
+}
+
+// FindHome slews to the mechanical home position and stops: counterweights
+// down at the pole for equatorial mounts, level and facing north for alt-az.
+func (s *Simulator) FindHome() error {
+	s.mu.Lock()
+	if !s.connected {
+		s.mu.Unlock()
+		return errNotConnected
+	}
+	if s.isParked {
+		s.mu.Unlock()
+		return errParked
+	}
+	if s.isParking || s.isFlipping {
+		s.mu.Unlock()
+		return errSlewing
+	}
+	s.mu.Unlock()
+
+	s.stopTracking()
+	s.stopPulses()
+
+	s.mu.Lock()
+	s.settle()
+	s.isTracking = false
+	s.trackingMode = "off"
+	s.trackBody = ""
+
+	ha, dec := homeHA, math.Copysign(poleDec, s.config.Latitude)
+	if s.isAltAz() {
+		ha, dec = horizontalToEquatorial(0, 0, s.config.Latitude)
+	}
+	plan := s.planFixed(ha, dec)
+	plan.home = true
+	s.beginSlew(plan)
+	s.mu.Unlock()
+
+	s.emit(EventHomeStarted, map[string]any{"eta": plan.duration()})
+	s.broadcast()
+	return nil
+}
+
+// GetParkPosition returns the configured park position.
+func (s *Simulator) GetParkPosition() ParkPosition {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.park
+}
+
+// SetParkPosition sets where the mount parks. A parked mount must be
+// unparked first, since it sits at the old position.
+func (s *Simulator) SetParkPosition(pos ParkPosition) error {
+	if pos.Alt < 0 || pos.Alt > 90 || pos.Az < 0 || pos.Az >= 360 {
+		return errInvalidParkPosition
+	}
+
+	s.mu.Lock()
+	if s.isParked || s.isParking {
+		s.mu.Unlock()
+		return errParked
+	}
+	s.park = pos
+	s.mu.Unlock()
+	s.broadcast()
+	return nil
+}
+
+// SetParkToCurrent makes the current position the park position, like
+// ASCOM SetPark, and returns it.
+func (s *Simulator) SetParkToCurrent() (ParkPosition, error) {
+	s.mu.Lock()
+	if !s.connected {
+		s.mu.Unlock()
+		return ParkPosition{}, errNotConnected
+	}
+	if s.isParked {
+		pos := s.park
+		s.mu.Unlock()
+		return pos, nil
+	}
+	if s.isSlewing {
+		s.mu.Unlock()
+		return ParkPosition{}, errSlewing
+	}
+
+	// Geometric, like the axes: the park position is mechanical
+	appRA, appDec := s.apparentPlace(s.position())
+	alt, az := astrometry.Horizontal(haOf(s.lst(), appRA), appDec, s.config.Latitude)
+	pos := ParkPosition{Alt: math.Max(0, alt), Az: az}
+	s.park = pos
+	s.mu.Unlock()
+
+	s.broadcast()
+	return pos, nil
+}
+
+// applyDeviceProfile takes the park position from a virtual mount's profile
+// settings ("park_alt" and "park_az"). The profile describes where the mount
+// parks, so a parked mount moves there.
+func (s *Simulator) applyDeviceProfile(dev *device.DeviceProfile) {
+	alt, okAlt := configFloat(dev.ConnectionConfig, "park_alt")
+	az, okAz := configFloat(dev.ConnectionConfig, "park_az")
+	if !okAlt || !okAz || alt < 0 || alt > 90 {
+		return
+	}
+
+	s.mu.Lock()
+	s.park = ParkPosition{Alt: alt, Az: math.Mod(math.Mod(az, 360)+360, 360)}
+	s.mu.Unlock()
+	s.broadcast()
+}
+
+// parkHADec returns the apparent hour angle and declination of the park
+// position. At the pole, where the hour angle is undefined, the mount parks
+// counterweights down. Must be called with at least a read lock.
+func (s *Simulator) parkHADec() (ha, dec float64) {
+	ha, dec = horizontalToEquatorial(s.park.Alt, s.park.Az, s.config.Latitude)
+	if !s.isAltAz() && math.Abs(dec) > poleDec {
+		ha, dec = homeHA, math.Copysign(poleDec, dec)
+	}
+	return ha, dec
+}
+
+// parkedPosition returns the J2000 position of the park position now; a
+// parked mount stays fixed while the sky turns. Must be called with at least
+// a read lock.
+func (s *Simulator) parkedPosition() (ra, dec float64) {
+	ha, appDec := s.parkHADec()
+	return s.meanPlace(wrapRA(s.lst()-ha), appDec)
+}
+
+// planFixed plans a slew to a fixed apparent hour angle and declination, for
+// parking and homing. Must be called with at least a read lock.
+func (s *Simulator) planFixed(ha, dec float64) *slewPlan {
+	startRA, startDec := s.apparentPlace(s.ra, s.dec)
+	startPrimary, startSecondary := s.mountAxes(haOf(s.lst(), startRA), startDec, s.pierSide)
+
+	side := s.sideFor(ha)
+	endPrimary, endSecondary := s.mountAxes(ha, dec, side)
+	if s.isAltAz() {
+		endPrimary = startPrimary + wrapDegrees180(endPrimary-startPrimary)
+	}
+
+	vmax, accel := s.slewSpeed(), s.slewAccel()
+	plan := &slewPlan{
+		side:      side,
+		primary:   newAxisMove(startPrimary, endPrimary, vmax, accel),
+		secondary: newAxisMove(startSecondary, endSecondary, vmax, accel),
+		started:   time.Now(),
+	}
+
+	// The target is wherever the fixed axes point on arrival
+	arrival := s.lst() + plan.duration()*s.clock.Rate()*siderealPerSolar/3600.0
+	plan.targetRA, plan.targetDec = s.meanPlace(wrapRA(arrival-ha), dec)
+	return plan
+}
+
+// configFloat reads a number from a device's connection config, which holds
+// float64 after a JSON round trip and int when built in code.
+func configFloat(cfg map[string]any, key string) (float64, bool) {
+	switch v := cfg[key].(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
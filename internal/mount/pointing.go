@@ -0,0 +1,346 @@
+package mount
+
+import "math"
+
+// EventSynced is published when the mount is synced to a position.
+const EventSynced = "mount.synced"
+
+// maxSyncDistance is how far (degrees) a sync may move the reported position.
+// Larger offsets almost always mean the wrong star was centered.
+const maxSyncDistance = 10.0
+
+// Pointing model terms, in the order they are added to the fit as more
+// sync points become available.
+const (
+	termIH = iota
+	termID
+	termME
+	termMA
+	termCH
+	termNP
+	termTF
+	numTerms
+)
+
+var termNames = [numTerms]string{"IH", "ID", "ME", "MA", "CH", "NP", "TF"}
+
+// PointingModel describes systematic pointing errors of an equatorial mount.
+// All terms are in arcseconds. The same model is used both for the errors
+// injected into the simulated mechanics and for the correction fitted from
+// sync points.
+type PointingModel struct {
+	IH float64 `json:"ih"` // hour angle index error
+	ID float64 `json:"id"` // declination index error
+	CH float64 `json:"ch"` // cone error: optical axis not perpendicular to the Dec axis
+	NP float64 `json:"np"` // non-perpendicularity of the Dec and polar axes
+	MA float64 `json:"ma"` // polar axis azimuth misalignment, positive east of the pole
+	ME float64 `json:"me"` // polar axis elevation misalignment, positive above the pole
+	TF float64 `json:"tf"` // tube flexure, proportional to the sine of zenith distance
+}
+
+// SyncPoint is one star used for alignment: where the mount thought it was
+// pointing and where it was actually pointing.
+type SyncPoint struct {
+	HA          float64 `json:"ha"`           // mount position, hours
+	Dec         float64 `json:"dec"`          // mount position, degrees
+	ObservedHA  float64 `json:"observed_ha"`  // true position, hours
+	ObservedDec float64 `json:"observed_dec"` // true position, degrees
+	PierSide    string  `json:"pier_side"`
+	Residual    float64 `json:"residual"` // arcsec on the sky after the fit
+}
+
+// PointingState is a snapshot of the pointing model subsystem.
+type PointingState struct {
+	Errors PointingModel `json:"errors"` // injected mechanical errors
+	Model  PointingModel `json:"model"`  // correction fitted from sync points
+	Terms  []string      `json:"terms"`  // terms included in the fit
+	Points []SyncPoint   `json:"points"`
+	RMS    float64       `json:"rms"` // arcsec
+}
+
+// Sync tells the mount it is actually pointing at ra/dec, e.g. after a plate
+// solve. The point is added to the alignment and the pointing model is refit
+// from all sync points, so one sync corrects the index errors and each
+// further star adds terms up to a full model.
+func (s *Simulator) Sync(ra, dec float64) error {
+	s.mu.Lock()
+	if !s.connected {
+		s.mu.Unlock()
+		return errNotConnected
+	}
+	if s.isParked {
+		s.mu.Unlock()
+		return errParked
+	}
+	if s.isSlewing {
+		s.mu.Unlock()
+		return errSlewing
+	}
+	if ra < 0 || ra >= 24 || dec < -90 || dec > 90 {
+		s.mu.Unlock()
+		return errInvalidSync
+	}
+
+	s.settle()
+	lst := s.lst()
+	ha := haOf(lst, s.ra)
+	obsHA := haOf(lst, ra)
+
+	dHA := wrapHA(obsHA-ha) * 15 * math.Cos(s.dec*deg2rad)
+	if math.Hypot(dHA, dec-s.dec) > maxSyncDistance {
+		s.mu.Unlock()
+		return errSyncTooFar
+	}
+
+	// Remove the current correction to recover the raw mount position
+	dH, dD := s.pointingModel.offset(ha, s.dec, s.config.Latitude, s.pierSide)
+	rawHA := wrapHA(ha - dH/arcsecPerHourRA)
+	rawDec := s.dec - dD/3600.0
+
+	s.syncPoints = append(s.syncPoints, SyncPoint{
+		HA:          rawHA,
+		Dec:         rawDec,
+		ObservedHA:  obsHA,
+		ObservedDec: dec,
+		PierSide:    s.pierSide,
+	})
+	s.fitPointing()
+
+	// Report the raw position through the new correction
+	dH, dD = s.pointingModel.offset(rawHA, rawDec, s.config.Latitude, s.pierSide)
+	s.ra = wrapRA(lst - (rawHA + dH/arcsecPerHourRA))
+	s.dec = clampDec(rawDec + dD/3600.0)
+	s.targetRA, s.targetDec = s.ra, s.dec
+	s.trackErr.reset()
+
+	stars := len(s.syncPoints)
+	rms := s.pointingRMS
+	s.mu.Unlock()
+
+	s.emit(EventSynced, map[string]any{
+		"ra":    ra,
+		"dec":   dec,
+		"stars": stars,
+		"rms":   rms,
+	})
+	s.broadcast()
+	return nil
+}
+
+// GetPointing returns the injected errors, fitted model and sync points.
+func (s *Simulator) GetPointing() PointingState {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	points := make([]SyncPoint, len(s.syncPoints))
+	copy(points, s.syncPoints)
+	terms := make([]string, len(s.pointingTerms))
+	copy(terms, s.pointingTerms)
+
+	return PointingState{
+		Errors: s.config.PointingErrors,
+		Model:  s.pointingModel,
+		Terms:  terms,
+		Points: points,
+		RMS:    s.pointingRMS,
+	}
+}
+
+// SetPointingErrors replaces the errors injected into the simulated mechanics.
+// The existing alignment is kept so its degradation can be observed.
+func (s *Simulator) SetPointingErrors(errs PointingModel) {
+	s.mu.Lock()
+	s.config.PointingErrors = errs
+	s.mu.Unlock()
+	s.broadcast()
+}
+
+// ClearAlignment discards all sync points and the fitted model.
+func (s *Simulator) ClearAlignment() {
+	s.mu.Lock()
+	s.settle()
+	lst := s.lst()
+	ha := haOf(lst, s.ra)
+	dH, dD := s.pointingModel.offset(ha, s.dec, s.config.Latitude, s.pierSide)
+	s.ra = wrapRA(s.ra + dH/arcsecPerHourRA)
+	s.dec = clampDec(s.dec - dD/3600.0)
+
+	s.syncPoints = nil
+	s.pointingModel = PointingModel{}
+	s.pointingTerms = nil
+	s.pointingRMS = 0
+	s.mu.Unlock()
+	s.broadcast()
+}
+
+// actualPosition returns where the optical axis really points given the
+// reported position ra/dec: the injected errors apply, less whatever the
+// fitted model already corrects. Must be called with at least a read lock.
+func (s *Simulator) actualPosition(ra, dec float64) (float64, float64) {
+	lst := s.lst()
+	ha := haOf(lst, ra)
+
+	errH, errD := s.config.PointingErrors.offset(ha, dec, s.config.Latitude, s.pierSide)
+	fitH, fitD := s.pointingModel.offset(ha, dec, s.config.Latitude, s.pierSide)
+
+	return wrapRA(ra - (errH-fitH)/arcsecPerHourRA), clampDec(dec + (errD-fitD)/3600.0)
+}
+
+// fitPointing fits the pointing model to the sync points by least squares.
+// Each point contributes two equations, so terms are added in order as
+// points accumulate; terms the geometry can't constrain are dropped. Must be
+// called with the write lock held.
+func (s *Simulator) fitPointing() {
+	lat := s.config.Latitude
+	n := min(numTerms, 2*len(s.syncPoints))
+
+	var coeffs []float64
+	for ; n > 0; n-- {
+		if c, ok := solvePointing(s.syncPoints, lat, n); ok {
+			coeffs = c
+			break
+		}
+	}
+
+	var all [numTerms]float64
+	copy(all[:], coeffs)
+	s.pointingModel = modelFromCoeffs(all)
+	s.pointingTerms = append([]string(nil), termNames[:len(coeffs)]...)
+
+	var sumSq float64
+	for i := range s.syncPoints {
+		p := &s.syncPoints[i]
+		dH, dD := s.pointingModel.offset(p.HA, p.Dec, lat, p.PierSide)
+		resH := (wrapHA(p.ObservedHA-p.HA)*arcsecPerHourRA - dH) * math.Cos(p.Dec*deg2rad)
+		resD := (p.ObservedDec-p.Dec)*3600.0 - dD
+		p.Residual = math.Hypot(resH, resD)
+		sumSq += p.Residual * p.Residual
+	}
+	if len(s.syncPoints) > 0 {
+		s.pointingRMS = math.Sqrt(sumSq / float64(len(s.syncPoints)))
+	}
+}
+
+// solvePointing solves the normal equations for the first n terms. Returns
+// false if the sync points don't constrain them all.
+func solvePointing(points []SyncPoint, lat float64, n int) ([]float64, bool) {
+	ata := make([][]float64, n)
+	for i := range ata {
+		ata[i] = make([]float64, n+1) // augmented with A^T b
+	}
+
+	addRow := func(row [numTerms]float64, b float64) {
+		for i := 0; i < n; i++ {
+			for j := 0; j < n; j++ {
+				ata[i][j] += row[i] * row[j]
+			}
+			ata[i][n] += row[i] * b
+		}
+	}
+
+	for _, p := range points {
+		rowH, rowD := termPartials(p.HA, p.Dec, lat, p.PierSide)
+		// Weight the HA equation by cos(dec) so both are arcsec on the sky
+		w := math.Cos(p.Dec * deg2rad)
+		for i := range rowH {
+			rowH[i] *= w
+		}
+		dH := wrapHA(p.ObservedHA-p.HA) * arcsecPerHourRA * w
+		dD := (p.ObservedDec - p.Dec) * 3600.0
+		addRow(rowH, dH)
+		addRow(rowD, dD)
+	}
+
+	var scale float64
+	for i := 0; i < n; i++ {
+		scale = math.Max(scale, math.Abs(ata[i][i]))
+	}
+
+	// Gaussian elimination with partial pivoting
+	for col := 0; col < n; col++ {
+		pivot := col
+		for r := col + 1; r < n; r++ {
+			if math.Abs(ata[r][col]) > math.Abs(ata[pivot][col]) {
+				pivot = r
+			}
+		}
+		if math.Abs(ata[pivot][col]) <= 1e-9*scale {
+			return nil, false
+		}
+		ata[col], ata[pivot] = ata[pivot], ata[col]
+
+		for r := 0; r < n; r++ {
+			if r == col {
+				continue
+			}
+			f := ata[r][col] / ata[col][col]
+			for c := col; c <= n; c++ {
+				ata[r][c] -= f * ata[col][c]
+			}
+		}
+	}
+
+	coeffs := make([]float64, n)
+	for i := range coeffs {
+		coeffs[i] = ata[i][n] / ata[i][i]
+	}
+	return coeffs, true
+}
+
+// offset returns the pointing displacement in arcseconds of hour angle and
+// declination for a mount at the given hour angle (hours), declination
+// (degrees), latitude and pier side.
+func (m PointingModel) offset(ha, dec, lat float64, side string) (dH, dD float64) {
+	rowH, rowD := termPartials(ha, dec, lat, side)
+	c := m.coeffs()
+	for i := range c {
+		dH += rowH[i] * c[i]
+		dD += rowD[i] * c[i]
+	}
+	return dH, dD
+}
+
+// termPartials returns each term's contribution per arcsecond to the hour
+// angle and declination displacement. Index, cone and non-perpendicularity
+// errors change sign when the mount is on the west side of the pier.
+func termPartials(ha, dec, lat float64, side string) (rowH, rowD [numTerms]float64) {
+	// Keep sec/tan finite at the pole
+	dec = math.Max(-89.5, math.Min(89.5, dec))
+
+	h := ha * 15 * deg2rad
+	d := dec * deg2rad
+	phi := lat * deg2rad
+	sinH, cosH := math.Sincos(h)
+	sinD, cosD := math.Sincos(d)
+	tanD := sinD / cosD
+	secD := 1 / cosD
+
+	p := 1.0
+	if side == pierWest {
+		p = -1.0
+	}
+
+	rowH[termIH] = 1
+	rowD[termID] = p
+	rowH[termCH] = p * secD
+	rowH[termNP] = p * tanD
+	rowH[termMA], rowD[termMA] = -cosH*tanD, sinH
+	rowH[termME], rowD[termME] = sinH*tanD, cosH
+	rowH[termTF] = math.Cos(phi) * sinH * secD
+	rowD[termTF] = math.Cos(phi)*cosH*sinD - math.Sin(phi)*cosD
+	return rowH, rowD
+}
+
+func (m PointingModel) coeffs() [numTerms]float64 {
+	var c [numTerms]float64
+	c[termIH], c[termID], c[termME], c[termMA] = m.IH, m.ID, m.ME, m.MA
+	c[termCH], c[termNP], c[termTF] = m.CH, m.NP, m.TF
+	return c
+}
+
+func modelFromCoeffs(c [numTerms]float64) PointingModel {
+	return PointingModel{
+		IH: c[termIH], ID: c[termID], ME: c[termME], MA: c[termMA],
+		CH: c[termCH], NP: c[termNP], TF: c[termTF],
+	}
+}
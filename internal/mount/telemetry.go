@@ -0,0 +1,241 @@
+package mount
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// telemetryInterval is how often, in real time, the simulator records
+	// a sample: the cadence the tracking loop steps the mount at.
+	telemetryInterval = time.Second
+
+	// defaultTelemetryCapacity keeps an hour of samples, enough to cover
+	// several worm periods. Under an accelerated clock it covers Rate
+	// hours of simulated time.
+	defaultTelemetryCapacity = 3600
+)
+
+// TelemetrySample is one snapshot of the mount: where it points, where it
+// was told to point, how far tracking has wandered and how much guiding
+// pushed it back since the previous sample.
+//
+// Samples are taken once a real second, like the tracking loop's steps, so
+// under an accelerated clock each spans ClockRate simulated seconds. At
+// rates approaching the worm period the periodic error is undersampled
+// and aliases.
+type TelemetrySample struct {
+	Time      time.Time `json:"time"`       // simulation time
+	ClockRate float64   `json:"clock_rate"` // simulated seconds per real second; 0 while paused
+
+	RA        float64 `json:"ra"`  // hours
+	Dec       float64 `json:"dec"` // degrees
+	TargetRA  float64 `json:"target_ra"`
+	TargetDec float64 `json:"target_dec"`
+	Alt       float64 `json:"alt"`
+	Az        float64 `json:"az"`
+	PierSide  string  `json:"pier_side"`
+
+	IsTracking bool `json:"is_tracking"`
+	IsSlewing  bool `json:"is_slewing"`
+
+	// TrackingErrorRA/Dec are the net error after guiding; RawErrorRA/Dec
+	// are the mechanical error alone (periodic error, drift and jitter)
+	TrackingErrorRA  float64 `json:"tracking_error_ra"`  // arcsec
+	TrackingErrorDec float64 `json:"tracking_error_dec"` // arcsec
+	RawErrorRA       float64 `json:"raw_error_ra"`       // arcsec
+	RawErrorDec      float64 `json:"raw_error_dec"`      // arcsec
+
+	GuidePulses int     `json:"guide_pulses"` // pulses started since the previous sample
+	GuideRA     float64 `json:"guide_ra"`     // arcsec of RA guide motion since the previous sample
+	GuideDec    float64 `json:"guide_dec"`    // arcsec of Dec guide motion since the previous sample
+}
+
+// TelemetryRecorder keeps the most recent samples in a fixed-size ring
+// buffer; once full, each new sample replaces the oldest.
+type TelemetryRecorder struct {
+	mu      sync.RWMutex
+	samples []TelemetrySample
+	next    int
+	full    bool
+}
+
+// NewTelemetryRecorder creates a recorder holding up to capacity samples.
+func NewTelemetryRecorder(capacity int) *TelemetryRecorder {
+	if capacity <= 0 {
+		capacity = defaultTelemetryCapacity
+	}
+	return &TelemetryRecorder{samples: make([]TelemetrySample, capacity)}
+}
+
+// Record adds a sample, overwriting the oldest once the buffer is full.
+func (r *TelemetryRecorder) Record(sample TelemetrySample) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.samples[r.next] = sample
+	r.next = (r.next + 1) % len(r.samples)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// Range returns the samples with from <= Time <= to, oldest first. A zero
+// from or to leaves that end of the range open.
+func (r *TelemetryRecorder) Range(from, to time.Time) []TelemetrySample {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	start, n := 0, r.next
+	if r.full {
+		start, n = r.next, len(r.samples)
+	}
+
+	out := make([]TelemetrySample, 0, n)
+	for i := 0; i < n; i++ {
+		sample := r.samples[(start+i)%len(r.samples)]
+		if !from.IsZero() && sample.Time.Before(from) {
+			continue
+		}
+		if !to.IsZero() && sample.Time.After(to) {
+			continue
+		}
+		out = append(out, sample)
+	}
+	return out
+}
+
+// Len returns the number of samples held.
+func (r *TelemetryRecorder) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.full {
+		return len(r.samples)
+	}
+	return r.next
+}
+
+// Capacity returns the maximum number of samples held.
+func (r *TelemetryRecorder) Capacity() int {
+	return len(r.samples)
+}
+
+// Clear discards all samples.
+func (r *TelemetryRecorder) Clear() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.next = 0
+	r.full = false
+}
+
+// telemetryColumns is the CSV header, in TelemetrySample field order.
+var telemetryColumns = []string{
+	"time", "clock_rate", "ra", "dec", "target_ra", "target_dec", "alt", "az", "pier_side",
+	"is_tracking", "is_slewing",
+	"tracking_error_ra", "tracking_error_dec", "raw_error_ra", "raw_error_dec",
+	"guide_pulses", "guide_ra", "guide_dec",
+}
+
+// WriteTelemetryCSV writes samples as CSV with a header row. Times are
+// RFC 3339 with milliseconds.
+func WriteTelemetryCSV(w io.Writer, samples []TelemetrySample) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(telemetryColumns); err != nil {
+		return err
+	}
+
+	num := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+	for _, s := range samples {
+		row := []string{
+			s.Time.UTC().Format("2006-01-02T15:04:05.000Z07:00"), num(s.ClockRate),
+			num(s.RA), num(s.Dec), num(s.TargetRA), num(s.TargetDec), num(s.Alt), num(s.Az), s.PierSide,
+			strconv.FormatBool(s.IsTracking), strconv.FormatBool(s.IsSlewing),
+			num(s.TrackingErrorRA), num(s.TrackingErrorDec), num(s.RawErrorRA), num(s.RawErrorDec),
+			strconv.Itoa(s.GuidePulses), num(s.GuideRA), num(s.GuideDec),
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// Telemetry returns the mount's telemetry recorder.
+func (s *Simulator) Telemetry() *TelemetryRecorder {
+	return s.telemetry
+}
+
+// startTelemetry records a sample every telemetryInterval of real time
+// until stopTelemetry is called.
+func (s *Simulator) startTelemetry() {
+	s.stopTelemetry()
+
+	s.mu.Lock()
+	done := make(chan struct{})
+	s.telemetryDone = done
+	s.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(telemetryInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				s.mu.Lock()
+				sample := s.telemetrySample()
+				s.guidePulses = 0
+				s.guideMotion = [2]float64{}
+				s.mu.Unlock()
+				s.telemetry.Record(sample)
+			}
+		}
+	}()
+}
+
+func (s *Simulator) stopTelemetry() {
+	s.mu.Lock()
+	if s.telemetryDone != nil {
+		close(s.telemetryDone)
+		s.telemetryDone = nil
+	}
+	s.mu.Unlock()
+}
+
+// telemetrySample snapshots the mount. Must be called with at least a read
+// lock.
+func (s *Simulator) telemetrySample() TelemetrySample {
+	ra, dec := s.position()
+	if s.isParked {
+		ra, dec = s.parkedPosition()
+	}
+	alt, az := s.horizontal(ra, dec)
+	errRA, errDec := s.trackErr.total()
+	rawRA, rawDec := s.trackErr.raw()
+
+	return TelemetrySample{
+		Time:             s.clock.Now(),
+		ClockRate:        s.clock.Rate(),
+		RA:               ra,
+		Dec:              dec,
+		TargetRA:         s.targetRA,
+		TargetDec:        s.targetDec,
+		Alt:              alt,
+		Az:               az,
+		PierSide:         s.pierSide,
+		IsTracking:       s.isTracking,
+		IsSlewing:        s.isSlewing,
+		TrackingErrorRA:  errRA,
+		TrackingErrorDec: errDec,
+		RawErrorRA:       rawRA,
+		RawErrorDec:      rawDec,
+		GuidePulses:      s.guidePulses,
+		GuideRA:          s.guideMotion[axisRA],
+		GuideDec:         s.guideMotion[axisDec],
+	}
+}
@@ -0,0 +1,114 @@
+package mount
+
+import (
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/darkdragonsastro/draco-simulator/internal/game"
+)
+
+const (
+	// siderealRate is the sidereal tracking rate in RA hours per second.
+	siderealRate = 15.041 / (3600.0 * 15.0)
+
+	// wormPeriod is the period of the RA worm gear in seconds. Most
+	// amateur mounts use a 144-tooth worm wheel, giving roughly 8 minutes.
+	wormPeriod = 480.0
+
+	// pecResidual is the fraction of periodic error left after PEC playback.
+	pecResidual = 0.25
+
+	// arcsecPerHourRA converts RA hours to arcseconds.
+	arcsecPerHourRA = 15.0 * 3600.0
+)
+
+// trackingErrorModel produces the RA/Dec error a real mount accumulates while
+// tracking: a sinusoidal worm period, a steady drift caused by polar
+// misalignment and random jitter from gear mesh, wind and vibration.
+type trackingErrorModel struct {
+	cfg game.VirtualMountConfig
+	rng *rand.Rand
+
+	elapsed    float64 // seconds since the model was reset
+	wormPhase  float64 // radians, worm position when tracking started
+	driftAngle float64 // radians, direction of the polar-misalignment drift
+
+	raErr  float64 // arcsec, current mechanical RA error
+	decErr float64 // arcsec, current mechanical Dec error
+
+	corrRA  float64 // arcsec, accumulated guide corrections in RA
+	corrDec float64 // arcsec, accumulated guide corrections in Dec
+}
+
+// newTrackingErrorModel creates an error model for the given mount characteristics.
+func newTrackingErrorModel(cfg game.VirtualMountConfig) *trackingErrorModel {
+	m := &trackingErrorModel{
+		cfg: cfg,
+		rng: rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	m.reset()
+	return m
+}
+
+// reset clears accumulated error. The worm phase and drift direction are
+// re-randomized since they depend on where the gears and axes happen to be.
+func (m *trackingErrorModel) reset() {
+	m.elapsed = 0
+	m.wormPhase = m.rng.Float64() * 2 * math.Pi
+	m.driftAngle = m.rng.Float64() * 2 * math.Pi
+	m.raErr = 0
+	m.decErr = 0
+	m.corrRA = 0
+	m.corrDec = 0
+}
+
+// correct records a guide correction in arcseconds so the reported error
+// reflects where the mount actually points.
+func (m *trackingErrorModel) correct(dRA, dDec float64) {
+	m.corrRA += dRA
+	m.corrDec += dDec
+}
+
+// total returns the net RA/Dec pointing error in arcseconds, including
+// guide corrections.
+func (m *trackingErrorModel) total() (ra, dec float64) {
+	return m.raErr + m.corrRA, m.decErr + m.corrDec
+}
+
+// raw returns the mechanical RA/Dec error in arcseconds, before guide
+// corrections.
+func (m *trackingErrorModel) raw() (ra, dec float64) {
+	return m.raErr, m.decErr
+}
+
+// step advances the model by dt seconds and returns the change in RA and Dec
+// error in arcseconds since the previous step.
+func (m *trackingErrorModel) step(dt float64) (dRA, dDec float64) {
+	m.elapsed += dt
+
+	// Periodic error only affects the RA axis (worm gear)
+	amplitude := m.cfg.PeriodicError / 2
+	if m.cfg.HasPEC {
+		amplitude *= pecResidual
+	}
+	pe := amplitude * math.Sin(2*math.Pi*m.elapsed/wormPeriod+m.wormPhase)
+
+	// Polar misalignment drift grows linearly, mostly in Dec
+	drift := m.cfg.DriftRate * m.elapsed / 3600.0
+	driftRA := drift * math.Sin(m.driftAngle)
+	driftDec := drift * math.Cos(m.driftAngle)
+
+	// Jitter is an instantaneous, uncorrelated offset
+	jitterRA := m.rng.NormFloat64() * m.cfg.TrackingJitter
+	jitterDec := m.rng.NormFloat64() * m.cfg.TrackingJitter
+
+	raErr := pe + driftRA + jitterRA
+	decErr := driftDec + jitterDec
+
+	dRA = raErr - m.raErr
+	dDec = decErr - m.decErr
+	m.raErr = raErr
+	m.decErr = decErr
+	return dRA, dDec
+}
@@ -0,0 +1,213 @@
+// Package simclock provides the simulation clock shared by every subsystem
+// that needs the current time: the mount, sky and catalog handlers, and the
+// game service.
+//
+// The clock can follow real time, run at a fixed offset from it, run faster
+// or slower than real time (e.g. 60x for a time-lapse night), or be paused
+// for deterministic tests.
+package simclock
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// Mode describes how simulated time relates to real time.
+type Mode string
+
+const (
+	ModeReal        Mode = "real"        // simulated time is real time
+	ModeOffset      Mode = "offset"      // real time shifted by a fixed offset
+	ModeAccelerated Mode = "accelerated" // runs at Rate times real time
+	ModePaused      Mode = "paused"      // frozen
+)
+
+// MaxRate is the fastest the clock may run relative to real time.
+const MaxRate = 3600.0
+
+var errInvalidRate = errors.New("clock rate must be greater than 0 and at most 3600")
+
+// Clock is a simulation clock. Simulated time advances from an anchor at
+// rate times the real time elapsed since that anchor. It is safe for
+// concurrent use.
+type Clock struct {
+	mu sync.RWMutex
+
+	mode       Mode
+	rate       float64 // simulated seconds per real second
+	resumeRate float64 // rate to restore when resuming from pause
+
+	realAnchor time.Time
+	simAnchor  time.Time
+
+	realNow func() time.Time
+}
+
+// State is a snapshot of the clock.
+type State struct {
+	Mode   Mode      `json:"mode"`
+	Time   time.Time `json:"time"`
+	Rate   float64   `json:"rate"`   // simulated seconds per real second
+	Offset float64   `json:"offset"` // hours ahead of real time
+}
+
+// New returns a clock following real time.
+func New() *Clock {
+	c := &Clock{realNow: time.Now}
+	c.SetRealTime()
+	return c
+}
+
+// NewPaused returns a clock frozen at t, for deterministic simulations.
+func NewPaused(t time.Time) *Clock {
+	c := New()
+	c.SetTime(t)
+	c.Pause()
+	return c
+}
+
+// Now returns the current simulated time in UTC.
+func (c *Clock) Now() time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.nowLocked()
+}
+
+// Since returns the simulated time elapsed since t.
+func (c *Clock) Since(t time.Time) time.Duration {
+	return c.Now().Sub(t)
+}
+
+// Mode returns the clock mode.
+func (c *Clock) Mode() Mode {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.mode
+}
+
+// Rate returns simulated seconds per real second; 0 while paused.
+func (c *Clock) Rate() float64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.rate
+}
+
+// Offset returns how far simulated time is ahead of real time.
+func (c *Clock) Offset() time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.nowLocked().Sub(c.realNow())
+}
+
+// State returns a snapshot of the clock.
+func (c *Clock) State() State {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	now := c.nowLocked()
+	return State{
+		Mode:   c.mode,
+		Time:   now,
+		Rate:   c.rate,
+		Offset: now.Sub(c.realNow()).Hours(),
+	}
+}
+
+// SetRealTime makes the clock follow real time.
+func (c *Clock) SetRealTime() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.realNow()
+	c.realAnchor = now
+	c.simAnchor = now
+	c.rate = 1
+	c.resumeRate = 1
+	c.mode = ModeReal
+}
+
+// SetOffset makes the clock run at real speed, offset from real time by d.
+func (c *Clock) SetOffset(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.realNow()
+	c.realAnchor = now
+	c.simAnchor = now.Add(d)
+	c.rate = 1
+	c.resumeRate = 1
+	c.mode = ModeOffset
+}
+
+// SetTime jumps the clock to t. A paused clock stays paused and an
+// accelerated clock keeps its rate; a real-time clock switches to offset mode.
+func (c *Clock) SetTime(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.realAnchor = c.realNow()
+	c.simAnchor = t
+	if c.mode == ModeReal {
+		c.mode = ModeOffset
+	}
+}
+
+// SetRate runs the clock at rate times real time from the current simulated
+// time. A rate of 1 is offset mode. Setting a rate resumes a paused clock.
+func (c *Clock) SetRate(rate float64) error {
+	if rate <= 0 || rate > MaxRate {
+		return errInvalidRate
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rebase()
+	c.rate = rate
+	c.resumeRate = rate
+	if rate == 1 {
+		c.mode = ModeOffset
+	} else {
+		c.mode = ModeAccelerated
+	}
+	return nil
+}
+
+// Pause freezes the clock at the current simulated time.
+func (c *Clock) Pause() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.mode == ModePaused {
+		return
+	}
+	c.rebase()
+	c.resumeRate = c.rate
+	c.rate = 0
+	c.mode = ModePaused
+}
+
+// Resume restarts a paused clock at the rate it had before pausing.
+func (c *Clock) Resume() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.mode != ModePaused {
+		return
+	}
+	c.rebase()
+	c.rate = c.resumeRate
+	if c.rate == 1 {
+		c.mode = ModeOffset
+	} else {
+		c.mode = ModeAccelerated
+	}
+}
+
+// rebase moves the anchors to now so the rate can change without a jump.
+// Must be called with the write lock held.
+func (c *Clock) rebase() {
+	sim := c.nowLocked()
+	c.realAnchor = c.realNow()
+	c.simAnchor = sim
+}
+
+// nowLocked computes simulated time. Must be called with at least a read lock.
+func (c *Clock) nowLocked() time.Time {
+	elapsed := c.realNow().Sub(c.realAnchor)
+	return c.simAnchor.Add(time.Duration(float64(elapsed) * c.rate)).UTC()
+}
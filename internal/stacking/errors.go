@@ -0,0 +1,14 @@
+package stacking
+
+import "errors"
+
+var (
+	errTooFewFrames     = errors.New("stacking needs at least two frames")
+	errMixedFrames      = errors.New("frames must share dimensions and Bayer pattern")
+	errNoReference      = errors.New("no frame has enough stars to register against")
+	errNotRegistered    = errors.New("fewer than two frames could be registered")
+	errNoMatch          = errors.New("too few stars match the reference")
+	errUnknownRejection = errors.New("unknown rejection method")
+	errUnknownWeighting = errors.New("unknown weighting")
+	errInvalidOptions   = errors.New("clipping limits must not be negative")
+)
@@ -0,0 +1,246 @@
+package stacking
+
+import (
+	"math"
+	"slices"
+)
+
+const (
+	// bandRows is how many rows of the stack are integrated at a time, so
+	// the registered frames never need to be held whole.
+	bandRows = 64
+
+	// minRejectValues is the fewest values of a pixel clipping works on;
+	// with fewer there's no telling the outlier.
+	minRejectValues = 3
+
+	// winsorClip is how far from the median, in standard deviations,
+	// winsorized clipping pulls values in while estimating their spread,
+	// and winsorCorrection restores the spread the pulling removes.
+	winsorClip       = 1.5
+	winsorCorrection = 1.134
+
+	// winsorIterations bounds the spread estimate's iterations, which stop
+	// once it changes by less than winsorTolerance of itself.
+	winsorIterations = 50
+	winsorTolerance  = 5e-4
+)
+
+// layer is one frame's contribution to the stack: its planes, where it
+// lies and how its levels are matched to the reference's.
+type layer struct {
+	planes    [][]float32
+	transform Transform
+	scale     float64   // multiplies the frame's levels
+	offsets   []float64 // then added, per plane
+	weight    float64
+}
+
+// sample is a value of a pixel from one layer.
+type sample struct {
+	v, w float64
+}
+
+// integration is the running tally of an integration.
+type integration struct {
+	values, low, high int
+}
+
+// integrate combines the layers into width×height planes, pixel by pixel
+// rejecting outliers and taking the weighted mean of what is left.
+func integrate(layers []layer, width, height int, opts Options) ([][]float32, integration) {
+	var tally integration
+	channels := len(layers[0].planes)
+	out := make([][]float32, channels)
+	for c := range out {
+		out[c] = make([]float32, width*height)
+	}
+
+	band := make([][]float32, len(layers))
+	for i := range band {
+		band[i] = make([]float32, bandRows*width)
+	}
+	samples := make([]sample, 0, len(layers))
+	for c := 0; c < channels; c++ {
+		for y0 := 0; y0 < height; y0 += bandRows {
+			rows := min(bandRows, height-y0)
+			for i, l := range layers {
+				l.resample(c, band[i], y0, rows, width, height)
+			}
+			for i := 0; i < rows*width; i++ {
+				samples = samples[:0]
+				for j, l := range layers {
+					if v := band[j][i]; !math.IsNaN(float64(v)) {
+						samples = append(samples, sample{float64(v), l.weight})
+					}
+				}
+				out[c][y0*width+i] = float32(combine(samples, opts, &tally))
+			}
+		}
+	}
+	return out, tally
+}
+
+// resample fills band with rows of plane c from row y0 of the reference
+// grid, interpolated from the layer's frame and matched to the
+// reference's levels. Pixels that fall off the frame are NaN.
+func (l layer) resample(c int, band []float32, y0, rows, width, height int) {
+	plane := l.planes[c]
+	for y := 0; y < rows; y++ {
+		for x := 0; x < width; x++ {
+			fx, fy := l.transform.Apply(float64(x), float64(y0+y))
+			v, ok := bicubic(plane, width, height, fx, fy)
+			if !ok {
+				band[y*width+x] = float32(math.NaN())
+				continue
+			}
+			band[y*width+x] = float32(l.scale*v + l.offsets[c])
+		}
+	}
+}
+
+// bicubic interpolates plane at (x, y) with the Catmull-Rom spline, which
+// passes through the pixels and softens the noise less than bilinear
+// interpolation. It reports false off the plane.
+func bicubic(plane []float32, width, height int, x, y float64) (float64, bool) {
+	if x < -0.5 || y < -0.5 || x > float64(width)-0.5 || y > float64(height)-0.5 {
+		return 0, false
+	}
+	x0, y0 := int(math.Floor(x)), int(math.Floor(y))
+	tx, ty := x-float64(x0), y-float64(y0)
+	if tx == 0 && ty == 0 && x0 >= 0 && y0 >= 0 && x0 < width && y0 < height {
+		return float64(plane[y0*width+x0]), true
+	}
+	wx, wy := catmullRom(tx), catmullRom(ty)
+	var sum float64
+	for j := 0; j < 4; j++ {
+		row := plane[max(0, min(height-1, y0-1+j))*width:]
+		var line float64
+		for i := 0; i < 4; i++ {
+			line += wx[i] * float64(row[max(0, min(width-1, x0-1+i))])
+		}
+		sum += wy[j] * line
+	}
+	return sum, true
+}
+
+// catmullRom returns the spline's weights for the four pixels around a
+// point t of the way between the middle two.
+func catmullRom(t float64) [4]float64 {
+	t2, t3 := t*t, t*t*t
+	return [4]float64{
+		(-t3 + 2*t2 - t) / 2,
+		(3*t3 - 5*t2 + 2) / 2,
+		(-3*t3 + 4*t2 + t) / 2,
+		(t3 - t2) / 2,
+	}
+}
+
+// combine rejects the outlying samples and returns the weighted mean of
+// the rest, counting both in tally; 0 when there are none. samples is
+// sorted.
+func combine(samples []sample, opts Options, tally *integration) float64 {
+	if len(samples) == 0 {
+		return 0
+	}
+	slices.SortFunc(samples, func(a, b sample) int {
+		switch {
+		case a.v < b.v:
+			return -1
+		case a.v > b.v:
+			return 1
+		}
+		return 0
+	})
+	lo, hi := 0, len(samples)
+	switch opts.Rejection {
+	case RejectSigma:
+		lo, hi = sigmaClip(samples, opts.Low, opts.High, false)
+	case RejectWinsorized:
+		lo, hi = sigmaClip(samples, opts.Low, opts.High, true)
+	}
+	tally.values += len(samples)
+	tally.low += lo
+	tally.high += len(samples) - hi
+
+	var sum, weights float64
+	for _, s := range samples[lo:hi] {
+		sum += s.w * s.v
+		weights += s.w
+	}
+	return sum / weights
+}
+
+// sigmaClip returns the range of the sorted samples left after rejecting
+// those more than low standard deviations below the median or high above
+// it, repeatedly until none are. Winsorized clipping measures the spread
+// with the outliers pulled in to the rest, so a bright satellite trail
+// doesn't inflate the spread enough to hide itself; since the outliers
+// don't sway it, one pass does, and repeating would only eat into the
+// noise as the survivors' spread narrows.
+func sigmaClip(samples []sample, low, high float64, winsorize bool) (lo, hi int) {
+	lo, hi = 0, len(samples)
+	for hi-lo >= minRejectValues {
+		kept := samples[lo:hi]
+		m := sampleMedian(kept)
+		sd := spread(kept, m, winsorize)
+		if sd == 0 {
+			break
+		}
+		nlo, nhi := lo, hi
+		for nlo < nhi && samples[nlo].v < m-low*sd {
+			nlo++
+		}
+		for nhi > nlo && samples[nhi-1].v > m+high*sd {
+			nhi--
+		}
+		if nlo == lo && nhi == hi {
+			break
+		}
+		lo, hi = nlo, nhi
+		if winsorize {
+			break
+		}
+	}
+	return lo, hi
+}
+
+// spread returns the standard deviation of the samples, or with winsorize
+// their winsorized standard deviation around the median m.
+func spread(samples []sample, m float64, winsorize bool) float64 {
+	sd := stddev(samples, math.Inf(-1), math.Inf(1))
+	if !winsorize {
+		return sd
+	}
+	for i := 0; i < winsorIterations && sd > 0; i++ {
+		next := winsorCorrection * stddev(samples, m-winsorClip*sd, m+winsorClip*sd)
+		done := math.Abs(next-sd) < winsorTolerance*sd
+		sd = next
+		if done {
+			break
+		}
+	}
+	return sd
+}
+
+// stddev returns the sample standard deviation of the samples, each
+// clamped to [lo, hi].
+func stddev(samples []sample, lo, hi float64) float64 {
+	var sum, sq float64
+	for _, s := range samples {
+		v := max(lo, min(hi, s.v))
+		sum += v
+		sq += v * v
+	}
+	n := float64(len(samples))
+	return math.Sqrt(max(0, (sq-sum*sum/n)/(n-1)))
+}
+
+// sampleMedian returns the median of sorted samples.
+func sampleMedian(samples []sample) float64 {
+	n := len(samples)
+	if n%2 == 0 {
+		return (samples[n/2-1].v + samples[n/2].v) / 2
+	}
+	return samples[n/2].v
+}
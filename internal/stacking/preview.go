@@ -0,0 +1,77 @@
+package stacking
+
+import (
+	"image"
+	"math"
+
+	"github.com/darkdragonsastro/draco-simulator/internal/stretch"
+)
+
+// Preview returns an 8-bit rendering of the stack no wider than maxWidth,
+// each channel stretched on its own so the background sits at a quarter
+// grey, as frame previews are.
+func (r *Result) Preview(maxWidth int) image.Image {
+	b := r.Binned(maxWidth)
+	w, h := b.Width, b.Height
+
+	planes := make([][]float64, len(b.Channels))
+	for c, plane := range b.Channels {
+		values := make([]float64, len(plane))
+		for i, v := range plane {
+			values[i] = float64(v) / math.MaxUint16
+		}
+		shadows, midtone := stretch.Auto(values)
+		for i, v := range values {
+			values[i] = 255 * stretch.Apply(v, shadows, midtone)
+		}
+		planes[c] = values
+	}
+
+	if len(planes) == 1 {
+		img := image.NewGray(image.Rect(0, 0, w, h))
+		for i, v := range planes[0] {
+			img.Pix[i] = uint8(math.Round(v))
+		}
+		return img
+	}
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for i := 0; i < w*h; i++ {
+		for c, plane := range planes {
+			img.Pix[4*i+c] = uint8(math.Round(plane[i]))
+		}
+		img.Pix[4*i+3] = 255
+	}
+	return img
+}
+
+// Binned returns a copy of the stack with its channels averaged down in
+// square blocks to no wider than maxWidth. The copy shares everything but
+// the pixels, so a preview can be kept without the full-resolution planes.
+func (r *Result) Binned(maxWidth int) *Result {
+	factor := 1
+	if maxWidth > 0 {
+		for r.Width/factor > maxWidth {
+			factor++
+		}
+	}
+	out := *r
+	out.Width, out.Height = r.Width/factor, r.Height/factor
+	out.Channels = make([][]float32, len(r.Channels))
+	for c, plane := range r.Channels {
+		binned := make([]float32, out.Width*out.Height)
+		for y := 0; y < out.Height; y++ {
+			for x := 0; x < out.Width; x++ {
+				var sum float64
+				for dy := 0; dy < factor; dy++ {
+					row := plane[(y*factor+dy)*r.Width:]
+					for dx := 0; dx < factor; dx++ {
+						sum += float64(row[x*factor+dx])
+					}
+				}
+				binned[y*out.Width+x] = float32(sum / float64(factor*factor))
+			}
+		}
+		out.Channels[c] = binned
+	}
+	return &out
+}
@@ -0,0 +1,298 @@
+package stacking
+
+import (
+	"math"
+	"slices"
+
+	"github.com/darkdragonsastro/draco-simulator/internal/analysis"
+)
+
+const (
+	// triangleStars is how many of the brightest stars of each frame the
+	// triangles are built from.
+	triangleStars = 25
+
+	// triangleTolerance is how closely two triangles' side ratios must agree
+	// for them to match.
+	triangleTolerance = 0.005
+
+	// minTriangleSide is the shortest side, in pixels, of a triangle whose
+	// shape is well enough measured to match.
+	minTriangleSide = 10.0
+
+	// candidatePairs is how many of the best-voted star pairs are tried two
+	// at a time as the basis of the transform.
+	candidatePairs = 12
+
+	// inlierRadius is how far, in pixels, a star may land from its match
+	// under a trial transform; matchRadius is the tighter distance the final
+	// fit pairs stars within.
+	inlierRadius = 3.0
+	matchRadius  = 2.0
+
+	// minMatches is the fewest matched stars a registration is trusted on.
+	minMatches = 6
+)
+
+// Transform is a similarity transform, a rotation and scale followed by a
+// shift, taking reference pixel coordinates to a frame's:
+//
+//	x' = A·x − B·y + TX
+//	y' = B·x + A·y + TY
+type Transform struct {
+	A, B   float64
+	TX, TY float64
+}
+
+// identity leaves coordinates unchanged.
+var identity = Transform{A: 1}
+
+// Apply maps (x, y) by the transform.
+func (t Transform) Apply(x, y float64) (float64, float64) {
+	return t.A*x - t.B*y + t.TX, t.B*x + t.A*y + t.TY
+}
+
+// Scale returns the frame's pixels per reference pixel.
+func (t Transform) Scale() float64 {
+	return math.Hypot(t.A, t.B)
+}
+
+// Rotation returns the rotation in degrees, counterclockwise from +x
+// towards +y.
+func (t Transform) Rotation() float64 {
+	return math.Atan2(t.B, t.A) * 180 / math.Pi
+}
+
+// triangle is three stars with their shape: the ratios of the middle and
+// shortest sides to the longest, which no shift, rotation or scale
+// changes. Its vertices are ordered opposite the longest, middle and
+// shortest sides, so matched triangles pair their stars.
+type triangle struct {
+	u, v     float64
+	stars    [3]int
+	mirrored bool
+}
+
+// triangles returns the triangles of the brightest stars, ordered by u.
+func triangles(stars []analysis.Star) []triangle {
+	n := min(len(stars), triangleStars)
+	var out []triangle
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			for k := j + 1; k < n; k++ {
+				if t, ok := makeTriangle(stars, i, j, k); ok {
+					out = append(out, t)
+				}
+			}
+		}
+	}
+	slices.SortFunc(out, func(a, b triangle) int {
+		switch {
+		case a.u < b.u:
+			return -1
+		case a.u > b.u:
+			return 1
+		}
+		return 0
+	})
+	return out
+}
+
+// makeTriangle returns the triangle of stars i, j and k, reporting false
+// for triangles too small to measure.
+func makeTriangle(stars []analysis.Star, i, j, k int) (triangle, bool) {
+	type side struct {
+		length   float64
+		opposite int
+	}
+	dist := func(a, b int) float64 {
+		return math.Hypot(stars[a].X-stars[b].X, stars[a].Y-stars[b].Y)
+	}
+	sides := [3]side{{dist(j, k), i}, {dist(i, k), j}, {dist(i, j), k}}
+	slices.SortFunc(sides[:], func(a, b side) int {
+		switch {
+		case a.length > b.length:
+			return -1
+		case a.length < b.length:
+			return 1
+		}
+		return 0
+	})
+	if sides[2].length < minTriangleSide {
+		return triangle{}, false
+	}
+	t := triangle{
+		u:     sides[1].length / sides[0].length,
+		v:     sides[2].length / sides[0].length,
+		stars: [3]int{sides[0].opposite, sides[1].opposite, sides[2].opposite},
+	}
+	a, b, c := stars[t.stars[0]], stars[t.stars[1]], stars[t.stars[2]]
+	t.mirrored = (b.X-a.X)*(c.Y-a.Y)-(b.Y-a.Y)*(c.X-a.X) < 0
+	return t, true
+}
+
+// match is a star of the frame paired with one of the reference.
+type match struct {
+	ref, frame int
+}
+
+// registration is how a frame lines up with the reference.
+type registration struct {
+	transform Transform
+	matches   []match
+	residual  float64 // RMS distance, in pixels, of matched stars from the fit
+}
+
+// register finds the transform from the reference's stars to the frame's:
+// matching triangles vote for the star pairs they contain, pairs of the
+// best-voted star pairs propose transforms, and the one most stars agree
+// with is refit to every star it pairs.
+func register(ref []analysis.Star, refTriangles []triangle, stars []analysis.Star) (*registration, error) {
+	votes := make(map[match]int)
+	for _, t := range triangles(stars) {
+		first, _ := slices.BinarySearchFunc(refTriangles, t.u-triangleTolerance, func(r triangle, u float64) int {
+			switch {
+			case r.u < u:
+				return -1
+			case r.u > u:
+				return 1
+			}
+			return 0
+		})
+		for _, r := range refTriangles[first:] {
+			if r.u > t.u+triangleTolerance {
+				break
+			}
+			if math.Abs(r.v-t.v) > triangleTolerance || r.mirrored != t.mirrored {
+				continue
+			}
+			for i := range r.stars {
+				votes[match{r.stars[i], t.stars[i]}]++
+			}
+		}
+	}
+
+	// Each reference star's best-voted partner, most votes first
+	best := make(map[int]match)
+	for m, n := range votes {
+		if b, ok := best[m.ref]; !ok || n > votes[b] || n == votes[b] && m.frame < b.frame {
+			best[m.ref] = m
+		}
+	}
+	var pairs []match
+	for _, m := range best {
+		pairs = append(pairs, m)
+	}
+	slices.SortFunc(pairs, func(a, b match) int {
+		if votes[a] != votes[b] {
+			return votes[b] - votes[a]
+		}
+		return a.ref - b.ref
+	})
+	if len(pairs) < 2 {
+		return nil, errNoMatch
+	}
+
+	var trial Transform
+	var inliers []match
+	basis := pairs[:min(len(pairs), candidatePairs)]
+	for i := range basis {
+		for j := i + 1; j < len(basis); j++ {
+			t, ok := fit(ref, stars, []match{basis[i], basis[j]})
+			if !ok {
+				continue
+			}
+			if agree := agreeing(ref, stars, pairs, t, inlierRadius); len(agree) > len(inliers) {
+				trial, inliers = t, agree
+			}
+		}
+	}
+	if len(inliers) < 3 {
+		return nil, errNoMatch
+	}
+	if t, ok := fit(ref, stars, inliers); ok {
+		trial = t
+	}
+
+	// Pair every star the fit lands near another, then refit to them all
+	matches := nearest(ref, stars, trial, matchRadius)
+	if len(matches) < minMatches {
+		return nil, errNoMatch
+	}
+	t, ok := fit(ref, stars, matches)
+	if !ok {
+		return nil, errNoMatch
+	}
+	reg := &registration{transform: t, matches: matches}
+	var sum float64
+	for _, m := range matches {
+		x, y := t.Apply(ref[m.ref].X, ref[m.ref].Y)
+		dx, dy := x-stars[m.frame].X, y-stars[m.frame].Y
+		sum += dx*dx + dy*dy
+	}
+	reg.residual = math.Sqrt(sum / float64(len(matches)))
+	return reg, nil
+}
+
+// agreeing returns the pairs t maps within radius of each other.
+func agreeing(ref, stars []analysis.Star, pairs []match, t Transform, radius float64) []match {
+	var out []match
+	for _, m := range pairs {
+		x, y := t.Apply(ref[m.ref].X, ref[m.ref].Y)
+		if math.Hypot(x-stars[m.frame].X, y-stars[m.frame].Y) <= radius {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// nearest pairs each reference star with the nearest frame star within
+// radius of where t maps it, each frame star used once.
+func nearest(ref, stars []analysis.Star, t Transform, radius float64) []match {
+	used := make([]bool, len(stars))
+	var out []match
+	for i, r := range ref {
+		x, y := t.Apply(r.X, r.Y)
+		found, closest := -1, radius
+		for j, s := range stars {
+			if d := math.Hypot(x-s.X, y-s.Y); !used[j] && d <= closest {
+				found, closest = j, d
+			}
+		}
+		if found >= 0 {
+			used[found] = true
+			out = append(out, match{i, found})
+		}
+	}
+	return out
+}
+
+// fit returns the least-squares similarity transform taking the matched
+// reference stars to the frame's, reporting false when they coincide.
+func fit(ref, stars []analysis.Star, matches []match) (Transform, bool) {
+	var px, py, qx, qy float64
+	for _, m := range matches {
+		px += ref[m.ref].X
+		py += ref[m.ref].Y
+		qx += stars[m.frame].X
+		qy += stars[m.frame].Y
+	}
+	n := float64(len(matches))
+	px, py, qx, qy = px/n, py/n, qx/n, qy/n
+
+	var dot, cross, norm float64
+	for _, m := range matches {
+		ax, ay := ref[m.ref].X-px, ref[m.ref].Y-py
+		bx, by := stars[m.frame].X-qx, stars[m.frame].Y-qy
+		dot += ax*bx + ay*by
+		cross += ax*by - ay*bx
+		norm += ax*ax + ay*ay
+	}
+	if norm == 0 {
+		return Transform{}, false
+	}
+	t := Transform{A: dot / norm, B: cross / norm}
+	t.TX = qx - (t.A*px - t.B*py)
+	t.TY = qy - (t.B*px + t.A*py)
+	return t, t.A != 0 || t.B != 0
+}
@@ -0,0 +1,392 @@
+// Package stacking combines many exposures of the same field into one
+// deeper image. Frames are registered to the sharpest of them by matching
+// triangles of stars, which finds the shift, rotation and scale between
+// them; outlying pixel values such as satellite trails and cosmic rays are
+// rejected by sigma or winsorized sigma clipping; and what is left is
+// averaged with each frame weighted by its noise.
+package stacking
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/darkdragonsastro/draco-simulator/internal/analysis"
+	"github.com/darkdragonsastro/draco-simulator/internal/debayer"
+	"github.com/darkdragonsastro/draco-simulator/internal/game"
+)
+
+const (
+	// DefaultClip is the default rejection limit, in standard deviations
+	// from the median, on either side.
+	DefaultClip = 3.0
+
+	// registrationStars is how many of each frame's brightest stars are
+	// measured for registration.
+	registrationStars = 200
+
+	// levelSamples is about how many pixels of each plane its background
+	// level is judged from.
+	levelSamples = 100000
+)
+
+// Rejection is a way of rejecting outlying pixel values.
+type Rejection string
+
+// Rejection methods.
+const (
+	// RejectNone averages every value.
+	RejectNone Rejection = "none"
+
+	// RejectSigma clips values beyond the limits, in standard deviations
+	// around the median, until none remain. An outlier inflates the
+	// spread it's judged by, so it needs many frames.
+	RejectSigma Rejection = "sigma"
+
+	// RejectWinsorized clips like RejectSigma but measures the spread with
+	// outliers pulled in to the rest, which holds up with fewer frames.
+	RejectWinsorized Rejection = "winsorized"
+)
+
+// ParseRejection returns the rejection method named by s.
+func ParseRejection(s string) (Rejection, error) {
+	switch r := Rejection(strings.ToLower(s)); r {
+	case RejectNone, RejectSigma, RejectWinsorized:
+		return r, nil
+	}
+	return "", fmt.Errorf("%w: %q", errUnknownRejection, s)
+}
+
+// Weighting is how frames are weighted in the average.
+type Weighting string
+
+// Weightings.
+const (
+	// WeightNoise weights each frame by the inverse of its noise
+	// variance, which gives the stack the least noise.
+	WeightNoise Weighting = "noise"
+
+	// WeightEqual weights every frame alike.
+	WeightEqual Weighting = "equal"
+)
+
+// ParseWeighting returns the weighting named by s.
+func ParseWeighting(s string) (Weighting, error) {
+	switch w := Weighting(strings.ToLower(s)); w {
+	case WeightNoise, WeightEqual:
+		return w, nil
+	}
+	return "", fmt.Errorf("%w: %q", errUnknownWeighting, s)
+}
+
+// Options tune the stacking. Zero values take the defaults: winsorized
+// clipping at DefaultClip, weighted by noise.
+type Options struct {
+	Rejection Rejection
+	Low       float64 // rejection limit below the median, in standard deviations
+	High      float64 // rejection limit above the median
+	Weighting Weighting
+}
+
+// FrameReport is how a frame went into the stack.
+type FrameReport struct {
+	Index      int    `json:"index"`
+	Registered bool   `json:"registered"`
+	Error      string `json:"error,omitempty"`
+
+	Stars   int `json:"stars"`   // detected
+	Matched int `json:"matched"` // paired with the reference's
+
+	// The frame's offset from the reference: the shift in pixels of the
+	// reference's origin, the rotation in degrees and the frame's pixels
+	// per reference pixel
+	ShiftX   float64 `json:"shift_x"`
+	ShiftY   float64 `json:"shift_y"`
+	Rotation float64 `json:"rotation"`
+	Scale    float64 `json:"scale"`
+	Residual float64 `json:"residual"` // RMS misfit of the matched stars, pixels
+
+	HFR    float64 `json:"hfr"`
+	Noise  float64 `json:"noise"`  // background noise, ADU
+	Weight float64 `json:"weight"` // relative to the reference's
+}
+
+// Result is a stacked image and how it was made.
+type Result struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+
+	// Channels are the stacked planes: one for monochrome frames, red,
+	// green and blue for raw color frames, which are debayered first
+	Channels [][]float32 `json:"-"`
+
+	Frames     []FrameReport `json:"frames"`
+	Reference  int           `json:"reference"`  // index of the frame aligned to
+	Integrated int           `json:"integrated"` // frames in the stack
+	Exposure   float64       `json:"exposure"`   // seconds of integration
+
+	// Fractions of the pixel values rejected below and above the rest
+	RejectedLow  float64 `json:"rejected_low"`
+	RejectedHigh float64 `json:"rejected_high"`
+
+	// Background noise of the reference frame and of the stack, in ADU,
+	// measured alike on their luminance. Their ratio is the SNR
+	// improvement; the expected improvement is what the frames' noise and
+	// weights promise. Interpolating the frames onto the reference smooths
+	// their noise a little, so the measured improvement can run above it
+	ReferenceNoise      float64 `json:"reference_noise"`
+	Noise               float64 `json:"noise"`
+	SNRImprovement      float64 `json:"snr_improvement"`
+	ExpectedImprovement float64 `json:"expected_improvement"`
+
+	analysis *analysis.Result
+	context  analysis.Image
+}
+
+// Stack registers frames to the sharpest of them and integrates them.
+// Frames must share their dimensions and Bayer pattern; ones that can't be
+// registered are left out and reported.
+func Stack(frames []*analysis.Image, opts Options) (*Result, error) {
+	if len(frames) < 2 {
+		return nil, errTooFewFrames
+	}
+	for _, f := range frames {
+		if f.Width != frames[0].Width || f.Height != frames[0].Height || f.BayerPattern != frames[0].BayerPattern {
+			return nil, errMixedFrames
+		}
+	}
+	if opts.Low < 0 || opts.High < 0 {
+		return nil, errInvalidOptions
+	}
+	if opts.Rejection == "" {
+		opts.Rejection = RejectWinsorized
+	}
+	if opts.Low == 0 {
+		opts.Low = DefaultClip
+	}
+	if opts.High == 0 {
+		opts.High = DefaultClip
+	}
+	if opts.Weighting == "" {
+		opts.Weighting = WeightNoise
+	}
+
+	res := &Result{Width: frames[0].Width, Height: frames[0].Height, Frames: make([]FrameReport, len(frames))}
+	measured := make([]*analysis.Result, len(frames))
+	res.Reference = -1
+	for i, f := range frames {
+		m, err := analysis.Analyze(f, analysis.Options{MaxStars: registrationStars})
+		if err != nil {
+			return nil, err
+		}
+		measured[i] = m
+		res.Frames[i] = FrameReport{Index: i, Stars: len(m.Stars), HFR: m.HFR, Noise: m.Noise}
+		if len(m.Stars) < minMatches || m.HFR <= 0 {
+			continue
+		}
+		if res.Reference < 0 || m.HFR < measured[res.Reference].HFR {
+			res.Reference = i
+		}
+	}
+	if res.Reference < 0 {
+		return nil, errNoReference
+	}
+
+	ref := measured[res.Reference]
+	refTriangles := triangles(ref.Stars)
+	var pattern debayer.Pattern
+	mosaic := frames[0].BayerPattern != ""
+	if mosaic {
+		p, err := debayer.ParsePattern(frames[0].BayerPattern)
+		if err != nil {
+			return nil, err
+		}
+		pattern = p
+	}
+
+	var layers []layer
+	var refLayer layer
+	for i, f := range frames {
+		report := &res.Frames[i]
+		reg := &registration{transform: identity}
+		if i != res.Reference {
+			r, err := register(ref.Stars, refTriangles, measured[i].Stars)
+			if err != nil {
+				report.Error = err.Error()
+				continue
+			}
+			reg = r
+			report.Matched = len(r.matches)
+		} else {
+			report.Matched = len(ref.Stars)
+		}
+		report.Registered = true
+		t := reg.transform
+		report.ShiftX, report.ShiftY = t.TX, t.TY
+		report.Rotation, report.Scale = t.Rotation(), t.Scale()
+		report.Residual = reg.residual
+
+		l := layer{transform: t, scale: 1}
+		if mosaic {
+			color, err := debayer.Debayer(f.Pixels, f.Width, f.Height, pattern, debayer.Bilinear)
+			if err != nil {
+				return nil, err
+			}
+			l.planes = color.Planes[:]
+		} else {
+			l.planes = [][]float32{f.Pixels}
+		}
+		if i != res.Reference {
+			l.scale = fluxScale(ref.Stars, measured[i].Stars, reg)
+		}
+		layers = append(layers, l)
+		if i == res.Reference {
+			refLayer = l
+		}
+	}
+	if len(layers) < 2 {
+		return nil, errNotRegistered
+	}
+
+	// Match each frame's sky and brightness to the reference's, and weight
+	// it by its noise once matched
+	refLevels := levels(refLayer.planes)
+	var weights, variance float64
+	for i, j := 0, 0; i < len(frames); i++ {
+		report := &res.Frames[i]
+		if !report.Registered {
+			continue
+		}
+		l := &layers[j]
+		j++
+		for c, level := range levels(l.planes) {
+			l.offsets = append(l.offsets, refLevels[c]-l.scale*level)
+		}
+		noise := l.scale * measured[i].Noise
+		l.weight = 1
+		if opts.Weighting == WeightNoise && noise > 0 {
+			l.weight = (ref.Noise * ref.Noise) / (noise * noise)
+		}
+		report.Weight = l.weight
+		weights += l.weight
+		if noise > 0 {
+			variance += l.weight * l.weight * noise * noise
+		}
+		res.Integrated++
+		res.Exposure += frames[i].Exposure
+	}
+
+	var tally integration
+	res.Channels, tally = integrate(layers, res.Width, res.Height, opts)
+	if tally.values > 0 {
+		res.RejectedLow = float64(tally.low) / float64(tally.values)
+		res.RejectedHigh = float64(tally.high) / float64(tally.values)
+	}
+
+	// The stack's context is the reference's, with the electrons of all
+	// the frames behind each of its ADU
+	var sq float64
+	for _, l := range layers {
+		sq += l.weight * l.weight
+	}
+	res.context = *frames[res.Reference]
+	res.context.Pixels = nil
+	res.context.BayerPattern = ""
+	res.context.Exposure = res.Exposure
+	res.context.ElectronsPerADU *= weights * weights / sq
+
+	stacked, err := analysis.Analyze(res.Image(), analysis.Options{Correlated: true})
+	if err != nil {
+		return nil, err
+	}
+	res.analysis = stacked
+	res.Noise = stacked.Noise
+
+	refImage := res.context
+	refImage.Exposure = frames[res.Reference].Exposure
+	refImage.ElectronsPerADU = frames[res.Reference].ElectronsPerADU
+	refImage.Pixels = luminance(refLayer.planes)
+	single, err := analysis.Analyze(&refImage, analysis.Options{MaxStars: 1})
+	if err != nil {
+		return nil, err
+	}
+	res.ReferenceNoise = single.Noise
+	if res.Noise > 0 {
+		res.SNRImprovement = res.ReferenceNoise / res.Noise
+	}
+	if variance > 0 {
+		res.ExpectedImprovement = ref.Noise * weights / math.Sqrt(variance)
+	}
+	return res, nil
+}
+
+// fluxScale returns the factor that brings the frame's stars to the
+// reference's brightness, as the median ratio of the unsaturated matched
+// stars' fluxes, allowing for the frame's pixels covering more or less sky
+// than the reference's. It is 1 when too few stars say otherwise.
+func fluxScale(ref, stars []analysis.Star, reg *registration) float64 {
+	area := reg.transform.Scale() * reg.transform.Scale()
+	var ratios []float64
+	for _, m := range reg.matches {
+		r, s := ref[m.ref], stars[m.frame]
+		if r.Saturated || s.Saturated || s.Flux <= 0 {
+			continue
+		}
+		ratios = append(ratios, r.Flux/(s.Flux*area))
+	}
+	if len(ratios) < minMatches {
+		return 1
+	}
+	slices.Sort(ratios)
+	return ratios[len(ratios)/2]
+}
+
+// levels returns the median of each plane, from an even sampling of its
+// pixels.
+func levels(planes [][]float32) []float64 {
+	out := make([]float64, len(planes))
+	for c, plane := range planes {
+		step := max(1, len(plane)/levelSamples)
+		values := make([]float64, 0, len(plane)/step+1)
+		for i := 0; i < len(plane); i += step {
+			values = append(values, float64(plane[i]))
+		}
+		slices.Sort(values)
+		out[c] = values[len(values)/2]
+	}
+	return out
+}
+
+// luminance returns the planes' luminance: the plane itself for
+// monochrome, and red, green and blue weighted as a Bayer cell weights
+// them for color.
+func luminance(planes [][]float32) []float32 {
+	if len(planes) == 1 {
+		return planes[0]
+	}
+	out := make([]float32, len(planes[0]))
+	for i := range out {
+		out[i] = (planes[debayer.Red][i] + 2*planes[debayer.Green][i] + planes[debayer.Blue][i]) / 4
+	}
+	return out
+}
+
+// Image returns the stack's luminance for analysis, with the context of
+// the reference frame and the stack's total exposure.
+func (r *Result) Image() *analysis.Image {
+	img := r.context
+	img.Width, img.Height = r.Width, r.Height
+	img.Pixels = luminance(r.Channels)
+	return &img
+}
+
+// Analysis returns the measurements of the stack's luminance.
+func (r *Result) Analysis() *analysis.Result {
+	return r.analysis
+}
+
+// Metrics returns the stack's metrics for scoring.
+func (r *Result) Metrics() game.ImageMetrics {
+	return r.analysis.Metrics()
+}
@@ -0,0 +1,59 @@
+// Package stretch maps linear image data to screen brightness in the manner
+// of PixInsight's screen transfer function: the shadows are clipped just
+// below the sky and a midtone transfer function lifts the background to a
+// quarter grey, so faint detail shows without saturating the stars.
+package stretch
+
+import (
+	"math"
+	"slices"
+)
+
+// Auto picks a shadows clip and midtone balance for values, normalized to
+// 0..1, from their median and spread.
+func Auto(values []float64) (shadows, midtone float64) {
+	if len(values) == 0 {
+		return 0, 0.5
+	}
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	median := sorted[len(sorted)/2]
+
+	dev := make([]float64, len(sorted))
+	for i, v := range sorted {
+		dev[i] = math.Abs(v - median)
+	}
+	slices.Sort(dev)
+	mad := 1.4826 * dev[len(dev)/2]
+
+	shadows = math.Max(0, median-2.8*mad)
+	if shadows >= 1 {
+		return 0, 0.5
+	}
+	// Solve for the midtone that maps the median to a quarter grey
+	m := (median - shadows) / (1 - shadows)
+	midtone = mtf(0.25, m)
+	return shadows, midtone
+}
+
+// Apply clips the shadows of v and applies the midtone transfer function,
+// giving a brightness from 0 to 1.
+func Apply(v, shadows, midtone float64) float64 {
+	if v <= shadows {
+		return 0
+	}
+	return math.Min(1, mtf(midtone, (v-shadows)/(1-shadows)))
+}
+
+// mtf is the midtone transfer function with balance m.
+func mtf(m, x float64) float64 {
+	switch {
+	case x <= 0:
+		return 0
+	case x >= 1:
+		return 1
+	case x == m:
+		return 0.5
+	}
+	return (m - 1) * x / ((2*m-1)*x - m)
+}
@@ -0,0 +1,14 @@
+package xisf
+
+import "errors"
+
+var (
+	errNotXISF                = errors.New("not a monolithic XISF file")
+	errUnsupported            = errors.New("unsupported XISF layout")
+	errUnsupportedCompression = errors.New("unsupported compression codec")
+	errCorrupt                = errors.New("corrupt compressed data block")
+	errBadDimensions          = errors.New("image dimensions do not match pixel count")
+	errTooLarge               = errors.New("image too large")
+	errInvalidSampleFormat    = errors.New("sample format must be UInt16 or Float32")
+	errNoImage                = errors.New("XISF file holds no image")
+)
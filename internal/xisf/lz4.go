@@ -0,0 +1,164 @@
+package xisf
+
+import "encoding/binary"
+
+// LZ4 block format, as XISF's lz4 codec stores it: a sequence of tokens
+// each carrying a run of literals and a back-reference.
+const (
+	lz4MinMatch    = 4
+	lz4HashLog     = 16
+	lz4MaxOffset   = 65535
+	lz4LastLiteral = 5  // the block must end with at least this many literals
+	lz4MFLimit     = 12 // no match may start within this many bytes of the end
+
+	// lz4MaxRatio bounds how many times larger than its block the data
+	// can decode to, a run of 255-byte length extensions each adding 255
+	lz4MaxRatio = 255
+)
+
+// lz4Compress compresses src into an LZ4 block using greedy hash matching.
+func lz4Compress(src []byte) []byte {
+	dst := make([]byte, 0, len(src)/2+16)
+	if len(src) < lz4MFLimit+1 {
+		return lz4AppendSequence(dst, src, 0, 0)
+	}
+
+	var table [1 << lz4HashLog]int32
+	for i := range table {
+		table[i] = -1
+	}
+	hash := func(i int) uint32 {
+		return binary.LittleEndian.Uint32(src[i:]) * 2654435761 >> (32 - lz4HashLog)
+	}
+
+	anchor := 0
+	limit := len(src) - lz4MFLimit
+	for i := 0; i < limit; {
+		h := hash(i)
+		ref := int(table[h])
+		table[h] = int32(i)
+		if ref < 0 || i-ref > lz4MaxOffset ||
+			binary.LittleEndian.Uint32(src[ref:]) != binary.LittleEndian.Uint32(src[i:]) {
+			i++
+			continue
+		}
+
+		// Extend the match, leaving the trailing literals alone
+		end := len(src) - lz4LastLiteral
+		n := lz4MinMatch
+		for i+n < end && src[ref+n] == src[i+n] {
+			n++
+		}
+
+		dst = lz4AppendSequence(dst, src[anchor:i], i-ref, n)
+		i += n
+		anchor = i
+	}
+	return lz4AppendSequence(dst, src[anchor:], 0, 0)
+}
+
+// lz4AppendSequence appends one sequence: literals followed by a match of
+// length n at offset, or literals alone when n is zero.
+func lz4AppendSequence(dst, literals []byte, offset, n int) []byte {
+	lit := len(literals)
+	var token byte
+	if lit >= 15 {
+		token = 15 << 4
+	} else {
+		token = byte(lit) << 4
+	}
+	ml := n - lz4MinMatch
+	if n > 0 {
+		if ml >= 15 {
+			token |= 15
+		} else {
+			token |= byte(ml)
+		}
+	}
+	dst = append(dst, token)
+	if lit >= 15 {
+		dst = lz4AppendLength(dst, lit-15)
+	}
+	dst = append(dst, literals...)
+	if n == 0 {
+		return dst
+	}
+	dst = append(dst, byte(offset), byte(offset>>8))
+	if ml >= 15 {
+		dst = lz4AppendLength(dst, ml-15)
+	}
+	return dst
+}
+
+func lz4AppendLength(dst []byte, n int) []byte {
+	for n >= 255 {
+		dst = append(dst, 255)
+		n -= 255
+	}
+	return append(dst, byte(n))
+}
+
+// lz4Decompress expands an LZ4 block that decodes to size bytes.
+func lz4Decompress(src []byte, size int) ([]byte, error) {
+	dst := make([]byte, 0, min(size, lz4MaxRatio*len(src)))
+	for i := 0; i < len(src); {
+		token := src[i]
+		i++
+
+		lit := int(token >> 4)
+		if lit == 15 {
+			for {
+				if i >= len(src) {
+					return nil, errCorrupt
+				}
+				b := src[i]
+				i++
+				lit += int(b)
+				if b != 255 {
+					break
+				}
+			}
+		}
+		if i+lit > len(src) || len(dst)+lit > size {
+			return nil, errCorrupt
+		}
+		dst = append(dst, src[i:i+lit]...)
+		i += lit
+		if i == len(src) {
+			break // the last sequence has no match
+		}
+
+		if i+2 > len(src) {
+			return nil, errCorrupt
+		}
+		offset := int(src[i]) | int(src[i+1])<<8
+		i += 2
+		n := int(token & 15)
+		if n == 15 {
+			for {
+				if i >= len(src) {
+					return nil, errCorrupt
+				}
+				b := src[i]
+				i++
+				n += int(b)
+				if b != 255 {
+					break
+				}
+			}
+		}
+		n += lz4MinMatch
+		if offset == 0 || offset > len(dst) || len(dst)+n > size {
+			return nil, errCorrupt
+		}
+		// Byte by byte: matches may overlap what they copy
+		start := len(dst) - offset
+		for k := 0; k < n; k++ {
+			dst = append(dst, dst[start+k])
+		}
+	}
+	if len(dst) != size {
+		return nil, errCorrupt
+	}
+	return dst, nil
+}
@@ -0,0 +1,92 @@
+package xisf
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math/rand"
+	"testing"
+)
+
+func TestLZ4RoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	random := make([]byte, 10000)
+	rng.Read(random)
+	noisy := make([]byte, 200000)
+	for i := range noisy {
+		// Low bits of sensor noise over a slowly changing background
+		if i%2 == 0 {
+			noisy[i] = byte(rng.Intn(4))
+		} else {
+			noisy[i] = byte(i / 1000)
+		}
+	}
+
+	for _, tc := range []struct {
+		name string
+		src  []byte
+	}{
+		{"empty", nil},
+		{"short", []byte("XISF")},
+		{"below match limit", []byte("abcabcabcab")},
+		{"zeros", make([]byte, 70000)},
+		{"random", random},
+		{"noisy", noisy},
+		{"shuffled", shuffle(noisy, 2)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			block := lz4Compress(tc.src)
+			got, err := lz4Decompress(block, len(tc.src))
+			if err != nil {
+				t.Fatalf("decompress: %v", err)
+			}
+			if !bytes.Equal(got, tc.src) {
+				t.Fatalf("round trip changed %d bytes to %d", len(tc.src), len(got))
+			}
+		})
+	}
+}
+
+// TestLZ4Reference decodes a block made by the reference implementation,
+// LZ4_compress_default of liblz4 1.9.4, whose long literal run and long
+// overlapping match exercise the length extensions.
+func TestLZ4Reference(t *testing.T) {
+	var want []byte
+	for i := 0; i < 6; i++ {
+		want = append(want, "PixInsight XISF "...)
+	}
+	want = append(want, make([]byte, 300)...)
+	for i := 0; i < 40; i++ {
+		want = append(want, byte(i))
+	}
+	want = append(want, "tail of the block"...)
+
+	block, _ := hex.DecodeString("ff01506978496e736967687420584953462010003d1f000100ff1af029010203040506" +
+		"0708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f20212223242526277461696c206f662074686520626c6f636b")
+	got, err := lz4Decompress(block, len(want))
+	if err != nil {
+		t.Fatalf("decompress: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("decoded %q, want %q", got, want)
+	}
+}
+
+func TestLZ4Corrupt(t *testing.T) {
+	block := lz4Compress(bytes.Repeat([]byte("0123456789"), 100))
+	for _, tc := range []struct {
+		name  string
+		block []byte
+		size  int
+	}{
+		{"truncated", block[:len(block)/2], 1000},
+		{"size too small", block, 999},
+		{"size too large", block, 1001},
+		{"offset before start", []byte{0x10, 'a', 0x05, 0x00, 0x00}, 10},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := lz4Decompress(tc.block, tc.size); err == nil {
+				t.Fatal("corrupt block decoded without error")
+			}
+		})
+	}
+}
@@ -0,0 +1,233 @@
+package xisf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+type xmlDocument struct {
+	Images   []xmlImage    `xml:"Image"`
+	Metadata []xmlProperty `xml:"Metadata>Property"`
+}
+
+type xmlImage struct {
+	Geometry     string           `xml:"geometry,attr"`
+	SampleFormat string           `xml:"sampleFormat,attr"`
+	ImageType    string           `xml:"imageType,attr"`
+	Compression  string           `xml:"compression,attr"`
+	Location     string           `xml:"location,attr"`
+	ByteOrder    string           `xml:"byteOrder,attr"`
+	PixelStorage string           `xml:"pixelStorage,attr"`
+	Properties   []xmlProperty    `xml:"Property"`
+	FITSKeywords []xmlFITSKeyword `xml:"FITSKeyword"`
+	CFA          *xmlCFA          `xml:"ColorFilterArray"`
+}
+
+type xmlProperty struct {
+	ID    string `xml:"id,attr"`
+	Type  string `xml:"type,attr"`
+	Value string `xml:"value,attr"`
+	Text  string `xml:",chardata"`
+}
+
+type xmlCFA struct {
+	Pattern string `xml:"pattern,attr"`
+	Width   int    `xml:"width,attr"`
+	Height  int    `xml:"height,attr"`
+}
+
+type xmlFITSKeyword struct {
+	Name    string `xml:"name,attr"`
+	Value   string `xml:"value,attr"`
+	Comment string `xml:"comment,attr"`
+}
+
+// Read decodes the first image of a monolithic XISF file.
+func Read(r io.Reader) (*Image, error) {
+	file, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(file) < 16 || string(file[:8]) != signature {
+		return nil, errNotXISF
+	}
+	headerLen := int(binary.LittleEndian.Uint32(file[8:]))
+	if 16+headerLen > len(file) {
+		return nil, errNotXISF
+	}
+
+	var doc xmlDocument
+	if err := xml.Unmarshal(file[16:16+headerLen], &doc); err != nil {
+		return nil, fmt.Errorf("parse XISF header: %w", err)
+	}
+	if len(doc.Images) == 0 {
+		return nil, errNoImage
+	}
+	x := doc.Images[0]
+
+	img := &Image{SampleFormat: x.SampleFormat, ImageType: x.ImageType}
+	if _, err := fmt.Sscanf(x.Geometry, "%d:%d:%d", &img.Width, &img.Height, &img.Channels); err != nil {
+		return nil, fmt.Errorf("%w: geometry %q", errUnsupported, x.Geometry)
+	}
+	if img.Width <= 0 || img.Height <= 0 || img.Channels <= 0 {
+		return nil, fmt.Errorf("%w: geometry %q", errUnsupported, x.Geometry)
+	}
+	if img.Width > maxDimension || img.Height > maxDimension ||
+		img.Channels > maxSamples/img.Width/img.Height {
+		return nil, fmt.Errorf("%w: geometry %q", errTooLarge, x.Geometry)
+	}
+	sampleSize, err := sampleSize(x.SampleFormat)
+	if err != nil {
+		return nil, err
+	}
+	n := img.Width * img.Height * img.Channels
+	if x.ByteOrder == "big" || (x.PixelStorage != "" && x.PixelStorage != "Planar") {
+		return nil, fmt.Errorf("%w: byte order %q, pixel storage %q", errUnsupported, x.ByteOrder, x.PixelStorage)
+	}
+	for _, p := range x.Properties {
+		img.Properties = append(img.Properties, p.property())
+	}
+	for _, k := range x.FITSKeywords {
+		img.FITSKeywords = append(img.FITSKeywords, FITSKeyword(k))
+	}
+	if x.CFA != nil {
+		img.ColorFilterArray = &ColorFilterArray{Pattern: x.CFA.Pattern, Width: x.CFA.Width, Height: x.CFA.Height}
+	}
+	for _, p := range doc.Metadata {
+		img.Metadata = append(img.Metadata, p.property())
+	}
+
+	var position, size int
+	if _, err := fmt.Sscanf(x.Location, "attachment:%d:%d", &position, &size); err != nil {
+		return nil, fmt.Errorf("%w: location %q", errUnsupported, x.Location)
+	}
+	if position < 0 || size < 0 || position > len(file) || size > len(file)-position {
+		return nil, errNotXISF
+	}
+	data := file[position : position+size]
+
+	if x.Compression != "" {
+		data, err = decompress(x.Compression, data, n*sampleSize)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	img.Pixels, err = decodeSamples(data, x.SampleFormat, n)
+	if err != nil {
+		return nil, err
+	}
+	return img, nil
+}
+
+// property converts a parsed property, taking string values from the
+// element text.
+func (p xmlProperty) property() Property {
+	value := p.Value
+	if value == "" {
+		value = strings.TrimSpace(p.Text)
+	}
+	return Property{ID: p.ID, Type: p.Type, Value: value}
+}
+
+// decompress expands a data block described by a compression attribute
+// such as "zlib:1048576" or "lz4+sh:1048576:2". A block may not claim to
+// expand beyond limit bytes, and memory for it grows with what it actually
+// expands to.
+func decompress(spec string, data []byte, limit int) ([]byte, error) {
+	parts := strings.Split(spec, ":")
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("%w: %q", errUnsupportedCompression, spec)
+	}
+	codec, shuffled := strings.CutSuffix(parts[0], "+sh")
+	size, err := strconv.Atoi(parts[1])
+	if err != nil || size < 0 {
+		return nil, fmt.Errorf("%w: %q", errUnsupportedCompression, spec)
+	}
+	if size > limit {
+		return nil, fmt.Errorf("%w: %q", errTooLarge, spec)
+	}
+	itemSize := 1
+	if shuffled && len(parts) > 2 {
+		if itemSize, err = strconv.Atoi(parts[2]); err != nil || itemSize < 1 {
+			return nil, fmt.Errorf("%w: %q", errUnsupportedCompression, spec)
+		}
+	}
+
+	var out []byte
+	switch codec {
+	case "zlib":
+		zr, err := zlib.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		if out, err = io.ReadAll(io.LimitReader(zr, int64(size))); err != nil {
+			return nil, err
+		}
+		if len(out) != size {
+			return nil, errCorrupt
+		}
+	case "lz4", "lz4hc":
+		if out, err = lz4Decompress(data, size); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%w: %q", errUnsupportedCompression, codec)
+	}
+
+	if shuffled {
+		out = unshuffle(out, itemSize)
+	}
+	return out, nil
+}
+
+// sampleSize returns the bytes per sample of a sample format.
+func sampleSize(format string) (int, error) {
+	switch format {
+	case "UInt8":
+		return 1, nil
+	case UInt16:
+		return 2, nil
+	case "UInt32", Float32:
+		return 4, nil
+	case "Float64":
+		return 8, nil
+	}
+	return 0, fmt.Errorf("%w: sample format %q", errUnsupported, format)
+}
+
+// decodeSamples reads n little-endian samples.
+func decodeSamples(data []byte, format string, n int) ([]float32, error) {
+	size, err := sampleSize(format)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < n*size {
+		return nil, errBadDimensions
+	}
+
+	pixels := make([]float32, n)
+	for i := range pixels {
+		b := data[i*size:]
+		switch format {
+		case "UInt8":
+			pixels[i] = float32(b[0])
+		case UInt16:
+			pixels[i] = float32(binary.LittleEndian.Uint16(b))
+		case "UInt32":
+			pixels[i] = float32(binary.LittleEndian.Uint32(b))
+		case Float32:
+			pixels[i] = math.Float32frombits(binary.LittleEndian.Uint32(b))
+		case "Float64":
+			pixels[i] = float32(math.Float64frombits(binary.LittleEndian.Uint64(b)))
+		}
+	}
+	return pixels, nil
+}
@@ -0,0 +1,337 @@
+// Package xisf reads and writes monolithic XISF 1.0 files, PixInsight's
+// native image format, holding a single image with its properties and
+// FITS keywords.
+package xisf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	signature = "XISF0100"
+	namespace = "http://www.pixinsight.com/xisf"
+
+	// blockAlignment is where attached data blocks start, as PixInsight
+	// aligns them.
+	blockAlignment = 4096
+
+	// Limits on the images Read accepts, well beyond any sensor's but
+	// short of exhausting memory on a hostile header: pixels along either
+	// axis, and samples over all channels.
+	maxDimension = 1 << 16
+	maxSamples   = 1 << 28
+)
+
+// Sample formats.
+const (
+	UInt16  = "UInt16"
+	Float32 = "Float32"
+)
+
+// Compression codecs.
+const (
+	CompressionNone = ""
+	CompressionZlib = "zlib"
+	CompressionLZ4  = "lz4"
+)
+
+// Property is an XISF property: a typed value under a namespaced id such
+// as "Observation:Object:Name".
+type Property struct {
+	ID    string
+	Type  string // "String", "Float64", "Int32", "Boolean", "TimePoint", ...
+	Value string // serialized value; TimePoints in ISO 8601
+}
+
+// StringProperty returns a String property.
+func StringProperty(id, value string) Property {
+	return Property{ID: id, Type: "String", Value: value}
+}
+
+// FloatProperty returns a Float64 property.
+func FloatProperty(id string, value float64) Property {
+	return Property{ID: id, Type: "Float64", Value: strconv.FormatFloat(value, 'g', -1, 64)}
+}
+
+// Float32Property returns a Float32 property, the type XISF specifies for
+// instrument readings such as exposure time and sensor temperature.
+func Float32Property(id string, value float64) Property {
+	return Property{ID: id, Type: "Float32", Value: strconv.FormatFloat(value, 'g', -1, 32)}
+}
+
+// IntProperty returns an Int32 property.
+func IntProperty(id string, value int) Property {
+	return Property{ID: id, Type: "Int32", Value: strconv.Itoa(value)}
+}
+
+// TimeProperty returns a TimePoint property.
+func TimeProperty(id string, value time.Time) Property {
+	return Property{ID: id, Type: "TimePoint", Value: value.UTC().Format("2006-01-02T15:04:05.000Z")}
+}
+
+// Float returns a numeric property's value.
+func (p Property) Float() (float64, bool) {
+	f, err := strconv.ParseFloat(p.Value, 64)
+	return f, err == nil
+}
+
+// FITSKeyword is a FITS header card carried for compatibility with FITS
+// tools. Value is serialized as in a FITS header, strings quoted.
+type FITSKeyword struct {
+	Name    string
+	Value   string
+	Comment string
+}
+
+// Image is an image with its metadata. Pixels hold values row by row from
+// the top, one channel after another; UInt16 images hold 0-65535 and
+// Float32 images 0-1.
+type Image struct {
+	Width        int
+	Height       int
+	Channels     int    // 1 for grayscale, 3 for RGB; 0 means 1
+	SampleFormat string // UInt16 or Float32 when writing
+	ImageType    string // "Light", "Dark", "Flat", "Bias", or empty
+	Pixels       []float32
+
+	Properties   []Property // image properties
+	FITSKeywords []FITSKeyword
+
+	// ColorFilterArray describes the mosaic of a raw one-shot color
+	// image, nil for images without one.
+	ColorFilterArray *ColorFilterArray
+
+	// Metadata holds file-level properties such as XISF:CreatorApplication.
+	// XISF:CreationTime is set when writing.
+	Metadata []Property
+}
+
+// ColorFilterArray is the filter mosaic a raw color image was taken
+// through: Pattern names the filter over each pixel of a Width×Height
+// cell, row by row, such as "RGGB" for a 2×2 Bayer cell.
+type ColorFilterArray struct {
+	Pattern string
+	Width   int
+	Height  int
+}
+
+// channels returns the channel count, defaulting to one.
+func (img *Image) channels() int {
+	return max(1, img.Channels)
+}
+
+// Property returns the image property with the given id.
+func (img *Image) Property(id string) (Property, bool) {
+	for _, p := range img.Properties {
+		if p.ID == id {
+			return p, true
+		}
+	}
+	return Property{}, false
+}
+
+// WriteOptions controls how an image is stored.
+type WriteOptions struct {
+	Compression string // CompressionNone, CompressionZlib or CompressionLZ4
+	Shuffle     bool   // byte-shuffle samples before compressing, which helps both codecs
+}
+
+// Write encodes img as a monolithic XISF file.
+func Write(w io.Writer, img *Image, opts WriteOptions) error {
+	if img.SampleFormat != UInt16 && img.SampleFormat != Float32 {
+		return errInvalidSampleFormat
+	}
+	if img.channels() != 1 && img.channels() != 3 {
+		return errBadDimensions
+	}
+	if img.Width <= 0 || img.Height <= 0 || len(img.Pixels) != img.Width*img.Height*img.channels() {
+		return errBadDimensions
+	}
+
+	data, itemSize := encodeSamples(img)
+	uncompressed := len(data)
+	var compression string
+	switch opts.Compression {
+	case CompressionNone:
+	case CompressionZlib, CompressionLZ4:
+		block := data
+		codec := opts.Compression
+		if opts.Shuffle {
+			block = shuffle(block, itemSize)
+			codec += "+sh"
+		}
+		compressed, err := compress(opts.Compression, block)
+		if err != nil {
+			return err
+		}
+		data = compressed
+		compression = fmt.Sprintf("%s:%d", codec, uncompressed)
+		if opts.Shuffle {
+			compression += fmt.Sprintf(":%d", itemSize)
+		}
+	default:
+		return fmt.Errorf("%w: %q", errUnsupportedCompression, opts.Compression)
+	}
+
+	// The header names the data block's position, which depends on the
+	// header's length; settle the position before writing
+	position := blockAlignment
+	var header []byte
+	for {
+		header = buildHeader(img, compression, position, len(data))
+		end := 16 + len(header)
+		next := (end + blockAlignment - 1) / blockAlignment * blockAlignment
+		if next == position {
+			break
+		}
+		position = next
+	}
+
+	var preamble [16]byte
+	copy(preamble[:], signature)
+	binary.LittleEndian.PutUint32(preamble[8:], uint32(len(header)))
+	if _, err := w.Write(preamble[:]); err != nil {
+		return err
+	}
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	if _, err := w.Write(make([]byte, position-16-len(header))); err != nil {
+		return err
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+// buildHeader renders the XML header.
+func buildHeader(img *Image, compression string, position, size int) []byte {
+	var b bytes.Buffer
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	b.WriteString(`<xisf version="1.0" xmlns="` + namespace + `" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance"` +
+		` xsi:schemaLocation="` + namespace + ` http://pixinsight.com/xisf/xisf-1.0.xsd">` + "\n")
+
+	fmt.Fprintf(&b, `<Image geometry="%d:%d:%d" sampleFormat="%s"`, img.Width, img.Height, img.channels(), img.SampleFormat)
+	if img.SampleFormat == Float32 {
+		b.WriteString(` bounds="0:1"`)
+	}
+	if img.channels() == 3 {
+		b.WriteString(` colorSpace="RGB"`)
+	} else {
+		b.WriteString(` colorSpace="Gray"`)
+	}
+	if img.ImageType != "" {
+		fmt.Fprintf(&b, ` imageType="%s"`, escape(img.ImageType))
+	}
+	if compression != "" {
+		fmt.Fprintf(&b, ` compression="%s"`, compression)
+	}
+	fmt.Fprintf(&b, ` location="attachment:%d:%d">`+"\n", position, size)
+	for _, p := range img.Properties {
+		writeProperty(&b, p)
+	}
+	if cfa := img.ColorFilterArray; cfa != nil {
+		fmt.Fprintf(&b, `<ColorFilterArray pattern="%s" width="%d" height="%d"/>`+"\n",
+			escape(cfa.Pattern), cfa.Width, cfa.Height)
+	}
+	for _, k := range img.FITSKeywords {
+		fmt.Fprintf(&b, `<FITSKeyword name="%s" value="%s" comment="%s"/>`+"\n",
+			escape(k.Name), escape(k.Value), escape(k.Comment))
+	}
+	b.WriteString("</Image>\n")
+
+	b.WriteString("<Metadata>\n")
+	writeProperty(&b, TimeProperty("XISF:CreationTime", time.Now()))
+	for _, p := range img.Metadata {
+		if p.ID != "XISF:CreationTime" {
+			writeProperty(&b, p)
+		}
+	}
+	b.WriteString("</Metadata>\n")
+	b.WriteString("</xisf>")
+	return b.Bytes()
+}
+
+// writeProperty renders a property. Strings go in the element's text, other
+// scalars in its value attribute.
+func writeProperty(b *bytes.Buffer, p Property) {
+	if p.Type == "String" {
+		fmt.Fprintf(b, `<Property id="%s" type="String">%s</Property>`+"\n", escape(p.ID), escape(p.Value))
+		return
+	}
+	fmt.Fprintf(b, `<Property id="%s" type="%s" value="%s"/>`+"\n", escape(p.ID), escape(p.Type), escape(p.Value))
+}
+
+func escape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// encodeSamples lays out the pixels little-endian in the image's sample
+// format and returns the bytes and the sample size.
+func encodeSamples(img *Image) ([]byte, int) {
+	if img.SampleFormat == UInt16 {
+		out := make([]byte, 2*len(img.Pixels))
+		for i, v := range img.Pixels {
+			u := math.Round(math.Max(0, math.Min(math.MaxUint16, float64(v))))
+			binary.LittleEndian.PutUint16(out[2*i:], uint16(u))
+		}
+		return out, 2
+	}
+	out := make([]byte, 4*len(img.Pixels))
+	for i, v := range img.Pixels {
+		binary.LittleEndian.PutUint32(out[4*i:], math.Float32bits(v))
+	}
+	return out, 4
+}
+
+func compress(codec string, data []byte) ([]byte, error) {
+	if codec == CompressionLZ4 {
+		return lz4Compress(data), nil
+	}
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// shuffle groups the bytes of each sample position together: all first
+// bytes, then all second bytes, and so on.
+func shuffle(data []byte, itemSize int) []byte {
+	n := len(data) / itemSize
+	out := make([]byte, len(data))
+	for i := 0; i < n; i++ {
+		for k := 0; k < itemSize; k++ {
+			out[k*n+i] = data[i*itemSize+k]
+		}
+	}
+	copy(out[n*itemSize:], data[n*itemSize:])
+	return out
+}
+
+// unshuffle reverses shuffle.
+func unshuffle(data []byte, itemSize int) []byte {
+	n := len(data) / itemSize
+	out := make([]byte, len(data))
+	for i := 0; i < n; i++ {
+		for k := 0; k < itemSize; k++ {
+			out[i*itemSize+k] = data[k*n+i]
+		}
+	}
+	copy(out[n*itemSize:], data[n*itemSize:])
+	return out
+}